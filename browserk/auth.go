@@ -3,6 +3,10 @@ package browserk
 // AuthService handles logging in and checking login state throughout a scan/crawl
 type AuthService interface {
 	Init() error
-	Login(c *Context)
+	Login(c *Context, browser Browser) error
+	IsLoggedIn(c *Context, browser Browser) bool
 	MustLogin() bool
+	AuthHeaders() map[string]interface{} // headers every browser must send to stay authenticated
+	Session() *SessionSnapshot           // session of the last successful login, nil if we haven't logged in
+	Role() *Role                         // role we are logging in as, nil if no roles were configured
 }
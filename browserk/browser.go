@@ -16,24 +16,36 @@ type BrowserPool interface {
 type BrowserOpts struct {
 }
 
+// BindingHandler is called with the navigation being executed and the payload whenever a page calls a binding
+type BindingHandler func(nav *Navigation, payload string)
+
 // Browser interface
 type Browser interface {
 	ID() int64
 	Init(*Config) error
 	GetURL() (string, error)
 	GetDOM() (string, error)
+	GetFrameDocuments() ([]*FrameDocument, error) // same origin frames of the current page
 	GetCookies() ([]*Cookie, error)
+	SetCookies(ctx context.Context, cookies []*Cookie) error
+	SnapshotSession(ctx context.Context) (*SessionSnapshot, error)       // cookies and storage of the current page
+	RestoreSession(ctx context.Context, snapshot *SessionSnapshot) error // restores the snapshot prior to loading any pages
 	GetBaseHref() string
 	GetStorageEvents() []*StorageEvent
 	GetConsoleEvents() []*ConsoleEvent
+	GetDialogEvents() []*DialogEvent
+	GetOpenedURLs() []string
 	Navigate(ctx context.Context, url string) (err error)
 	FindElements(ctx context.Context, querySelector string, canRefreshDoc bool) ([]*HTMLElement, error)
 	FindForms(ctx context.Context) ([]*HTMLFormElement, error)
 	FindInteractables() ([]*HTMLElement, error)
+	FindScrollables(ctx context.Context) ([]*HTMLElement, error) // elements with content that overflows and can be scrolled
+	Scroll(ctx context.Context, ele *HTMLElement) (bool, error)  // scrolls the window (nil) or element one step, true if more content loaded
 	GetMessages() ([]*HTTPMessage, error)
 	Screenshot() (string, error)
 	InjectRequest(ctx context.Context, method, URI string) error
-	RefreshDocument()                                                         // reloads the document/elements
-	ExecuteAction(ctx context.Context, nav *Navigation) ([]byte, bool, error) // result, caused page load, err
+	AddBinding(ctx context.Context, name, script string, handler BindingHandler) error // script runs in every document and may call window[name](payload)
+	RefreshDocument()                                                                  // reloads the document/elements
+	ExecuteAction(ctx context.Context, nav *Navigation) ([]byte, bool, error)          // result, caused page load, err
 	Close()
 }
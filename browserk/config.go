@@ -2,9 +2,19 @@ package browserk
 
 // Credentials for logging into a target site
 type Credentials struct {
-	Username string
-	Password string
-	Email    string
+	Username   string
+	Password   string
+	Email      string
+	TOTPSecret string // base32 encoded secret for generating one time passwords in login scripts
+}
+
+// Role is a named set of credentials to scan as. Each role crawls its own copy of the
+// crawl graph and requests found by more privileged roles are replayed with the sessions
+// of less privileged roles to find authorization flaws. A role without Credentials is anonymous.
+type Role struct {
+	Name        string
+	Privilege   int // higher values are more privileged, anonymous roles should be 0
+	Credentials *Credentials
 }
 
 // AuthType defines how we are going to authenticate
@@ -15,8 +25,68 @@ const (
 	Script AuthType = iota
 	// Raw POST / whatever
 	Raw
+	// Form based authentication, fills in the login form found at AuthURL
+	Form
+	// Register a new account using the FormData, verify it via the emailed link and then login
+	Register
+)
+
+// Registration configures the Register AuthType. The target must be configured to send mail to
+// SMTPAddr, where an embedded SMTP server waits for the verification mail.
+type Registration struct {
+	URL         string // page with the registration form
+	SMTPAddr    string // address the embedded SMTP server listens on (default 127.0.0.1:2525)
+	LinkRegex   string // selects the verification link from the mail, defaults to the first link that looks like one
+	MailTimeout int    // seconds to wait for the verification mail (default 120)
+}
+
+// RawAuth is the HTTP request sent for Raw based authentication or refreshing tokens. Headers
+// and Body are text/template's executed with the Credentials e.g. {"user": {{json .Username}}},
+// the current {{.Token}} and {{.RefreshToken}} are also available.
+type RawAuth struct {
+	Method           string
+	URL              string
+	Headers          map[string]string
+	Body             string
+	TokenPath        string   // dot separated path to a bearer token in a JSON response (e.g. data.tokens.0.access_token)
+	RefreshTokenPath string   // dot separated path to a refresh token in a JSON response
+	Cookies          []string // Set-Cookie names to install in the browser, all are installed if empty
+}
+
+// SafetyLevel determines what happens to navigations and attack requests that look destructive
+// (DELETE/PUT/PATCH, deletion/payment/account changes or admin endpoints)
+type SafetyLevel int8
+
+const (
+	// SafetyOff performs every action
+	SafetyOff SafetyLevel = iota
+	// SafetyPassive records destructive navigations without performing them, destructive attack requests are not sent
+	SafetyPassive
+	// SafetyAllowlist blocks destructive navigations and attack requests unless they match the Allowlist
+	SafetyAllowlist
+	// SafetyBlock blocks all destructive navigations and attack requests
+	SafetyBlock
 )
 
+// Safety configures the safe mode for scanning shared environments
+type Safety struct {
+	Level      SafetyLevel
+	AdminPaths []string // regexes matching the url path of admin endpoints (default DefaultAdminPaths)
+	Allowlist  []string // regexes matching the url or text of destructive actions allowed with SafetyAllowlist
+}
+
+// DefaultAdminPaths considered admin endpoints by the safe mode
+var DefaultAdminPaths = []string{`(?i)/(admin|administrator|wp-admin|phpmyadmin|manage|management)(/|$)`}
+
+// AuthIndicators are used to determine if our session is still valid during a scan
+type AuthIndicators struct {
+	LoggedInRegex     string // regex that must match the page while logged in
+	LoggedOutRegex    string // regex that matches the page once we are logged out
+	LoggedInSelector  string // CSS selector that must be present while logged in
+	LoggedOutSelector string // CSS selector that must not be present while logged in
+	SessionCookie     string // name of the cookie that must be set while logged in
+}
+
 type FormData struct {
 	// Name/User related
 	UserName      string
@@ -67,6 +137,9 @@ type FormData struct {
 	Network      string
 	IPV4         string
 	IPV6         string
+
+	// file uploads
+	UploadFiles map[string]string // file extension (.png, .pdf) -> custom file to upload instead of the generated fixture
 }
 
 // DefaultFormValues to use
@@ -112,26 +185,43 @@ var DefaultFormValues = FormData{
 
 // Config for browserker
 type Config struct {
-	URL               string
-	CrawlOnly         bool     // only crawl/passive plugins do not attack
-	AllowedHosts      []string // considered 'in scope' for testing/access
-	Proxy             string
-	DisableHeadless   bool     // disables headless mode for debugging/watching
-	IgnoredHosts      []string // will access, but not report/run tests against (this is the default for non AllowedURLs)
-	ExcludedHosts     []string // will be forcibly dropped by interceptors
-	ExcludedURIs      []string // will not access (logout/signout) can be relative, or absolute (relative will be from config URL base path)
-	ExcludedForms     []string // will not submit forms that have this id or name
-	DataPath          string
-	AuthScript        string
-	AuthType          AuthType
-	Credentials       *Credentials
-	NumBrowsers       int                    // number of concurrent browsers to use, > 7-ish not recommended
-	MaxDepth          int                    // maximum distance of paths we will traverse (limit depth) (default 10)
-	MaxActions        int                    // maximum number of actions we should take (limit breadth) (default 700)
-	MaxAttackFailures int                    // maximum number of timeout/connection errors during attacks where we stop attacking a particular path (default is 5)
-	FormData          *FormData              // config form data
-	CustomHeaders     map[string]interface{} // list of custom headers to attach to every request
-	CustomCookies     map[string]interface{} // list of custom cookies to attach to every request
-	JSPluginPath      string                 // path to javascript plugins (will walk sub directories)
-	DisabledPlugins   []string               // plugins we will not load
+	URL                 string
+	CrawlOnly           bool     // only crawl/passive plugins do not attack
+	AllowedHosts        []string // considered 'in scope' for testing/access
+	Proxy               string
+	DisableHeadless     bool           // disables headless mode for debugging/watching
+	IgnoredHosts        []string       // will access, but not report/run tests against (this is the default for non AllowedURLs)
+	ExcludedHosts       []string       // will be forcibly dropped by interceptors
+	ResolveHosts        bool           // resolve hostnames through the system resolver so they match IP/CIDR hosts and ScopeRules
+	ExcludedURIs        []string       // will not access (logout/signout) can be relative, or absolute (relative will be from config URL base path)
+	ScopeRules          []*ScopeRule   // scheme/host/port/path/query rules evaluated along with the above hosts and URIs
+	ExcludedForms       []string       // will not submit forms that have this id or name
+	ExcludedElements    []*ElementRule // will not click/submit elements (or forms containing them) matching these rules
+	AllowLogout         bool           // disables automatic detection and exclusion of logout links/buttons/forms
+	Safety              *Safety        // blocks or restricts destructive actions when scanning shared environments
+	DataPath            string
+	AuthScript          string // path to a javascript login script for Script based authentication
+	AuthType            AuthType
+	AuthURL             string // login page to load for Form based authentication
+	Credentials         *Credentials
+	Roles               []*Role                // scan as each of these roles instead of the Credentials
+	RawAuth             *RawAuth               // request to send for Raw based authentication
+	Registration        *Registration          // registration form and mail settings for Register based authentication
+	RefreshAuth         *RawAuth               // request to send to refresh bearer tokens before they expire
+	TokenStorageKey     string                 // local/sessionStorage key the application writes its bearer token to during login
+	AuthIndicators      *AuthIndicators        // checked after every action to determine if we were logged out
+	NumBrowsers         int                    // number of concurrent browsers to use, > 7-ish not recommended
+	MaxDepth            int                    // maximum distance of paths we will traverse (limit depth) (default 10)
+	MaxPagesPerSkeleton int                    // stop expanding pages once this many share the same DOM structure (default 5, -1 to disable)
+	CrawlStrategy       CrawlStrategy          // order unvisited navigations are crawled in (default BreadthFirst)
+	MaxFormVariants     int                    // value sets (select options, radios, checkboxes) tried per form (default 4, -1 to fill once)
+	CrawlDisallowed     bool                   // crawl paths robots.txt disallows, otherwise they are only recorded as excluded navigations
+	MaxScrolls          int                    // steps to scroll pages/containers that load more content when scrolled (default 5, -1 to disable)
+	MaxActions          int                    // maximum number of actions we should take (limit breadth) (default 700)
+	MaxAttackFailures   int                    // maximum number of timeout/connection errors during attacks where we stop attacking a particular path (default is 5)
+	FormData            *FormData              // config form data
+	CustomHeaders       map[string]interface{} // list of custom headers to attach to every request
+	CustomCookies       map[string]interface{} // list of custom cookies to attach to every request
+	JSPluginPath        string                 // path to javascript plugins (will walk sub directories)
+	DisabledPlugins     []string               // plugins we will not load
 }
@@ -28,6 +28,7 @@ type Context struct {
 	Log            *zerolog.Logger
 	CtxComplete    func()
 	Auth           AuthService
+	Roles          []AuthService // auth services of every configured role
 	Scope          ScopeService
 	FormHandler    FormHandler
 	Crawl          CrawlGrapher
@@ -63,6 +64,8 @@ func (c *Context) Copy() *Context {
 	return &Context{
 		Ctx:             c.Ctx,
 		CtxComplete:     c.CtxComplete,
+		Auth:            c.Auth,
+		Roles:           c.Roles,
 		Scope:           c.Scope,
 		FormHandler:     c.FormHandler,
 		Crawl:           c.Crawl,
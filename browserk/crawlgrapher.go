@@ -10,11 +10,14 @@ type CrawlGrapher interface {
 	Find(ctx context.Context, byState, setState NavState, limit int64) [][]*Navigation
 	FindWithResults(ctx context.Context, byState, setState NavState, limit int64) [][]*NavigationWithResult
 	FindPathByNavID(ctx context.Context, navID []byte) []*Navigation
+	FindNavigations(ctx context.Context, byState NavState) []*Navigation
 	AddNavigation(nav *Navigation) error
 	AddNavigations(navs []*Navigation) error
 	SetNavigationState(navID []byte, setState NavState) error
+	ExcludeNavigation(navID []byte, reason string) error
 	AddResult(result *NavigationResult) error
 	NavExists(nav *Navigation) bool
 	GetNavigation(id []byte) (*Navigation, error)
 	GetNavigationResults() ([]*NavigationResult, error)
+	SkeletonPages(skeleton []byte, role string) []string
 }
@@ -3,7 +3,9 @@ package browserk
 import (
 	"bytes"
 	"crypto/md5"
+	"io"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -15,6 +17,30 @@ type ActHTMLElement interface {
 	AllAttributes() map[string]string
 	Depth() int
 	Hash() []byte
+	HostChain() []*ElementHost
+}
+
+// ElementHost is an (i)frame or shadow root host an element is nested in, it is found in its own document (or
+// shadow root) by the Index of the elements matching Selector
+type ElementHost struct {
+	Frame    bool   // an (i)frame, otherwise the host of an open shadow root
+	Selector string // tag name, or tag name and id, of the host
+	Index    int
+}
+
+func (e *ElementHost) String() string {
+	kind := "shadow"
+	if e.Frame {
+		kind = "frame"
+	}
+	return kind + ":" + e.Selector + ":" + strconv.Itoa(e.Index)
+}
+
+// hashHosts so the same element in different frames/components is unique
+func hashHosts(hash io.Writer, hosts []*ElementHost) {
+	for _, host := range hosts {
+		hash.Write([]byte(host.String()))
+	}
 }
 
 // HTMLElement type
@@ -27,8 +53,10 @@ type HTMLElement struct {
 	Hidden        bool
 	NodeDepth     int
 	ID            []byte
-	Value         string // value to set if it's an input field or whatever
-	DocURL        string // which document/url this form element belongs to
+	Value         string         // value to set if it's an input field or whatever
+	DocURL        string         // which document/url this form element belongs to
+	Hosts         []*ElementHost // frames and shadow hosts this element is nested in, outermost first
+	Checked       bool           // radio/checkbox should be checked when filling a form variant
 }
 
 func (h *HTMLElement) IsForm() bool {
@@ -63,6 +91,7 @@ func (h *HTMLElement) Hash() []byte {
 	} else {
 		hash.Write([]byte{0})
 	}
+	hashHosts(hash, h.Hosts)
 	h.ID = hash.Sum(nil)
 	return h.ID
 }
@@ -95,6 +124,10 @@ func (h *HTMLElement) AllAttributes() map[string]string {
 	return h.Attributes
 }
 
+func (h *HTMLElement) HostChain() []*ElementHost {
+	return h.Hosts
+}
+
 // FormType determine what type of form it is
 type FormType int8
 
@@ -128,6 +161,8 @@ type HTMLFormElement struct {
 	ChildElements  []*HTMLElement // capture all children (labels etc) so we can do context analysis
 	ID             []byte
 	SubmitButtonID []byte
+	Hosts          []*ElementHost // frames and shadow hosts this form is nested in, outermost first
+	Variant        string         // select/radio/checkbox choices this form is filled with, part of the navigation ID
 }
 
 // Hash the form and it's input elements to (hopefully) a unique value
@@ -150,6 +185,7 @@ func (h *HTMLFormElement) Hash() []byte {
 	} else {
 		hash.Write([]byte{0})
 	}
+	hashHosts(hash, h.Hosts)
 
 	h.ID = hash.Sum(nil)
 	return h.ID
@@ -175,6 +211,21 @@ func (h *HTMLFormElement) AllAttributes() map[string]string {
 	return h.Attributes
 }
 
+func (h *HTMLFormElement) HostChain() []*ElementHost {
+	return h.Hosts
+}
+
+// Copy the form and its child elements so they can be filled with different values
+func (h *HTMLFormElement) Copy() *HTMLFormElement {
+	c := *h
+	c.ChildElements = make([]*HTMLElement, len(h.ChildElements))
+	for i, child := range h.ChildElements {
+		childCopy := *child
+		c.ChildElements[i] = &childCopy
+	}
+	return &c
+}
+
 func (h *HTMLFormElement) Tag() string {
 	tag := strings.ToLower(HTMLTypeToStrMap[h.Type])
 	if h.Type == CUSTOM {
@@ -0,0 +1,24 @@
+package browserk_test
+
+import (
+	"bytes"
+	"testing"
+
+	"gitlab.com/browserker/browserk"
+)
+
+func TestElementHostsHash(t *testing.T) {
+	top := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Save"}
+	alice := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Save", Hosts: []*browserk.ElementHost{{Selector: "user-card", Index: 0}}}
+	bob := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Save", Hosts: []*browserk.ElementHost{{Selector: "user-card", Index: 1}}}
+	framed := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Save", Hosts: []*browserk.ElementHost{{Frame: true, Selector: "user-card", Index: 0}}}
+
+	if bytes.Equal(top.Hash(), alice.Hash()) || bytes.Equal(alice.Hash(), bob.Hash()) || bytes.Equal(alice.Hash(), framed.Hash()) {
+		t.Fatalf("expected elements in different hosts to have different hashes\n")
+	}
+
+	// elements of the top document hash as they always have
+	if !bytes.Equal(top.Hash(), (&browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Save", Hosts: []*browserk.ElementHost{}}).Hash()) {
+		t.Fatalf("expected no hosts to not change the hash\n")
+	}
+}
@@ -6,4 +6,10 @@ var (
 	// ErrInjectionTimeout happened
 	ErrInjectionTimeout       = errors.New("injection timed out")
 	ErrEmptyInjectionResponse = errors.New("injection body was empty")
+	// ErrLoginFormNotFound no form with a password input was found on the login page
+	ErrLoginFormNotFound = errors.New("unable to find login form")
+	// ErrLoginFailed login was attempted but we do not appear to be logged in
+	ErrLoginFailed = errors.New("login failed")
+	// ErrRegistrationFormNotFound no form with a password input was found on the registration page
+	ErrRegistrationFormNotFound = errors.New("unable to find registration form")
 )
@@ -37,3 +37,18 @@ type ConsoleEvent struct {
 	Column   int       `json:"column,omitempty"` // Column number in the resource that generated this message (1-based).
 	Observed time.Time `json:"observed"`         // time the console event occurred
 }
+
+// DialogEvent captures javascript dialogs (alert, confirm, prompt, beforeunload), they are never displayed
+type DialogEvent struct {
+	Type     string    `json:"type"`          // alert, confirm, prompt or beforeunload
+	Message  string    `json:"message"`       // text of the dialog
+	URL      string    `json:"url,omitempty"` // url of the document that opened the dialog
+	Observed time.Time `json:"observed"`      // time the dialog was opened
+}
+
+// RouteEvent captures client side route changes of single page apps (history api and hash changes)
+type RouteEvent struct {
+	Type     string    `json:"type"`     // pushState, replaceState, popstate or hashchange
+	URL      string    `json:"url"`      // absolute url after the route change
+	Observed time.Time `json:"observed"` // time the route changed
+}
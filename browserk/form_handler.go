@@ -5,4 +5,5 @@ package browserk
 type FormHandler interface {
 	Init() error
 	Fill(form *HTMLFormElement)
+	Variants(form *HTMLFormElement, limit int) []*HTMLFormElement // filled copies of the form choosing different select options, radios and checkboxes
 }
@@ -1,10 +1,7 @@
 package browserk
 
 import (
-	"bytes"
 	"crypto/md5"
-	"net/url"
-	"sort"
 	"strings"
 	"time"
 
@@ -57,25 +54,11 @@ func (h *HTTPRequest) StrHeaders() string {
 	return headers
 }
 
-// hashURL scheme, host (and port), path and sorted query names only (not values)
-// TODO: Do analysis of query values to see if they are dynamic or static
-// if static, they should be included: think x.jsp?page=admin&x=y vs x.jsp?page=user&x=y
+// hashURL scheme, host (and port), path and sorted query names with dynamic path segments and
+// query values replaced by their class (see NormalizeURL), so x.jsp?page=admin&id=1 and
+// x.jsp?page=user&id=1 differ but /orders/1234 and /orders/1235 do not
 func hashURL(in string) []byte {
-	buf := &bytes.Buffer{}
-	u, err := url.Parse(in)
-	if err != nil {
-		return []byte(in)
-	}
-	buf.Write([]byte(u.Scheme))
-	buf.Write([]byte(u.Host))
-	buf.Write([]byte(u.Path))
-	queryNames := make([]string, 0)
-	for k := range u.Query() {
-		queryNames = append(queryNames, k)
-	}
-	sort.Strings(queryNames)
-	buf.Write([]byte(strings.Join(queryNames, ",")))
-	return buf.Bytes()
+	return []byte(NormalizeURL(in))
 }
 
 // Copy does a deep copy
@@ -212,6 +195,17 @@ func (h *InterceptedHTTPRequest) Hash() []byte {
 	return h.ID
 }
 
+// SetHeader on the modified request, replacing any headers of the same name. If the headers
+// have not been modified yet, the original request headers are kept.
+func (h *InterceptedHTTPRequest) SetHeader(name, value string) {
+	if h.Modified.Headers == nil {
+		h.Modified.Headers = make([]*gcdapi.FetchHeaderEntry, len(h.RequestHeaders))
+		copy(h.Modified.Headers, h.RequestHeaders)
+	}
+	h.Modified.RemoveHeader(name)
+	h.Modified.Headers = append(h.Modified.Headers, &gcdapi.FetchHeaderEntry{Name: name, Value: value})
+}
+
 // Copy does a deep copy
 // TODO: write a small astutil to generate deep copy with nil checks of nested objects
 // for now, be super lazy
@@ -270,11 +264,16 @@ func (h *HTTPModifiedRequest) Copy() *HTTPModifiedRequest {
 	return c
 }
 
+// SetHeaders replaces any existing headers of the same name with the provided headers
 func (h *HTTPModifiedRequest) SetHeaders(headers map[string]interface{}) {
 	if h.Headers == nil {
 		h.Headers = make([]*gcdapi.FetchHeaderEntry, 0)
 	}
 
+	for k := range headers {
+		h.RemoveHeader(k)
+	}
+
 	for k, value := range headers {
 		switch v := value.(type) {
 		case string:
@@ -288,6 +287,17 @@ func (h *HTTPModifiedRequest) SetHeaders(headers map[string]interface{}) {
 	}
 }
 
+// RemoveHeader removes all headers matching name (case insensitive)
+func (h *HTTPModifiedRequest) RemoveHeader(name string) {
+	headers := h.Headers[:0]
+	for _, header := range h.Headers {
+		if !strings.EqualFold(header.Name, name) {
+			headers = append(headers, header)
+		}
+	}
+	h.Headers = headers
+}
+
 // InterceptedHTTPResponse to pass to middleware and allow modifications to Modified
 type InterceptedHTTPResponse struct {
 	ID                  []byte                     `json:"id"`
@@ -1,6 +1,10 @@
 package browserk
 
-import "time"
+import (
+	"time"
+
+	"github.com/wirepair/gcd/v2/gcdapi"
+)
 
 // HTTPMessage is the request/response pair
 type HTTPMessage struct {
@@ -77,3 +81,22 @@ func (m *InterceptedHTTPMessage) Copy() *InterceptedHTTPMessage {
 		Response: m.Response.Copy(),
 	}
 }
+
+// AttackTarget is a request we found outside of the browser's traffic (api routes in scripts) that the
+// attack phase sends directly
+type AttackTarget struct {
+	Method string
+	URL    string
+	Source string // where it was found, script url and line
+}
+
+// Message of the target so it can be attacked like a captured request, documentURL is the page that would send it
+func (t *AttackTarget) Message(documentURL string) *HTTPMessage {
+	return &HTTPMessage{
+		Request: &HTTPRequest{
+			DocumentURL: documentURL,
+			Type:        "Fetch",
+			Request:     &gcdapi.NetworkRequest{Url: t.URL, Method: t.Method, Headers: make(map[string]interface{})},
+		},
+	}
+}
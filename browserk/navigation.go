@@ -3,6 +3,7 @@ package browserk
 import (
 	"crypto/md5"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,6 +22,8 @@ const (
 	TrigPlugin
 	// TrigAutoBrowser something caused the browser to trigger this (redirect etc)
 	TrigAutoBrowser
+	// TrigScript endpoint extracted from a javascript response
+	TrigScript
 )
 
 // NavState is the state of a navigation
@@ -39,6 +42,8 @@ const (
 	NavFailed
 	// NavAudited crawler has audited the entire navigation path
 	NavAudited
+	// NavExcluded crawler will not execute this navigation (logout etc)
+	NavExcluded
 )
 
 // NavigationWithResult is for the BrowserkAttacker
@@ -57,6 +62,10 @@ type Navigation struct {
 	Action           *Action     `graph:"action"`
 	Scope            Scope       `graph:"scope"`
 	Distance         int         `graph:"dist"`
+	Role             string      `graph:"role"`            // name of the role that found this navigation
+	ExcludedReason   string      `graph:"excluded_reason"` // why this navigation was excluded from the crawl
+	BlockedReason    string      `graph:"blocked_reason"`  // why the safe mode blocked this navigation
+	Source           string      `graph:"source"`          // script url:line a TrigScript navigation was extracted from
 }
 
 // NewNavigation type
@@ -71,12 +80,107 @@ func NewNavigation(triggeredBy TriggeredBy, action *Action) *Navigation {
 
 	// TODO: add originID as part of new nav id for uniqueness?
 	h := md5.New()
-	h.Write(n.Action.Input)
+	h.Write(patternInput(n.Action))
 	h.Write([]byte{byte(n.Action.Type)})
 	n.ID = h.Sum(nil)
 	return n
 }
 
+// SetRole of a navigation created with NewNavigation, the role is part of the ID so each role
+// gets its own crawl graph
+func (n *Navigation) SetRole(role string) {
+	n.Role = role
+	n.ID = roleID(n.ID, role)
+}
+
+// roleID keeps the original id if we aren't scanning with roles
+func roleID(id []byte, role string) []byte {
+	if role == "" {
+		return id
+	}
+	h := md5.New()
+	h.Write(id)
+	h.Write([]byte(role))
+	return h.Sum(nil)
+}
+
+// Exclude this navigation from being crawled, it is still stored in the graph so it can be reviewed
+func (n *Navigation) Exclude(reason string) {
+	n.State = NavExcluded
+	n.Scope = ExcludedFromScope
+	n.ExcludedReason = reason
+}
+
+// patternInput of load url actions so urls that only differ by dynamic values (ids etc) share a navigation ID.
+// Fragments are usually ignored, unless they are hash router routes (#/users, #!/users).
+func patternInput(action *Action) []byte {
+	if action.Type != ActLoadURL {
+		return action.Input
+	}
+
+	input := string(action.Input)
+	pattern := NormalizeURL(input)
+	if i := strings.Index(input, "#"); i != -1 {
+		if route := strings.TrimPrefix(input[i+1:], "!"); strings.HasPrefix(route, "/") {
+			pattern += "#" + NormalizeURL(route)
+		}
+	}
+	return []byte(pattern)
+}
+
+// patternElement copies the element with its url attributes replaced by their pattern, for navigation IDs.
+// Where the element has a url it identifies the element, otherwise the text does once its dynamic words are
+// normalized, so "Order 1234" and "Order 1235" links/buttons share an ID. href="#" and javascript: links are
+// told apart by their text.
+func patternElement(ele *HTMLElement) *HTMLElement {
+	c := *ele
+	c.ID = nil
+	c.Attributes = patternAttributes(ele.Attributes, "href", "formaction")
+	if isURLValue(c.GetAttribute("href")) || isURLValue(c.GetAttribute("formaction")) {
+		c.InnerText = ""
+	} else {
+		c.InnerText = NormalizeText(ele.InnerText)
+	}
+	return &c
+}
+
+// patternForm copies the form with its action replaced by its pattern, for navigation IDs
+func patternForm(form *HTMLFormElement) *HTMLFormElement {
+	c := *form
+	c.ID = nil
+	c.Attributes = patternAttributes(form.Attributes, "action")
+	return &c
+}
+
+func patternAttributes(attributes map[string]string, names ...string) map[string]string {
+	if attributes == nil {
+		return nil
+	}
+
+	patterned := make(map[string]string, len(attributes))
+	for k, v := range attributes {
+		patterned[k] = v
+	}
+
+	for _, name := range names {
+		if value, ok := patterned[name]; ok && isURLValue(value) {
+			patterned[name] = NormalizeURL(value)
+		}
+	}
+	return patterned
+}
+
+// isURLValue returns true if the attribute value is a url, in page anchors (#) and javascript: handlers are not
+func isURLValue(value string) bool {
+	return value != "" && !strings.HasPrefix(value, "#") && !strings.HasPrefix(value, "javascript:")
+}
+
+// Block this navigation as unsafe, it is excluded from the crawl and recorded with the reason
+func (n *Navigation) Block(reason string) {
+	n.BlockedReason = reason
+	n.Exclude("safe mode: " + reason)
+}
+
 func (n *Navigation) Copy() *Navigation {
 	if n == nil {
 		return nil
@@ -102,6 +206,7 @@ func NewNavigationFromBrowser(from *Navigation, triggeredBy TriggeredBy, action
 	n := &Navigation{
 		OriginID:         from.ID,
 		Distance:         from.Distance + 1,
+		Role:             from.Role,
 		Action:           action,
 		TriggeredBy:      triggeredBy,
 		State:            NavUnvisited,
@@ -111,9 +216,9 @@ func NewNavigationFromBrowser(from *Navigation, triggeredBy TriggeredBy, action
 
 	// TODO: add originID as part of new nav id for uniqueness?
 	h := md5.New()
-	h.Write(n.Action.Input)
+	h.Write(patternInput(n.Action))
 	h.Write([]byte{byte(n.Action.Type)})
-	n.ID = h.Sum(nil)
+	n.ID = roleID(h.Sum(nil), n.Role)
 	return n
 }
 
@@ -136,16 +241,21 @@ func NewNavigationFromForm(from *Navigation, triggeredBy TriggeredBy, form *HTML
 		StateUpdatedTime: time.Now(),
 		Scope:            InScope,
 		Distance:         from.Distance + 1,
+		Role:             from.Role,
 	}
 
 	h := md5.New()
 	h.Write([]byte{byte(ActFillForm)})
-	h.Write(n.Action.Form.Hash())
+	h.Write(patternForm(n.Action.Form).Hash())
+	// variants of the same form must not collide
+	if form.Variant != "" {
+		h.Write([]byte(form.Variant))
+	}
 	// if the action is # and there are no bound events, that means this form is specific to this page
 	if (form.GetAttribute("action") == "#" || form.GetAttribute("action") == "") && len(form.Events) == 0 {
 		h.Write([]byte(n.Action.Form.DocURL))
 	}
-	n.ID = h.Sum(nil)
+	n.ID = roleID(h.Sum(nil), n.Role)
 	return n
 }
 
@@ -168,6 +278,7 @@ func NewNavigationFromElement(from *Navigation, triggeredBy TriggeredBy, ele *HT
 		StateUpdatedTime: time.Now(),
 		Scope:            InScope,
 		Distance:         from.Distance + 1,
+		Role:             from.Role,
 	}
 
 	h := md5.New()
@@ -179,26 +290,90 @@ func NewNavigationFromElement(from *Navigation, triggeredBy TriggeredBy, ele *HT
 		}
 	}
 	h.Write([]byte{byte(aType)})
-	h.Write(n.Action.Element.Hash())
-	n.ID = h.Sum(nil)
+	h.Write(patternElement(n.Action.Element).Hash())
+	n.ID = roleID(h.Sum(nil), n.Role)
+	return n
+}
+
+// NewScrollNavigation scrolls the window (nil ele) or a scrollable element of the page one more step, the step
+// is stored as the input so each step is its own navigation. The page's url pattern is part of the ID so every
+// action taken on the same feed doesn't start a new scroll.
+func NewScrollNavigation(from *Navigation, pageURL string, ele *HTMLElement, step int) *Navigation {
+	aType := ActScroll
+	if ele != nil {
+		aType = ActMouseWheel
+	}
+
+	action := &Action{
+		Type:    aType,
+		Input:   []byte(strconv.Itoa(step)),
+		Element: ele,
+		Form:    nil,
+		Result:  nil,
+	}
+
+	n := &Navigation{
+		Action:           action,
+		OriginID:         from.ID,
+		TriggeredBy:      TrigCrawler,
+		State:            NavUnvisited,
+		StateUpdatedTime: time.Now(),
+		Scope:            InScope,
+		Distance:         from.Distance + 1,
+		Role:             from.Role,
+	}
+
+	h := md5.New()
+	h.Write([]byte(NormalizeURL(pageURL)))
+	h.Write([]byte{byte(aType)})
+	if ele != nil {
+		h.Write(patternElement(ele).Hash())
+	}
+	h.Write(action.Input)
+	n.ID = roleID(h.Sum(nil), n.Role)
 	return n
 }
 
+// ScrollStep of navigations created by NewScrollNavigation, 0 for any other navigation
+func (n *Navigation) ScrollStep() int {
+	if n.Action == nil || (n.Action.Type != ActScroll && n.Action.Type != ActMouseWheel) {
+		return 0
+	}
+
+	step, err := strconv.Atoi(string(n.Action.Input))
+	if err != nil {
+		return 0
+	}
+	return step
+}
+
 // NavigationResult captures result details about a navigation
 type NavigationResult struct {
-	ID            []byte          `graph:"r_id"`
-	NavigationID  []byte          `graph:"r_nav_id"`
-	DOM           string          `graph:"r_dom"`
-	StartURL      string          `graph:"r_start_url"`
-	EndURL        string          `graph:"r_end_url"`
-	MessageCount  int             `graph:"r_message_count"`
-	Messages      []*HTTPMessage  `graph:"r_messages"`
-	Cookies       []*Cookie       `graph:"r_cookies"`
-	ConsoleEvents []*ConsoleEvent `graph:"r_console"`
-	StorageEvents []*StorageEvent `graph:"r_storage"`
-	CausedLoad    bool            `graph:"r_caused_load"`
-	WasError      bool            `graph:"r_was_error"`
-	Errors        []error         `graph:"r_errors"`
+	ID             []byte           `graph:"r_id"`
+	NavigationID   []byte           `graph:"r_nav_id"`
+	DOM            string           `graph:"r_dom"`
+	StartURL       string           `graph:"r_start_url"`
+	EndURL         string           `graph:"r_end_url"`
+	MessageCount   int              `graph:"r_message_count"`
+	Messages       []*HTTPMessage   `graph:"r_messages"`
+	Cookies        []*Cookie        `graph:"r_cookies"`
+	ConsoleEvents  []*ConsoleEvent  `graph:"r_console"`
+	StorageEvents  []*StorageEvent  `graph:"r_storage"`
+	CausedLoad     bool             `graph:"r_caused_load"`
+	WasError       bool             `graph:"r_was_error"`
+	Errors         []error          `graph:"r_errors"`
+	Role           string           `graph:"r_role"`
+	SessionClosed  bool             `graph:"r_session_closed"` // the action removed the session cookie (logout)
+	Skeleton       []byte           `graph:"r_skeleton"`       // hash of the DOM structure, shared by pages of the same template
+	DialogEvents   []*DialogEvent   `graph:"r_dialogs"`        // dialogs opened by the action, xss payloads usually call alert
+	FrameDocuments []*FrameDocument `graph:"r_frames"`         // same origin frames of the page, DOM only has the top document
+	AttackTargets  []*AttackTarget  `graph:"r_attack_targets"` // requests the page's scripts can send but the browser never did
+}
+
+// FrameDocument is the serialized DOM of a frame
+type FrameDocument struct {
+	URL string
+	DOM string
 }
 
 // Hash a unique ID for this result (needs work)
@@ -25,6 +25,7 @@ type PluginOpts struct {
 	ListenConsole    bool                // listens for console.log events
 	ListenURL        bool                // listens for URL change/updates
 	ListenJS         bool                // listens to JS events
+	ListenCrawl      bool                // notified once every role finished crawling
 	ExecutionType    PluginExecutionType // How often/when this plugin executes
 	// list of injection points this plugin will execute on:
 	// (method, path, query_name, query_value, header_name, header_value, cookie_name, cookie_value, body_param, body_value,
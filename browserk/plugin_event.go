@@ -21,6 +21,7 @@ const (
 	EvtStorage
 	EvtCookie
 	EvtConsole
+	EvtCrawlComplete
 )
 
 type PluginEvent struct {
@@ -68,6 +69,10 @@ func (e *PluginEvent) Storage() *StorageEvent {
 	return e.EventData.Storage
 }
 
+func (e *PluginEvent) Route() *RouteEvent {
+	return e.EventData.Route
+}
+
 func (e *PluginEvent) InterceptedHTTPRequest() *InterceptedHTTPRequest {
 	return e.EventData.InterceptedHTTPRequest
 }
@@ -85,6 +90,7 @@ type PluginEventData struct {
 	Storage                 *StorageEvent
 	Cookie                  *Cookie
 	Console                 *ConsoleEvent
+	Route                   *RouteEvent
 }
 
 func (p *PluginEventData) Hash() []byte {
@@ -147,6 +153,9 @@ func (p *PluginEventData) Hash() []byte {
 		hash.Write([]byte{byte(p.Console.Line)})
 		hash.Write([]byte{byte(p.Console.Column)})
 		hash.Write([]byte(p.Console.Source))
+	} else if p.Route != nil {
+		hash.Write([]byte(p.Route.Type))
+		hash.Write([]byte(p.Route.URL))
 	}
 	p.ID = hash.Sum(nil)
 	return p.ID
@@ -200,6 +209,21 @@ func ConsolePluginEvent(bctx *Context, URL string, nav *Navigation, console *Con
 	return evt
 }
 
+func URLPluginEvent(bctx *Context, URL string, nav *Navigation, route *RouteEvent) *PluginEvent {
+	evt := newPluginEvent(bctx, URL, nav, EvtURL)
+	evt.EventData = &PluginEventData{Route: route}
+	evt.Hash()
+	return evt
+}
+
+// CrawlCompletePluginEvent is sent once every role finished crawling, before the attack phase
+func CrawlCompletePluginEvent(bctx *Context) *PluginEvent {
+	evt := newPluginEvent(bctx, "", nil, EvtCrawlComplete)
+	evt.EventData = &PluginEventData{}
+	evt.Hash()
+	return evt
+}
+
 func newPluginEvent(bctx *Context, URL string, nav *Navigation, eventType PluginEventType) *PluginEvent {
 	return &PluginEvent{
 		Type: eventType,
@@ -9,6 +9,7 @@ type PluginServicer interface {
 	Register(plugin Plugin)
 	Unregister(plugin Plugin)
 	DispatchEvent(evt *PluginEvent)
+	CrawlComplete(bctx *Context)
 	RegisterForResponse(requestID string, respCh chan<- *InterceptedHTTPMessage, injection *InterceptedHTTPRequest)
 	DispatchResponse(requestID string, interceptedMessage *InterceptedHTTPResponse)
 	Store() PluginStorer
@@ -0,0 +1,29 @@
+package browserk
+
+// CrawlStrategy determines the order unvisited navigations are crawled in
+type CrawlStrategy int8
+
+const (
+	// BreadthFirst crawls the navigations closest to the start first
+	BreadthFirst CrawlStrategy = iota
+	// DepthFirst crawls the deepest navigations first
+	DepthFirst
+	// NoveltyFirst crawls forms, elements like those that revealed new elements and unseen url patterns first
+	NoveltyFirst
+)
+
+// CrawlStrategyMap for logging
+var CrawlStrategyMap = map[CrawlStrategy]string{
+	BreadthFirst: "breadth first",
+	DepthFirst:   "depth first",
+	NoveltyFirst: "novelty first",
+}
+
+// Scheduler picks which unvisited navigations are crawled next
+type Scheduler interface {
+	// Next returns up to limit of the candidate unvisited navigations to crawl, candidates are every unvisited
+	// navigation of the crawl graph
+	Next(candidates []*Navigation, limit int) []*Navigation
+	// Visited is called after crawling the final navigation of a path with the new navigations it found
+	Visited(nav *Navigation, result *NavigationResult, found []*Navigation)
+}
@@ -14,11 +14,39 @@ const (
 	ExcludedFromScope
 )
 
+// ScopeRule matches urls by any combination of its fields, empty fields match everything.
+// Rules are evaluated by highest Priority first, if rules of the same Priority match the most
+// restrictive Scope wins (ExcludedFromScope, then OutOfScope, then InScope). If no rule matches
+// the url is OutOfScope.
+type ScopeRule struct {
+	Scope    Scope             // scope of matching urls
+	Priority int               // higher priority rules take precedence
+	Scheme   string            // http, https etc.
+	Host     string            // exact host, glob (*.corp.local), domain and subdomains (.corp.local), IP or CIDR, may include the port (localhost:8080)
+	Port     string            // port, urls without an explicit port use the scheme's default port
+	Path     string            // regex the path must match
+	Query    map[string]string // query parameter name to regex its value must match, an empty regex only requires the parameter
+}
+
+// ElementRule excludes matching elements (and forms containing them) from being crawled, all set
+// fields must match. Use it for dangerous actions such as "Delete account" or "Reset database".
+type ElementRule struct {
+	Name       string            // shown in the report, defaults to a description of the rule
+	Selector   string            // CSS selector
+	Text       string            // regex matched against the element's text or value
+	Attributes map[string]string // attribute name to regex its value must match
+}
+
 // ScopeService checks if a url is in scope
 type ScopeService interface {
 	AddScope(inputs []string, scope Scope)
+	AddRules(rules []*ScopeRule) error
 	AddExcludedURIs(inputs []string)
 	ExcludeForms(idsOrNames []string)
+	ExcludeElements(rules []*ElementRule) error
+	ExcludedSelectors() []string
+	ExcludedElement(ele *HTMLElement, selected map[string][]string) string
+	ExcludedForm(form *HTMLFormElement, selected map[string][]string) string
 	Check(uri *url.URL) Scope
 	CheckURL(url string) Scope
 	CheckRelative(host, relative string) Scope
@@ -0,0 +1,31 @@
+package browserk
+
+import "time"
+
+// StorageItem is a local or sessionStorage entry of an origin
+type StorageItem struct {
+	Origin         string
+	IsLocalStorage bool
+	Key            string
+	Value          string
+}
+
+// IndexedDBStore is an IndexedDB object store and its entries, keys and values are JSON encoded
+type IndexedDBStore struct {
+	Origin        string
+	Database      string
+	Version       int
+	Store         string
+	KeyPath       string // JSON encoded, null for out of line keys
+	AutoIncrement bool
+	Keys          []string
+	Values        []string
+}
+
+// SessionSnapshot of a logged in browser, restored into new browsers so they don't have to login again
+type SessionSnapshot struct {
+	Cookies   []*Cookie
+	Storage   []*StorageItem
+	IndexedDB []*IndexedDBStore
+	Taken     time.Time
+}
@@ -0,0 +1,208 @@
+package browserk
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ValueClass of a path segment or query value
+type ValueClass int8
+
+const (
+	// ValueEnum is one of a small set of values (page=admin, /orders) and is part of the pattern
+	ValueEnum ValueClass = iota
+	// ValueNumericID 1234
+	ValueNumericID
+	// ValueUUID 7d444840-9dc0-11d1-b245-5ffdce74fad2
+	ValueUUID
+	// ValueHash hex digests and long random looking tokens
+	ValueHash
+	// ValueDate 2020-07-21, 21/07/2020
+	ValueDate
+	// ValueFreeText anything else with more distinct values than we consider an enum (search terms, slugs)
+	ValueFreeText
+)
+
+// ValueClassMap the placeholders used in patterns for each class
+var ValueClassMap = map[ValueClass]string{
+	ValueEnum:      "{enum}",
+	ValueNumericID: "{id}",
+	ValueUUID:      "{uuid}",
+	ValueHash:      "{hash}",
+	ValueDate:      "{date}",
+	ValueFreeText:  "{text}",
+}
+
+// MaxEnumValues a position may have before its values are considered free text
+const MaxEnumValues = 10
+
+var (
+	numericRe = regexp.MustCompile(`^-?\d+$`)
+	uuidRe    = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	hexRe     = regexp.MustCompile(`(?i)^[0-9a-f]{16,}$`)
+	tokenRe   = regexp.MustCompile(`^[A-Za-z0-9_-]{24,}={0,2}$`)
+	digitRe   = regexp.MustCompile(`\d`)
+	dateRe    = regexp.MustCompile(`^(\d{4}-\d{1,2}-\d{1,2}([T ]\d{1,2}:\d{2}(:\d{2})?.*)?|\d{1,2}[/.-]\d{1,2}[/.-]\d{2,4})$`)
+)
+
+// NormalizeURL replaces the path segments and query values that are dynamic by their syntax alone (IDs, UUIDs,
+// hashes, dates and free text) with their placeholder, other values are kept as is e.g.
+// https://example.com/orders/{id}?page=admin. Unlike a URLPatternLearner the result never depends on what else was
+// seen, so it's what request hashes and navigation IDs (which are persisted and compared across crawls) use.
+func NormalizeURL(rawURL string) string {
+	return patternURL(rawURL, func(position, value string) string {
+		if class := SyntacticClass(value); class != ValueEnum {
+			return ValueClassMap[class]
+		}
+		return value
+	})
+}
+
+// NormalizeText replaces the words of the text that are dynamic by their syntax alone with their placeholder, so
+// "Order 1234" and "Order 1235" normalize to "Order {id}"
+func NormalizeText(text string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		if class := SyntacticClass(word); class != ValueEnum {
+			words[i] = ValueClassMap[class]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// URLPatternLearner classifies path segments and query values so urls that only differ by dynamic values
+// (/orders/1234, /orders/1235) share a pattern, while static values (page=admin, page=user) do not. IDs,
+// UUIDs, hashes and dates are detected by their syntax, other values are enums until a position has seen
+// more than MaxEnumValues distinct values, after which they are free text. As patterns depend on the order
+// urls are learned in, use NormalizeURL for anything that is stored.
+type URLPatternLearner struct {
+	lock     *sync.Mutex
+	values   map[string]map[string]struct{} // position -> distinct values seen
+	freeText map[string]struct{}            // positions with too many distinct values
+}
+
+// NewURLPatternLearner with nothing learned
+func NewURLPatternLearner() *URLPatternLearner {
+	return &URLPatternLearner{
+		lock:     &sync.Mutex{},
+		values:   make(map[string]map[string]struct{}),
+		freeText: make(map[string]struct{}),
+	}
+}
+
+// Pattern of the url after learning its values e.g. https://example.com/orders/{id}?page=admin&q={text}. The
+// fragment is ignored, unparsable urls are returned as is.
+func (l *URLPatternLearner) Pattern(rawURL string) string {
+	return patternURL(rawURL, l.placeholder)
+}
+
+// patternURL replaces each path segment and query value with what placeholder returns for it
+func patternURL(rawURL string, placeholder func(position, value string) string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	pattern := &strings.Builder{}
+	if u.Scheme != "" {
+		pattern.WriteString(u.Scheme + "://")
+	}
+	pattern.WriteString(u.Host)
+
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, segment := range segments {
+		if i > 0 {
+			pattern.WriteString("/")
+		}
+
+		if segment == "" {
+			continue
+		}
+		// the position depends on the pattern so far, /users/{id}/edit and /posts/{id}/edit are different positions
+		pattern.WriteString(placeholder(pattern.String(), segment))
+	}
+
+	query := u.Query()
+	if len(query) == 0 {
+		return pattern.String()
+	}
+
+	path := pattern.String()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	params := make([]string, 0, len(names))
+	for _, name := range names {
+		values := make([]string, 0, len(query[name]))
+		for _, value := range query[name] {
+			values = append(values, placeholder(path+"?"+name, value))
+		}
+		params = append(params, name+"="+strings.Join(values, ","))
+	}
+	return path + "?" + strings.Join(params, "&")
+}
+
+// Classify the value at the position (learning it), returns the class
+func (l *URLPatternLearner) Classify(position, value string) ValueClass {
+	if class := SyntacticClass(value); class != ValueEnum {
+		return class
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if _, ok := l.freeText[position]; ok {
+		return ValueFreeText
+	}
+
+	seen, ok := l.values[position]
+	if !ok {
+		seen = make(map[string]struct{})
+		l.values[position] = seen
+	}
+	seen[value] = struct{}{}
+
+	if len(seen) > MaxEnumValues {
+		l.freeText[position] = struct{}{}
+		delete(l.values, position)
+		return ValueFreeText
+	}
+	return ValueEnum
+}
+
+// placeholder for the value, or the value itself if it's an enum
+func (l *URLPatternLearner) placeholder(position, value string) string {
+	class := l.Classify(position, value)
+	if class == ValueEnum {
+		return value
+	}
+	return ValueClassMap[class]
+}
+
+// SyntacticClass of the value, returns ValueEnum if it could be an enum or free text (which depends
+// on what else was seen at the same position)
+func SyntacticClass(value string) ValueClass {
+	switch {
+	case value == "":
+		return ValueEnum
+	case numericRe.MatchString(value):
+		return ValueNumericID
+	case uuidRe.MatchString(value):
+		return ValueUUID
+	case dateRe.MatchString(value):
+		return ValueDate
+	case hexRe.MatchString(value) && digitRe.MatchString(value):
+		return ValueHash
+	case tokenRe.MatchString(value) && digitRe.MatchString(value):
+		return ValueHash
+	case strings.ContainsAny(value, " +\t\n") || len(value) > 64:
+		return ValueFreeText
+	}
+	return ValueEnum
+}
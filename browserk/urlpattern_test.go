@@ -0,0 +1,170 @@
+package browserk_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/wirepair/gcd/v2/gcdapi"
+	"gitlab.com/browserker/browserk"
+)
+
+func TestSyntacticClass(t *testing.T) {
+	var inputs = []struct {
+		in       string
+		expected browserk.ValueClass
+	}{
+		{"1234", browserk.ValueNumericID},
+		{"7d444840-9dc0-11d1-b245-5ffdce74fad2", browserk.ValueUUID},
+		{"d41d8cd98f00b204e9800998ecf8427e", browserk.ValueHash},
+		{"eyJhbGciOiJIUzI1NiJ9_abc123defghi", browserk.ValueHash},
+		{"2020-07-21", browserk.ValueDate},
+		{"21/07/2020", browserk.ValueDate},
+		{"red shoes", browserk.ValueFreeText},
+		{"admin", browserk.ValueEnum},
+		{"orders", browserk.ValueEnum},
+	}
+
+	for _, in := range inputs {
+		if class := browserk.SyntacticClass(in.in); class != in.expected {
+			t.Fatalf("%s: expected %s got %s\n", in.in, browserk.ValueClassMap[in.expected], browserk.ValueClassMap[class])
+		}
+	}
+}
+
+func TestURLPatternLearner(t *testing.T) {
+	l := browserk.NewURLPatternLearner()
+
+	var inputs = []struct {
+		in       string
+		expected string
+	}{
+		{"http://example.com/orders/1234", "http://example.com/orders/{id}"},
+		{"http://example.com/orders/1235?sort=asc", "http://example.com/orders/{id}?sort=asc"},
+		{"http://example.com/x.jsp?page=admin&id=5", "http://example.com/x.jsp?id={id}&page=admin"},
+		{"http://example.com/x.jsp?page=user&id=6", "http://example.com/x.jsp?id={id}&page=user"},
+		{"http://example.com/files/d41d8cd98f00b204e9800998ecf8427e/2020-07-21", "http://example.com/files/{hash}/{date}"},
+		{"/users/7d444840-9dc0-11d1-b245-5ffdce74fad2/edit#top", "/users/{uuid}/edit"},
+	}
+
+	for _, in := range inputs {
+		if pattern := l.Pattern(in.in); pattern != in.expected {
+			t.Fatalf("expected %s got %s\n", in.expected, pattern)
+		}
+	}
+
+	// slugs are enums until there are too many of them
+	for i := 0; i <= browserk.MaxEnumValues; i++ {
+		l.Pattern(fmt.Sprintf("http://example.com/blog/post-%c", 'a'+i))
+		l.Pattern(fmt.Sprintf("http://example.com/search?q=shoe%c", 'a'+i))
+	}
+
+	if pattern := l.Pattern("http://example.com/blog/another-post"); pattern != "http://example.com/blog/{text}" {
+		t.Fatalf("expected slugs to become free text got %s\n", pattern)
+	}
+
+	if pattern := l.Pattern("http://example.com/search?q=hat"); pattern != "http://example.com/search?q={text}" {
+		t.Fatalf("expected search terms to become free text got %s\n", pattern)
+	}
+
+	// positions are per pattern, other paths can still have enums
+	if pattern := l.Pattern("http://example.com/docs/another-post"); pattern != "http://example.com/docs/another-post" {
+		t.Fatalf("expected docs to keep its enum got %s\n", pattern)
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	var inputs = []struct {
+		in       string
+		expected string
+	}{
+		{"http://example.com/orders/1234", "http://example.com/orders/{id}"},
+		{"http://example.com/x.jsp?page=admin&id=5", "http://example.com/x.jsp?id={id}&page=admin"},
+		{"http://example.com/files/d41d8cd98f00b204e9800998ecf8427e/2020-07-21", "http://example.com/files/{hash}/{date}"},
+		{"/users/7d444840-9dc0-11d1-b245-5ffdce74fad2/edit#top", "/users/{uuid}/edit"},
+		{"http://example.com/search?q=red+shoes", "http://example.com/search?q={text}"},
+		{"http://example.com/blog/post-a", "http://example.com/blog/post-a"},
+	}
+
+	for _, in := range inputs {
+		if normalized := browserk.NormalizeURL(in.in); normalized != in.expected {
+			t.Fatalf("expected %s got %s\n", in.expected, normalized)
+		}
+	}
+
+	if text := browserk.NormalizeText(" Order  1234 from 2020-07-21 "); text != "Order {id} from {date}" {
+		t.Fatalf("expected dynamic words to be normalized got %s\n", text)
+	}
+}
+
+func TestHashUsesNormalizedURLs(t *testing.T) {
+	request := func(reqURL string) *browserk.HTTPRequest {
+		return &browserk.HTTPRequest{Request: &gcdapi.NetworkRequest{Method: "GET", Url: reqURL}}
+	}
+
+	if !bytes.Equal(request("http://example.com/orders/1234").Hash(), request("http://example.com/orders/1235").Hash()) {
+		t.Fatalf("expected requests differing by id to share a hash\n")
+	}
+
+	if bytes.Equal(request("http://example.com/x.jsp?page=admin").Hash(), request("http://example.com/x.jsp?page=user").Hash()) {
+		t.Fatalf("expected requests differing by enum value to have different hashes\n")
+	}
+
+	load := func(navURL string) *browserk.Navigation {
+		return browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction(navURL))
+	}
+
+	if !bytes.Equal(load("http://example.com/orders/1234").ID, load("http://example.com/orders/1235").ID) {
+		t.Fatalf("expected load url navigations differing by id to share an ID\n")
+	}
+
+	entry := load("http://example.com/")
+	link := func(href string) *browserk.Navigation {
+		ele := &browserk.HTMLElement{Type: browserk.A, InnerText: "details", Attributes: map[string]string{"href": href}}
+		return browserk.NewNavigationFromElement(entry, browserk.TrigCrawler, ele, browserk.ActLeftClick)
+	}
+
+	if !bytes.Equal(link("/orders/1234").ID, link("/orders/1235").ID) {
+		t.Fatalf("expected links differing by id to share a navigation ID\n")
+	}
+
+	if bytes.Equal(link("/orders?status=open").ID, link("/orders?status=closed").ID) {
+		t.Fatalf("expected links differing by enum value to have different navigation IDs\n")
+	}
+
+	order := func(href, text string) *browserk.Navigation {
+		ele := &browserk.HTMLElement{Type: browserk.A, InnerText: text, Attributes: map[string]string{"href": href}}
+		return browserk.NewNavigationFromElement(entry, browserk.TrigCrawler, ele, browserk.ActLeftClick)
+	}
+
+	if !bytes.Equal(order("/orders/1234", "Order 1234").ID, order("/orders/1235", "Order 1235").ID) {
+		t.Fatalf("expected links differing by id in their href and text to share a navigation ID\n")
+	}
+
+	button := func(text string) *browserk.Navigation {
+		ele := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: text}
+		return browserk.NewNavigationFromElement(entry, browserk.TrigCrawler, ele, browserk.ActLeftClick)
+	}
+
+	if !bytes.Equal(button("Cancel order 1234").ID, button("Cancel order 1235").ID) {
+		t.Fatalf("expected buttons differing by id in their text to share a navigation ID\n")
+	}
+
+	if bytes.Equal(button("Save").ID, button("Delete").ID) {
+		t.Fatalf("expected buttons with different text to have different navigation IDs\n")
+	}
+
+	if bytes.Equal(order("#", "Edit").ID, order("#", "Delete").ID) || bytes.Equal(order("javascript:void(0)", "Edit").ID, order("javascript:void(0)", "Delete").ID) {
+		t.Fatalf("expected links without a url to be told apart by their text\n")
+	}
+
+	// IDs must not depend on what was seen before, they are persisted and compared across (resumed) crawls
+	first := link("/blog/post-a").ID
+	for i := 0; i <= browserk.MaxEnumValues; i++ {
+		link(fmt.Sprintf("/blog/post-%c", 'b'+i))
+	}
+
+	if !bytes.Equal(first, link("/blog/post-a").ID) {
+		t.Fatalf("expected navigation ID to be stable\n")
+	}
+}
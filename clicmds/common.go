@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/emicklei/dot"
@@ -22,6 +23,12 @@ func writeReport(fileName string, cfg *browserk.Config, crawl browserk.CrawlGrap
 
 	failedEntries := crawl.Find(nil, browserk.NavFailed, browserk.NavFailed, 9999)
 	auditedEntries := crawl.Find(nil, browserk.NavAudited, browserk.NavAudited, 9999)
+	excludedEntries := crawl.Find(nil, browserk.NavExcluded, browserk.NavExcluded, 9999)
+
+	type excludedNav struct {
+		Navigation string `json:"navigation"`
+		Rule       string `json:"rule"`
+	}
 
 	type reportFormat struct {
 		Target          string             `json:"target"`
@@ -31,6 +38,7 @@ func writeReport(fileName string, cfg *browserk.Config, crawl browserk.CrawlGrap
 		AuditedURLs     []string           `json:"audited_urls"`
 		FailedNavCount  int                `json:"failed_nav_count"`
 		AuditedNavCount int                `json:"audited_nav_count"`
+		Excluded        []*excludedNav     `json:"excluded"`
 	}
 
 	r := &reportFormat{
@@ -40,6 +48,15 @@ func writeReport(fileName string, cfg *browserk.Config, crawl browserk.CrawlGrap
 		Findings:        reports,
 		FailedNavCount:  len(failedEntries),
 		AuditedNavCount: len(auditedEntries),
+		Excluded:        make([]*excludedNav, 0),
+	}
+
+	for _, path := range excludedEntries {
+		if len(path) == 0 {
+			continue
+		}
+		nav := path[len(path)-1]
+		r.Excluded = append(r.Excluded, &excludedNav{Navigation: nav.String(), Rule: nav.ExcludedReason})
 	}
 
 	results, err := crawl.GetNavigationResults()
@@ -111,8 +128,13 @@ func printSummary(crawl *store.CrawlGraph, dotFile string) error {
 	auditedEntries := crawl.Find(nil, browserk.NavAudited, browserk.NavAudited, 9999)
 	printEntries(auditedEntries, "audited")
 
+	excludedEntries := crawl.Find(nil, browserk.NavExcluded, browserk.NavExcluded, 9999)
+	printEntries(excludedEntries, "excluded")
+	printExcluded(excludedEntries)
+	printClusters(results)
+
 	if dotFile != "" {
-		printDOT(dotFile, auditedEntries, visitedEntries, unvisitedEntries, inProcessEntries, failedEntries)
+		printDOT(dotFile, auditedEntries, visitedEntries, unvisitedEntries, inProcessEntries, failedEntries, excludedEntries)
 	}
 	return nil
 }
@@ -131,7 +153,60 @@ func printEntries(entries [][]*browserk.Navigation, navType string) {
 	}
 }
 
-func printDOT(fileName string, audited, visited, unvisited, inprocess, failed [][]*browserk.Navigation) {
+// printExcluded navigations and why they were excluded (usually logouts) so they can be reviewed
+func printExcluded(entries [][]*browserk.Navigation) {
+	fmt.Printf("\n\nExcluded navigations:\n")
+	for _, paths := range entries {
+		if len(paths) == 0 {
+			continue
+		}
+		nav := paths[len(paths)-1]
+		fmt.Printf("ID: %x %s (%s)\n", string(nav.ID), nav, nav.ExcludedReason)
+	}
+}
+
+// printClusters of pages sharing a DOM skeleton, largest first. Only MaxPagesPerSkeleton pages of each were expanded.
+func printClusters(results []*browserk.NavigationResult) {
+	clusters := make(map[string][]string)
+	skeletons := make([]string, 0)
+	for _, result := range results {
+		if result.Skeleton == nil {
+			continue
+		}
+
+		skeleton := string(result.Skeleton)
+		pages, ok := clusters[skeleton]
+		if !ok {
+			skeletons = append(skeletons, skeleton)
+		}
+
+		found := false
+		for _, page := range pages {
+			if page == result.EndURL {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			clusters[skeleton] = append(pages, result.EndURL)
+		}
+	}
+
+	sort.SliceStable(skeletons, func(i, j int) bool {
+		return len(clusters[skeletons[i]]) > len(clusters[skeletons[j]])
+	})
+
+	fmt.Printf("\n\nPage clusters:\n")
+	for _, skeleton := range skeletons {
+		fmt.Printf("Skeleton: %x (%d pages)\n", skeleton, len(clusters[skeleton]))
+		for _, page := range clusters[skeleton] {
+			fmt.Printf("  %s\n", page)
+		}
+	}
+}
+
+func printDOT(fileName string, audited, visited, unvisited, inprocess, failed, excluded [][]*browserk.Navigation) {
 	g := dot.NewGraph(dot.Directed)
 	g.Attr("rankdir", "LR")
 	subGraph(g.Subgraph("Audited"), audited)
@@ -139,6 +214,7 @@ func printDOT(fileName string, audited, visited, unvisited, inprocess, failed []
 	subGraph(g.Subgraph("Unvisited"), unvisited)
 	subGraph(g.Subgraph("In Process"), inprocess)
 	subGraph(g.Subgraph("Failed"), failed)
+	subGraph(g.Subgraph("Excluded"), excluded)
 
 	ioutil.WriteFile(fileName, []byte(g.String()), 0677)
 }
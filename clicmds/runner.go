@@ -126,7 +126,7 @@ func Run(cliCtx *cli.Context) error {
 		return err
 	}
 
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
@@ -1,9 +1,32 @@
 package clicmds
 
-import "github.com/urfave/cli/v2"
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pelletier/go-toml"
+	"github.com/rs/zerolog/log"
+	"github.com/urfave/cli/v2"
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/scanner"
+	"gitlab.com/browserker/scanner/auth"
+	"gitlab.com/browserker/scanner/browser"
+)
 
 func TestAuthFlags() []cli.Flag {
 	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "config",
+			Usage: "config to use, user/pass/url override the config values",
+			Value: "",
+		},
 		&cli.StringFlag{
 			Name:  "user",
 			Usage: "username to auth with",
@@ -19,9 +42,201 @@ func TestAuthFlags() []cli.Flag {
 			Usage: "url to authenticate to",
 			Value: "http://localhost/login",
 		},
+		&cli.StringFlag{
+			Name:  "screenshots",
+			Usage: "directory to save login screenshots to",
+			Value: ".",
+		},
 	}
 }
 
-func TestAuth(ctx *cli.Context) error {
+// recordingBrowser keeps a copy of storage events since reading them clears them from the browser
+type recordingBrowser struct {
+	browserk.Browser
+	lock          sync.Mutex
+	storageEvents []*browserk.StorageEvent
+}
+
+func (r *recordingBrowser) GetStorageEvents() []*browserk.StorageEvent {
+	evts := r.Browser.GetStorageEvents()
+	r.lock.Lock()
+	r.storageEvents = append(r.storageEvents, evts...)
+	r.lock.Unlock()
+	return evts
+}
+
+// TestAuth runs the configured login, printing each step so login configurations can be debugged
+// without running a full scan
+func TestAuth(cliCtx *cli.Context) error {
+	cfg, err := testAuthConfig(cliCtx)
+	if err != nil {
+		return err
+	}
+
+	scope, err := scanner.NewScopeFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	authService := auth.New(cfg)
+	if err := authService.Init(); err != nil {
+		return cli.Exit(fmt.Sprintf("failed to init auth service: %s", err), 1)
+	}
+	defer authService.Close()
+
+	if !authService.MustLogin() {
+		return cli.Exit("auth is not configured, Credentials and AuthURL, AuthScript or RawAuth, or Registration are required", 1)
+	}
+	fmt.Printf("[*] logging in to %s\n", cfg.URL)
+	if sink := authService.MailSink(); sink != nil {
+		fmt.Printf("[*] registering %s, waiting for verification mail on smtp://%s\n", authService.Credentials().Email, sink.Addr())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*2)
+	defer cancel()
+	bctx := browserk.NewContext(ctx, cancel)
+	bctx.Log = &log.Logger
+	bctx.Auth = authService
+	bctx.AddReqHandler(authService.AddAuthHeader)
+	bctx.Scope = scope
+
+	leaser := browser.NewLocalLeaser()
+	if !cfg.DisableHeadless {
+		leaser.SetHeadless()
+	}
+	if cfg.Proxy != "" {
+		leaser.SetProxy(cfg.Proxy)
+	}
+
+	pool := browser.NewGCDBrowserPool(1, leaser)
+	if err := pool.Init(); err != nil {
+		return err
+	}
+	defer pool.Shutdown()
+
+	b, port, err := pool.Take(bctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Return(ctx, port)
+	defer b.Close()
+
+	if err := b.Init(cfg); err != nil {
+		return err
+	}
+	recorder := &recordingBrowser{Browser: b}
+
+	screenshotDir := cliCtx.String("screenshots")
+	stepCount := 0
+	authService.SetStepHandler(func(step string, br browserk.Browser) {
+		stepCount++
+		currentURL, _ := br.GetURL()
+		fmt.Printf("[*] step %d: %s (url: %s)\n", stepCount, step, currentURL)
+		if step != auth.StepBeforeSubmit && step != auth.StepAfterSubmit {
+			return
+		}
+
+		fileName := filepath.Join(screenshotDir, fmt.Sprintf("testauth_%d_%s.png", stepCount, step))
+		if err := saveScreenshot(br, fileName); err != nil {
+			fmt.Printf("[!] failed to save screenshot: %s\n", err)
+			return
+		}
+		fmt.Printf("[*] saved screenshot %s\n", fileName)
+	})
+
+	loginErr := authService.Login(bctx, recorder)
+
+	cookies, err := b.GetCookies()
+	if err != nil {
+		fmt.Printf("[!] failed to get cookies: %s\n", err)
+	}
+	fmt.Printf("[*] cookies (%d):\n", len(cookies))
+	for _, cookie := range cookies {
+		fmt.Printf("\t%s\n", cookie)
+	}
+
+	recorder.GetStorageEvents()
+	fmt.Printf("[*] storage events (%d):\n", len(recorder.storageEvents))
+	for _, evt := range recorder.storageEvents {
+		storageType := "sessionStorage"
+		if evt.IsLocalStorage {
+			storageType = "localStorage"
+		}
+		fmt.Printf("\t%s %s %s=%s\n", evt.SecurityOrigin, storageType, evt.Key, evt.NewValue)
+	}
+
+	if headers := authService.AuthHeaders(); headers != nil {
+		fmt.Printf("[*] bearer token will be sent with all in scope requests\n")
+		if expires, ok := authService.TokenExpiry(); ok {
+			fmt.Printf("[*] bearer token expires %s\n", expires)
+		}
+	}
+
+	if loginErr != nil {
+		return cli.Exit(fmt.Sprintf("[!] login failed: %s", loginErr), 1)
+	}
+
+	loggedIn, indicator := authService.CheckSession(bctx, b)
+	if !loggedIn {
+		return cli.Exit(fmt.Sprintf("[!] login failed: %s", indicator), 1)
+	}
+	fmt.Printf("[*] login successful, matched: %s\n", indicator)
 	return nil
 }
+
+func testAuthConfig(cliCtx *cli.Context) (*browserk.Config, error) {
+	cfg := &browserk.Config{}
+	cfg.FormData = &browserk.DefaultFormValues
+
+	if cliCtx.String("config") == "" {
+		target, err := url.Parse(cliCtx.String("url"))
+		if err != nil {
+			return nil, err
+		}
+		cfg.URL = cliCtx.String("url")
+		cfg.AllowedHosts = []string{target.Hostname()}
+		cfg.AuthType = browserk.Form
+		cfg.AuthURL = cliCtx.String("url")
+		cfg.Credentials = &browserk.Credentials{Username: cliCtx.String("user"), Password: cliCtx.String("pass")}
+		return cfg, nil
+	}
+
+	data, err := ioutil.ReadFile(cliCtx.String("config"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := toml.NewDecoder(strings.NewReader(string(data))).Decode(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.Credentials == nil {
+		cfg.Credentials = &browserk.Credentials{}
+	}
+
+	if cliCtx.IsSet("user") {
+		cfg.Credentials.Username = cliCtx.String("user")
+	}
+
+	if cliCtx.IsSet("pass") {
+		cfg.Credentials.Password = cliCtx.String("pass")
+	}
+
+	if cliCtx.IsSet("url") {
+		cfg.AuthURL = cliCtx.String("url")
+	}
+	return cfg, nil
+}
+
+func saveScreenshot(b browserk.Browser, fileName string) error {
+	encoded, err := b.Screenshot()
+	if err != nil {
+		return err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
@@ -16,16 +16,13 @@ func main() {
 	app.Authors = []*cli.Author{{Name: "isaac dawson", Email: "isaac.dawson@gmail.com"}}
 	app.Usage = "Analyzes a Web Site for Vulnerabilities"
 	app.Commands = []*cli.Command{
-		/*
-			Enable when auth is ready
-				{
-					Name:    "testauth",
-					Aliases: []string{"ta"},
-					Usage:   "test authentication",
-					Action:  clicmds.TestAuth,
-					Flags:   clicmds.TestAuthFlags(),
-				},
-		*/
+		{
+			Name:    "testauth",
+			Aliases: []string{"ta"},
+			Usage:   "test authentication",
+			Action:  clicmds.TestAuth,
+			Flags:   clicmds.TestAuthFlags(),
+		},
 		{
 			Name:    "run",
 			Aliases: []string{"r"},
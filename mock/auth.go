@@ -0,0 +1,84 @@
+package mock
+
+import (
+	"gitlab.com/browserker/browserk"
+)
+
+// AuthService logs in to the target
+type AuthService struct {
+	InitFn     func() error
+	InitCalled bool
+
+	LoginFn     func(c *browserk.Context, browser browserk.Browser) error
+	LoginCalled bool
+
+	IsLoggedInFn     func(c *browserk.Context, browser browserk.Browser) bool
+	IsLoggedInCalled bool
+
+	MustLoginFn     func() bool
+	MustLoginCalled bool
+
+	AuthHeadersFn     func() map[string]interface{}
+	AuthHeadersCalled bool
+
+	SessionFn     func() *browserk.SessionSnapshot
+	SessionCalled bool
+
+	RoleFn     func() *browserk.Role
+	RoleCalled bool
+}
+
+// Init the auth service
+func (a *AuthService) Init() error {
+	a.InitCalled = true
+	return a.InitFn()
+}
+
+// Login using the browser
+func (a *AuthService) Login(c *browserk.Context, browser browserk.Browser) error {
+	a.LoginCalled = true
+	return a.LoginFn(c, browser)
+}
+
+// IsLoggedIn checks the browser's session
+func (a *AuthService) IsLoggedIn(c *browserk.Context, browser browserk.Browser) bool {
+	a.IsLoggedInCalled = true
+	return a.IsLoggedInFn(c, browser)
+}
+
+// MustLogin if credentials were configured
+func (a *AuthService) MustLogin() bool {
+	a.MustLoginCalled = true
+	return a.MustLoginFn()
+}
+
+// AuthHeaders to send with every request
+func (a *AuthService) AuthHeaders() map[string]interface{} {
+	a.AuthHeadersCalled = true
+	return a.AuthHeadersFn()
+}
+
+// Session of the last login
+func (a *AuthService) Session() *browserk.SessionSnapshot {
+	a.SessionCalled = true
+	return a.SessionFn()
+}
+
+// Role we are logging in as
+func (a *AuthService) Role() *browserk.Role {
+	a.RoleCalled = true
+	return a.RoleFn()
+}
+
+// MakeMockAuthService logged in as the role with the provided session cookies
+func MakeMockAuthService(role *browserk.Role, cookies []*browserk.Cookie) *AuthService {
+	a := &AuthService{}
+	a.InitFn = func() error { return nil }
+	a.LoginFn = func(c *browserk.Context, browser browserk.Browser) error { return nil }
+	a.IsLoggedInFn = func(c *browserk.Context, browser browserk.Browser) bool { return true }
+	a.MustLoginFn = func() bool { return role != nil && role.Credentials != nil }
+	a.AuthHeadersFn = func() map[string]interface{} { return nil }
+	a.SessionFn = func() *browserk.SessionSnapshot { return &browserk.SessionSnapshot{Cookies: cookies} }
+	a.RoleFn = func() *browserk.Role { return role }
+	return a
+}
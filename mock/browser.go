@@ -0,0 +1,292 @@
+package mock
+
+import (
+	"context"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// Browser mock
+type Browser struct {
+	IDFn     func() int64
+	IDCalled bool
+
+	InitFn     func(cfg *browserk.Config) error
+	InitCalled bool
+
+	GetURLFn     func() (string, error)
+	GetURLCalled bool
+
+	GetDOMFn     func() (string, error)
+	GetDOMCalled bool
+
+	GetCookiesFn     func() ([]*browserk.Cookie, error)
+	GetCookiesCalled bool
+
+	SetCookiesFn     func(ctx context.Context, cookies []*browserk.Cookie) error
+	SetCookiesCalled bool
+
+	SnapshotSessionFn     func(ctx context.Context) (*browserk.SessionSnapshot, error)
+	SnapshotSessionCalled bool
+
+	RestoreSessionFn     func(ctx context.Context, snapshot *browserk.SessionSnapshot) error
+	RestoreSessionCalled bool
+
+	GetBaseHrefFn     func() string
+	GetBaseHrefCalled bool
+
+	GetStorageEventsFn     func() []*browserk.StorageEvent
+	GetStorageEventsCalled bool
+
+	GetConsoleEventsFn     func() []*browserk.ConsoleEvent
+	GetConsoleEventsCalled bool
+
+	GetDialogEventsFn     func() []*browserk.DialogEvent
+	GetDialogEventsCalled bool
+
+	GetOpenedURLsFn     func() []string
+	GetOpenedURLsCalled bool
+
+	GetFrameDocumentsFn     func() ([]*browserk.FrameDocument, error)
+	GetFrameDocumentsCalled bool
+
+	NavigateFn     func(ctx context.Context, url string) error
+	NavigateCalled bool
+
+	FindElementsFn     func(ctx context.Context, querySelector string, canRefreshDoc bool) ([]*browserk.HTMLElement, error)
+	FindElementsCalled bool
+
+	FindFormsFn     func(ctx context.Context) ([]*browserk.HTMLFormElement, error)
+	FindFormsCalled bool
+
+	FindInteractablesFn     func() ([]*browserk.HTMLElement, error)
+	FindInteractablesCalled bool
+
+	FindScrollablesFn     func(ctx context.Context) ([]*browserk.HTMLElement, error)
+	FindScrollablesCalled bool
+
+	ScrollFn     func(ctx context.Context, ele *browserk.HTMLElement) (bool, error)
+	ScrollCalled bool
+
+	GetMessagesFn     func() ([]*browserk.HTTPMessage, error)
+	GetMessagesCalled bool
+
+	ScreenshotFn     func() (string, error)
+	ScreenshotCalled bool
+
+	InjectRequestFn     func(ctx context.Context, method, URI string) error
+	InjectRequestCalled bool
+
+	AddBindingFn     func(ctx context.Context, name, script string, handler browserk.BindingHandler) error
+	AddBindingCalled bool
+
+	RefreshDocumentFn     func()
+	RefreshDocumentCalled bool
+
+	ExecuteActionFn     func(ctx context.Context, nav *browserk.Navigation) ([]byte, bool, error)
+	ExecuteActionCalled bool
+
+	CloseFn     func()
+	CloseCalled bool
+}
+
+// ID of the browser
+func (b *Browser) ID() int64 {
+	b.IDCalled = true
+	return b.IDFn()
+}
+
+// Init the browser
+func (b *Browser) Init(cfg *browserk.Config) error {
+	b.InitCalled = true
+	return b.InitFn(cfg)
+}
+
+// GetURL of the current page
+func (b *Browser) GetURL() (string, error) {
+	b.GetURLCalled = true
+	return b.GetURLFn()
+}
+
+// GetDOM of the current page
+func (b *Browser) GetDOM() (string, error) {
+	b.GetDOMCalled = true
+	return b.GetDOMFn()
+}
+
+// GetCookies of the browser
+func (b *Browser) GetCookies() ([]*browserk.Cookie, error) {
+	b.GetCookiesCalled = true
+	return b.GetCookiesFn()
+}
+
+// SetCookies in the browser
+func (b *Browser) SetCookies(ctx context.Context, cookies []*browserk.Cookie) error {
+	b.SetCookiesCalled = true
+	return b.SetCookiesFn(ctx, cookies)
+}
+
+// SnapshotSession of the current page
+func (b *Browser) SnapshotSession(ctx context.Context) (*browserk.SessionSnapshot, error) {
+	b.SnapshotSessionCalled = true
+	return b.SnapshotSessionFn(ctx)
+}
+
+// RestoreSession into the browser
+func (b *Browser) RestoreSession(ctx context.Context, snapshot *browserk.SessionSnapshot) error {
+	b.RestoreSessionCalled = true
+	return b.RestoreSessionFn(ctx, snapshot)
+}
+
+// GetBaseHref of the current page
+func (b *Browser) GetBaseHref() string {
+	b.GetBaseHrefCalled = true
+	return b.GetBaseHrefFn()
+}
+
+// GetStorageEvents captured
+func (b *Browser) GetStorageEvents() []*browserk.StorageEvent {
+	b.GetStorageEventsCalled = true
+	return b.GetStorageEventsFn()
+}
+
+// GetConsoleEvents captured
+func (b *Browser) GetConsoleEvents() []*browserk.ConsoleEvent {
+	b.GetConsoleEventsCalled = true
+	return b.GetConsoleEventsFn()
+}
+
+// GetDialogEvents captured
+func (b *Browser) GetDialogEvents() []*browserk.DialogEvent {
+	b.GetDialogEventsCalled = true
+	return b.GetDialogEventsFn()
+}
+
+// GetFrameDocuments of the current page
+func (b *Browser) GetFrameDocuments() ([]*browserk.FrameDocument, error) {
+	b.GetFrameDocumentsCalled = true
+	return b.GetFrameDocumentsFn()
+}
+
+// GetOpenedURLs of windows the page opened
+func (b *Browser) GetOpenedURLs() []string {
+	b.GetOpenedURLsCalled = true
+	return b.GetOpenedURLsFn()
+}
+
+// Navigate to the url
+func (b *Browser) Navigate(ctx context.Context, url string) error {
+	b.NavigateCalled = true
+	return b.NavigateFn(ctx, url)
+}
+
+// FindElements matching the query selector
+func (b *Browser) FindElements(ctx context.Context, querySelector string, canRefreshDoc bool) ([]*browserk.HTMLElement, error) {
+	b.FindElementsCalled = true
+	return b.FindElementsFn(ctx, querySelector, canRefreshDoc)
+}
+
+// FindForms in the current page
+func (b *Browser) FindForms(ctx context.Context) ([]*browserk.HTMLFormElement, error) {
+	b.FindFormsCalled = true
+	return b.FindFormsFn(ctx)
+}
+
+// FindInteractables in the current page
+func (b *Browser) FindInteractables() ([]*browserk.HTMLElement, error) {
+	b.FindInteractablesCalled = true
+	return b.FindInteractablesFn()
+}
+
+// FindScrollables in the current page
+func (b *Browser) FindScrollables(ctx context.Context) ([]*browserk.HTMLElement, error) {
+	b.FindScrollablesCalled = true
+	return b.FindScrollablesFn(ctx)
+}
+
+// Scroll the window or element
+func (b *Browser) Scroll(ctx context.Context, ele *browserk.HTMLElement) (bool, error) {
+	b.ScrollCalled = true
+	return b.ScrollFn(ctx, ele)
+}
+
+// GetMessages captured
+func (b *Browser) GetMessages() ([]*browserk.HTTPMessage, error) {
+	b.GetMessagesCalled = true
+	return b.GetMessagesFn()
+}
+
+// Screenshot of the current page
+func (b *Browser) Screenshot() (string, error) {
+	b.ScreenshotCalled = true
+	return b.ScreenshotFn()
+}
+
+// InjectRequest into the browser
+func (b *Browser) InjectRequest(ctx context.Context, method, URI string) error {
+	b.InjectRequestCalled = true
+	return b.InjectRequestFn(ctx, method, URI)
+}
+
+// AddBinding to the browser
+func (b *Browser) AddBinding(ctx context.Context, name, script string, handler browserk.BindingHandler) error {
+	b.AddBindingCalled = true
+	return b.AddBindingFn(ctx, name, script, handler)
+}
+
+// RefreshDocument of the current page
+func (b *Browser) RefreshDocument() {
+	b.RefreshDocumentCalled = true
+	b.RefreshDocumentFn()
+}
+
+// ExecuteAction of the navigation
+func (b *Browser) ExecuteAction(ctx context.Context, nav *browserk.Navigation) ([]byte, bool, error) {
+	b.ExecuteActionCalled = true
+	return b.ExecuteActionFn(ctx, nav)
+}
+
+// Close the browser
+func (b *Browser) Close() {
+	b.CloseCalled = true
+	b.CloseFn()
+}
+
+// MakeMockBrowser that does nothing and returns empty results
+func MakeMockBrowser() *Browser {
+	b := &Browser{}
+	b.IDFn = func() int64 { return 1 }
+	b.InitFn = func(cfg *browserk.Config) error { return nil }
+	b.GetURLFn = func() (string, error) { return "", nil }
+	b.GetDOMFn = func() (string, error) { return "", nil }
+	b.GetCookiesFn = func() ([]*browserk.Cookie, error) { return nil, nil }
+	b.SetCookiesFn = func(ctx context.Context, cookies []*browserk.Cookie) error { return nil }
+	b.SnapshotSessionFn = func(ctx context.Context) (*browserk.SessionSnapshot, error) {
+		return &browserk.SessionSnapshot{}, nil
+	}
+	b.RestoreSessionFn = func(ctx context.Context, snapshot *browserk.SessionSnapshot) error { return nil }
+	b.GetBaseHrefFn = func() string { return "" }
+	b.GetStorageEventsFn = func() []*browserk.StorageEvent { return nil }
+	b.GetConsoleEventsFn = func() []*browserk.ConsoleEvent { return nil }
+	b.GetDialogEventsFn = func() []*browserk.DialogEvent { return nil }
+	b.GetOpenedURLsFn = func() []string { return nil }
+	b.GetFrameDocumentsFn = func() ([]*browserk.FrameDocument, error) { return nil, nil }
+	b.NavigateFn = func(ctx context.Context, url string) error { return nil }
+	b.FindElementsFn = func(ctx context.Context, querySelector string, canRefreshDoc bool) ([]*browserk.HTMLElement, error) {
+		return nil, nil
+	}
+	b.FindFormsFn = func(ctx context.Context) ([]*browserk.HTMLFormElement, error) { return nil, nil }
+	b.FindInteractablesFn = func() ([]*browserk.HTMLElement, error) { return nil, nil }
+	b.FindScrollablesFn = func(ctx context.Context) ([]*browserk.HTMLElement, error) { return nil, nil }
+	b.ScrollFn = func(ctx context.Context, ele *browserk.HTMLElement) (bool, error) { return false, nil }
+	b.GetMessagesFn = func() ([]*browserk.HTTPMessage, error) { return nil, nil }
+	b.ScreenshotFn = func() (string, error) { return "", nil }
+	b.InjectRequestFn = func(ctx context.Context, method, URI string) error { return nil }
+	b.AddBindingFn = func(ctx context.Context, name, script string, handler browserk.BindingHandler) error { return nil }
+	b.RefreshDocumentFn = func() {}
+	b.ExecuteActionFn = func(ctx context.Context, nav *browserk.Navigation) ([]byte, bool, error) {
+		return nil, false, nil
+	}
+	b.CloseFn = func() {}
+	return b
+}
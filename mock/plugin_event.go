@@ -20,6 +20,7 @@ func MakeMockPluginEvent(url string, evt browserk.PluginEventType) *browserk.Plu
 	case browserk.EvtStorage:
 		return MakeMockStorageEvent(url)
 	case browserk.EvtURL:
+		return MakeMockURLEvent(url)
 	case browserk.EvtWebSocketRequest:
 	case browserk.EvtWebSocketResponse:
 	}
@@ -63,3 +64,8 @@ func MakeMockInterceptedHTTPResponseEvent(url string) *browserk.PluginEvent {
 func MakeMockStorageEvent(url string) *browserk.PluginEvent {
 	return nil
 }
+
+func MakeMockURLEvent(url string) *browserk.PluginEvent {
+	nav := MakeMockNavi([]byte{1, 2, 3})
+	return browserk.URLPluginEvent(nil, url, nav, &browserk.RouteEvent{Type: "pushState", URL: url})
+}
@@ -20,6 +20,9 @@ type PluginServicer struct {
 	DispatchEventFn     func(evt *browserk.PluginEvent)
 	DispatchEventCalled bool
 
+	CrawlCompleteFn     func(bctx *browserk.Context)
+	CrawlCompleteCalled bool
+
 	StoreFn     func() browserk.PluginStorer
 	StoreCalled bool
 
@@ -52,6 +55,11 @@ func (p *PluginServicer) DispatchEvent(evt *browserk.PluginEvent) {
 	p.DispatchEventFn(evt)
 }
 
+func (p *PluginServicer) CrawlComplete(bctx *browserk.Context) {
+	p.CrawlCompleteCalled = true
+	p.CrawlCompleteFn(bctx)
+}
+
 func (p *PluginServicer) Store() browserk.PluginStorer {
 	p.StoreCalled = true
 	return p.StoreFn()
@@ -107,6 +115,17 @@ func MakeMockPluginServicer() *PluginServicer {
 		}
 	}
 
+	p.CrawlCompleteFn = func(bctx *browserk.Context) {
+		evt := browserk.CrawlCompletePluginEvent(bctx)
+		pLock.RLock()
+		defer pLock.RUnlock()
+		for _, p := range plugins {
+			if p.Options().ListenCrawl {
+				p.OnEvent(evt)
+			}
+		}
+	}
+
 	p.InjectFn = func(mainContext *browserk.Context, injector browserk.Injector) {
 		for _, plugin := range plugins {
 			if plugin.Options().WriteRequests {
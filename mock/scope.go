@@ -11,12 +11,27 @@ type ScopeService struct {
 	AddScopeFn     func(inputs []string, scope browserk.Scope)
 	AddScopeCalled bool
 
+	AddRulesFn     func(rules []*browserk.ScopeRule) error
+	AddRulesCalled bool
+
 	AddExcludedURIsFn     func(inputs []string)
 	AddExcludedURIsCalled bool
 
 	ExcludeFormsFn     func(idsOrNames []string)
 	ExcludeFormsCalled bool
 
+	ExcludeElementsFn     func(rules []*browserk.ElementRule) error
+	ExcludeElementsCalled bool
+
+	ExcludedSelectorsFn     func() []string
+	ExcludedSelectorsCalled bool
+
+	ExcludedElementFn     func(ele *browserk.HTMLElement, selected map[string][]string) string
+	ExcludedElementCalled bool
+
+	ExcludedFormFn     func(form *browserk.HTMLFormElement, selected map[string][]string) string
+	ExcludedFormCalled bool
+
 	CheckFn     func(uri *url.URL) browserk.Scope
 	CheckCalled bool
 
@@ -42,6 +57,12 @@ func (s *ScopeService) AddScope(inputs []string, scope browserk.Scope) {
 	s.AddScopeFn(inputs, scope)
 }
 
+// AddRules to the scope service
+func (s *ScopeService) AddRules(rules []*browserk.ScopeRule) error {
+	s.AddRulesCalled = true
+	return s.AddRulesFn(rules)
+}
+
 // AddExcludedURIs so we don't logout or whatever
 // TODO: allow ability to add query params as well
 func (s *ScopeService) AddExcludedURIs(inputs []string) {
@@ -91,11 +112,34 @@ func (s *ScopeService) CheckRelative(host, relative string) browserk.Scope {
 
 // ExcludeForms based on name or id for html element
 func (s *ScopeService) ExcludeForms(idsOrNames []string) {
-	// TODO IMPLEMENT
 	s.ExcludeFormsCalled = true
 	s.ExcludeFormsFn(idsOrNames)
 }
 
+// ExcludeElements matching the rules
+func (s *ScopeService) ExcludeElements(rules []*browserk.ElementRule) error {
+	s.ExcludeElementsCalled = true
+	return s.ExcludeElementsFn(rules)
+}
+
+// ExcludedSelectors of the element rules
+func (s *ScopeService) ExcludedSelectors() []string {
+	s.ExcludedSelectorsCalled = true
+	return s.ExcludedSelectorsFn()
+}
+
+// ExcludedElement returns the rule the element matched
+func (s *ScopeService) ExcludedElement(ele *browserk.HTMLElement, selected map[string][]string) string {
+	s.ExcludedElementCalled = true
+	return s.ExcludedElementFn(ele, selected)
+}
+
+// ExcludedForm returns the rule the form matched
+func (s *ScopeService) ExcludedForm(form *browserk.HTMLFormElement, selected map[string][]string) string {
+	s.ExcludedFormCalled = true
+	return s.ExcludedFormFn(form, selected)
+}
+
 func MakeMockScopeService(target *url.URL) *ScopeService {
 	s := &ScopeService{}
 	s.GetTargetFn = func() *url.URL {
@@ -109,5 +153,21 @@ func MakeMockScopeService(target *url.URL) *ScopeService {
 	s.CheckFn = func(uri *url.URL) browserk.Scope {
 		return browserk.InScope
 	}
+	s.AddRulesFn = func(rules []*browserk.ScopeRule) error {
+		return nil
+	}
+	s.ExcludeFormsFn = func(idsOrNames []string) {}
+	s.ExcludeElementsFn = func(rules []*browserk.ElementRule) error {
+		return nil
+	}
+	s.ExcludedSelectorsFn = func() []string {
+		return nil
+	}
+	s.ExcludedElementFn = func(ele *browserk.HTMLElement, selected map[string][]string) string {
+		return ""
+	}
+	s.ExcludedFormFn = func(form *browserk.HTMLFormElement, selected map[string][]string) string {
+		return ""
+	}
 	return s
 }
@@ -1,22 +1,462 @@
 package auth
 
-import "gitlab.com/browserker/browserk"
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/scanner/crawler"
+)
+
+// Login steps passed to the StepHandler
+const (
+	StepLoginPageLoaded = "login_page_loaded"
+	StepBeforeSubmit    = "before_submit"
+	StepAfterSubmit     = "after_submit"
+)
+
+// StepHandler is called as a login progresses, used for debugging login configurations
+type StepHandler func(step string, browser browserk.Browser)
+
+// Service handles logging in to the target and checking if we are still logged in
 type Service struct {
+	cfg         *browserk.Config
+	role        *browserk.Role
+	authType    browserk.AuthType
+	formData    *browserk.FormData
+	formHandler *crawler.CrawlerFormHandler
+	authScript  string
+
+	loggedInRe  *regexp.Regexp
+	loggedOutRe *regexp.Regexp
+
+	stepHandler StepHandler
+
+	tokenLock    *sync.RWMutex
+	token        string // bearer token added to in scope requests
+	refreshToken string // used by RefreshAuth requests
+
+	sessionLock *sync.RWMutex
+	session     *browserk.SessionSnapshot // restored into new browsers so they don't have to login
+
+	mailSink     *MailSink      // receives verification mails for Register based authentication
+	linkRe       *regexp.Regexp // selects the verification link
+	registerLock *sync.Mutex
+	registered   bool // only register once if we can login afterwards
 }
 
+// New auth service for the configured credentials
 func New(cfg *browserk.Config) *Service {
-	return &Service{}
+	return &Service{cfg: cfg, authType: cfg.AuthType, tokenLock: &sync.RWMutex{}, sessionLock: &sync.RWMutex{}, registerLock: &sync.Mutex{}}
 }
 
+// NewForRole creates an auth service that logs in with the role's credentials instead, a bearer token
+// in the CustomHeaders is not used as the role only sends the token it obtained itself. Roles without
+// credentials are anonymous, so they don't run the AuthScript either.
+func NewForRole(cfg *browserk.Config, role *browserk.Role) *Service {
+	roleCfg := *cfg
+	roleCfg.Credentials = role.Credentials
+	roleCfg.CustomHeaders = WithoutAuthorization(cfg.CustomHeaders)
+	if role.Credentials == nil {
+		roleCfg.AuthScript = ""
+	}
+	s := New(&roleCfg)
+	s.role = role
+	return s
+}
+
+// Init the auth service, builds the form data used to fill login forms
+// out of the configured credentials
 func (s *Service) Init() error {
+	if s.role == nil {
+		s.tokenFromHeaders(s.cfg.CustomHeaders)
+	}
+
+	// generates credentials for the new account if none were configured
+	if s.authType == browserk.Register {
+		if err := s.initRegistration(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.compileIndicators(); err != nil {
+		return err
+	}
+
+	// no script provided, default to filling in the login form
+	if s.authType == browserk.Script && s.cfg.AuthScript == "" {
+		s.authType = browserk.Form
+	}
+
+	// scripts may carry their own secrets, so they are loaded even without credentials
+	if s.authType == browserk.Script {
+		if err := s.loadScript(); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.Credentials == nil {
+		return nil
+	}
+
+	formData := browserk.DefaultFormValues
+	if s.cfg.FormData != nil {
+		formData = *s.cfg.FormData
+	}
+
+	creds := s.cfg.Credentials
+	formData.UserName = creds.Username
+	formData.Email = creds.Email
+	formData.Password = creds.Password
+	// a lot of sites use the email address as the user name, and vice versa
+	if formData.Email == "" {
+		formData.Email = creds.Username
+	}
+	if formData.UserName == "" {
+		formData.UserName = creds.Email
+	}
+	s.formData = &formData
+	s.formHandler = crawler.NewCrawlerFormHandler(s.formData)
+	return s.formHandler.Init()
+}
+
+// MustLogin returns true if credentials were configured, or a login script which doesn't need them
+func (s *Service) MustLogin() bool {
+	if s.authType == browserk.Script {
+		return s.authScript != ""
+	}
+
+	if s.cfg.Credentials == nil {
+		return false
+	}
+
+	switch s.authType {
+	case browserk.Raw:
+		return s.cfg.RawAuth != nil && s.cfg.RawAuth.URL != ""
+	case browserk.Form:
+		return s.cfg.AuthURL != ""
+	case browserk.Register:
+		return s.cfg.Registration != nil && s.cfg.Registration.URL != ""
+	}
+	return false
+}
+
+// Login using the provided browser
+func (s *Service) Login(bctx *browserk.Context, browser browserk.Browser) error {
+	timeout := time.Second * 45
+	if s.authType == browserk.Register {
+		timeout += s.mailTimeout()
+	}
+	ctx, cancel := context.WithTimeout(bctx.Ctx, timeout)
+	defer cancel()
+
+	var err error
+	switch s.authType {
+	case browserk.Script:
+		err = s.scriptLogin(ctx, bctx, browser)
+	case browserk.Raw:
+		// the browser never loaded a page, so rawLogin snapshots the session itself
+		return s.rawLogin(ctx, bctx, browser)
+	case browserk.Form:
+		err = s.formLogin(ctx, bctx, browser)
+	case browserk.Register:
+		// relogins restore the registered session, so registerLogin snapshots it itself
+		return s.registerLogin(ctx, bctx, browser)
+	}
+
+	if err != nil {
+		return err
+	}
+	s.captureSession(ctx, bctx, browser)
 	return nil
 }
 
-func (s *Service) Login(c *browserk.Context) {
+// Role we are logging in as, nil if no roles were configured
+func (s *Service) Role() *browserk.Role {
+	return s.role
+}
 
+// Session returns the snapshot of the last successful login, nil if we haven't logged in yet
+func (s *Service) Session() *browserk.SessionSnapshot {
+	s.sessionLock.RLock()
+	defer s.sessionLock.RUnlock()
+	return s.session
 }
 
-func (s *Service) MustLogin() bool {
+// captureSession snapshots the browser's cookies and storage so new browsers can be restored
+// into the logged in state
+func (s *Service) captureSession(ctx context.Context, bctx *browserk.Context, browser browserk.Browser) {
+	snapshot, err := browser.SnapshotSession(ctx)
+	if err != nil {
+		bctx.Log.Warn().Err(err).Msg("failed to snapshot session, browsers will have to login")
+		return
+	}
+	bctx.Log.Info().Int("cookies", len(snapshot.Cookies)).Int("storage", len(snapshot.Storage)).Int("indexeddb", len(snapshot.IndexedDB)).Msg("captured session")
+	s.setSession(snapshot)
+}
+
+func (s *Service) setSession(snapshot *browserk.SessionSnapshot) {
+	s.sessionLock.Lock()
+	s.session = snapshot
+	s.sessionLock.Unlock()
+}
+
+// SetStepHandler to be notified of each step of the login
+func (s *Service) SetStepHandler(handler StepHandler) {
+	s.stepHandler = handler
+}
+
+func (s *Service) step(step string, browser browserk.Browser) {
+	if s.stepHandler != nil {
+		s.stepHandler(step, browser)
+	}
+}
+
+// AddAuthHeader is a browserk.RequestHandler that adds our bearer token (if any) to all in scope requests
+func (s *Service) AddAuthHeader(bctx *browserk.Context, browser browserk.Browser, i *browserk.InterceptedHTTPRequest) bool {
+	token := s.bearerToken()
+	if token == "" {
+		return false
+	}
+
+	u, err := url.Parse(i.Request.Url)
+	if err != nil || bctx.Scope.Check(u) != browserk.InScope {
+		return false
+	}
+	i.SetHeader("Authorization", "Bearer "+token)
+	return false
+}
+
+// IsLoggedIn checks if the browser's current page looks like we are logged in
+func (s *Service) IsLoggedIn(bctx *browserk.Context, browser browserk.Browser) bool {
+	loggedIn, reason := s.CheckSession(bctx, browser)
+	if !loggedIn {
+		bctx.Log.Info().Str("reason", reason).Msg("session appears to be logged out")
+	}
+	return loggedIn
+}
+
+// CheckSession uses the configured AuthIndicators to determine if we are logged in, returning
+// which indicators matched if we are, or the reason if we are not. If no indicators are
+// configured we fall back to looking for visible password fields.
+func (s *Service) CheckSession(bctx *browserk.Context, browser browserk.Browser) (bool, string) {
+	ctx, cancel := context.WithTimeout(bctx.Ctx, time.Second*10)
+	defer cancel()
+
+	indicators := s.cfg.AuthIndicators
+	if indicators == nil {
+		// if we can still see a password field, we most likely failed
+		if s.hasVisibleElement(ctx, bctx, browser, "input[type='password']") {
+			return false, "password input visible"
+		}
+		return true, "no password input visible"
+	}
+
+	matched := make([]string, 0)
+	if indicators.SessionCookie != "" {
+		cookies, err := browser.GetCookies()
+		if err != nil {
+			return false, "unable to get cookies"
+		}
+		if !hasCookie(cookies, indicators.SessionCookie) {
+			return false, "session cookie missing"
+		}
+		matched = append(matched, "session cookie "+indicators.SessionCookie+" set")
+	}
+
+	if indicators.LoggedInSelector != "" {
+		if !s.hasVisibleElement(ctx, bctx, browser, indicators.LoggedInSelector) {
+			return false, "logged in selector missing"
+		}
+		matched = append(matched, "logged in selector "+indicators.LoggedInSelector+" present")
+	}
+
+	if indicators.LoggedOutSelector != "" {
+		if s.hasVisibleElement(ctx, bctx, browser, indicators.LoggedOutSelector) {
+			return false, "logged out selector present"
+		}
+		matched = append(matched, "logged out selector "+indicators.LoggedOutSelector+" absent")
+	}
+
+	if s.loggedInRe != nil || s.loggedOutRe != nil {
+		dom, err := browser.GetDOM()
+		if err != nil {
+			return false, "unable to get dom"
+		}
+
+		if s.loggedInRe != nil {
+			if !s.loggedInRe.MatchString(dom) {
+				return false, "logged in regex did not match"
+			}
+			matched = append(matched, "logged in regex matched")
+		}
+
+		if s.loggedOutRe != nil {
+			if s.loggedOutRe.MatchString(dom) {
+				return false, "logged out regex matched"
+			}
+			matched = append(matched, "logged out regex did not match")
+		}
+	}
+	return true, strings.Join(matched, ", ")
+}
+
+func (s *Service) hasVisibleElement(ctx context.Context, bctx *browserk.Context, browser browserk.Browser, querySelector string) bool {
+	elements, err := browser.FindElements(ctx, querySelector, true)
+	if err != nil {
+		bctx.Log.Warn().Err(err).Str("selector", querySelector).Msg("failed to search for elements")
+		return false
+	}
+
+	for _, ele := range elements {
+		if !ele.Hidden {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) compileIndicators() error {
+	var err error
+	indicators := s.cfg.AuthIndicators
+	if indicators == nil {
+		return nil
+	}
+
+	if indicators.LoggedInRegex != "" {
+		if s.loggedInRe, err = regexp.Compile(indicators.LoggedInRegex); err != nil {
+			return err
+		}
+	}
+
+	if indicators.LoggedOutRegex != "" {
+		if s.loggedOutRe, err = regexp.Compile(indicators.LoggedOutRegex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasCookie(cookies []*browserk.Cookie, name string) bool {
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			return true
+		}
+	}
 	return false
 }
+
+// formLogin loads the AuthURL, finds the login form, fills it in with our
+// credentials and submits it.
+func (s *Service) formLogin(ctx context.Context, bctx *browserk.Context, browser browserk.Browser) error {
+	loadNav := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(s.cfg.AuthURL))
+	if _, _, err := browser.ExecuteAction(ctx, loadNav); err != nil {
+		return err
+	}
+	s.step(StepLoginPageLoaded, browser)
+
+	forms, err := browser.FindForms(ctx)
+	if err != nil {
+		return err
+	}
+
+	form := findLoginForm(forms)
+	if form == nil {
+		return browserk.ErrLoginFormNotFound
+	}
+	s.fillLoginForm(form)
+
+	bctx.Log.Info().Str("url", s.cfg.AuthURL).Str("action", form.GetAttribute("action")).Msg("submitting login form")
+	loginNav := browserk.NewNavigationFromForm(loadNav, browserk.TrigInitial, form)
+	s.step(StepBeforeSubmit, browser)
+	if _, _, err := browser.ExecuteAction(ctx, loginNav); err != nil {
+		return err
+	}
+	s.step(StepAfterSubmit, browser)
+
+	if !s.IsLoggedIn(bctx, browser) {
+		return browserk.ErrLoginFailed
+	}
+	s.captureStorageToken(bctx, browser)
+	bctx.Log.Info().Msg("login successful")
+	return nil
+}
+
+// fillLoginForm uses the crawler's form heuristics to determine which inputs are the
+// username/email/password fields. Inputs the heuristics can't figure out keep the form
+// handler's value, unless none was identified as the username, then the text input right
+// before the password is assumed to be it.
+func (s *Service) fillLoginForm(form *browserk.HTMLFormElement) {
+	form.FormType = browserk.FormLogin
+	formContext := s.formHandler.CreateFormContext(form)
+	foundUser := false
+	for eleHash, input := range formContext.Inputs {
+		ele := form.GetChildByHash([]byte(eleHash))
+		// hidden inputs keep the value the application set (csrf tokens etc)
+		if ele == nil || ele.Hidden || input.Type == "hidden" {
+			continue
+		}
+		ele.Value = s.formHandler.GetSuggestedInput(input)
+		if ele.Value != "" && (ele.Value == s.formData.UserName || ele.Value == s.formData.Email) {
+			foundUser = true
+		}
+	}
+
+	if !foundUser {
+		if ele := inputBeforePassword(form); ele != nil {
+			ele.Value = s.formData.UserName
+		}
+	}
+
+	form.SubmitButtonID = formContext.Submit
+	if form.SubmitButtonID == nil {
+		form.SubmitButtonID = defaultSubmitButton(form)
+	}
+}
+
+// inputBeforePassword returns the visible text or email input preceding the first password input
+func inputBeforePassword(form *browserk.HTMLFormElement) *browserk.HTMLElement {
+	var previous *browserk.HTMLElement
+	for _, child := range form.ChildElements {
+		if child.Type != browserk.INPUT || child.Hidden {
+			continue
+		}
+
+		switch child.GetAttribute("type") {
+		case "password":
+			return previous
+		case "", "text", "email":
+			previous = child
+		}
+	}
+	return nil
+}
+
+// defaultSubmitButton returns the first <button> without a type, as those are submit buttons
+func defaultSubmitButton(form *browserk.HTMLFormElement) []byte {
+	for _, child := range form.ChildElements {
+		if child.Type == browserk.BUTTON && child.GetAttribute("type") == "" {
+			return child.Hash()
+		}
+	}
+	return nil
+}
+
+// findLoginForm returns the first visible form with a password input
+func findLoginForm(forms []*browserk.HTMLFormElement) *browserk.HTMLFormElement {
+	for _, form := range forms {
+		if form.Hidden {
+			continue
+		}
+		for _, child := range form.ChildElements {
+			if child.Type == browserk.INPUT && child.GetAttribute("type") == "password" && !child.Hidden {
+				return form
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"testing"
+
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/mock"
+	"gitlab.com/browserker/scanner/auth"
+)
+
+func TestFormLogin(t *testing.T) {
+	var tests = []struct {
+		name     string
+		form     *browserk.HTMLFormElement
+		expected map[string]string // input name -> value
+		submit   string            // name of the submit button
+	}{
+		{
+			name: "username",
+			form: &browserk.HTMLFormElement{
+				Type: browserk.FORM,
+				ChildElements: []*browserk.HTMLElement{
+					{Type: browserk.INPUT, Attributes: map[string]string{"type": "hidden", "name": "csrf"}, Value: "token"},
+					{Type: browserk.INPUT, Attributes: map[string]string{"type": "text", "name": "username"}},
+					{Type: browserk.INPUT, Attributes: map[string]string{"type": "password", "name": "password"}},
+					{Type: browserk.INPUT, Attributes: map[string]string{"type": "text", "name": "otp"}},
+					{Type: browserk.BUTTON, Attributes: map[string]string{"type": "button", "name": "show"}},
+					{Type: browserk.BUTTON, Attributes: map[string]string{"type": "submit", "name": "login"}},
+				},
+			},
+			expected: map[string]string{"csrf": "token", "username": "user", "password": "pass", "otp": browserk.DefaultFormValues.Default},
+			submit:   "login",
+		},
+		{
+			name: "email",
+			form: &browserk.HTMLFormElement{
+				Type: browserk.FORM,
+				ChildElements: []*browserk.HTMLElement{
+					{Type: browserk.INPUT, Attributes: map[string]string{"type": "email", "name": "address"}},
+					{Type: browserk.INPUT, Attributes: map[string]string{"type": "password", "name": "password"}},
+					{Type: browserk.BUTTON, Attributes: map[string]string{"name": "signin"}},
+				},
+			},
+			expected: map[string]string{"address": "user@example.com", "password": "pass"},
+			submit:   "signin",
+		},
+		{
+			name: "unidentified username",
+			form: &browserk.HTMLFormElement{
+				Type: browserk.FORM,
+				ChildElements: []*browserk.HTMLElement{
+					{Type: browserk.INPUT, Attributes: map[string]string{"type": "search", "name": "q"}},
+					{Type: browserk.INPUT, Attributes: map[string]string{"type": "text", "name": "login"}},
+					{Type: browserk.INPUT, Attributes: map[string]string{"type": "password", "name": "secret"}},
+					{Type: browserk.INPUT, Attributes: map[string]string{"type": "submit", "name": "go"}},
+				},
+			},
+			expected: map[string]string{"q": browserk.DefaultFormValues.SearchTerm, "login": "user", "secret": "pass"},
+			submit:   "go",
+		},
+	}
+
+	cfg := &browserk.Config{
+		URL:         "http://localhost/",
+		AuthType:    browserk.Form,
+		AuthURL:     "http://localhost/login",
+		Credentials: &browserk.Credentials{Username: "user", Email: "user@example.com", Password: "pass"},
+	}
+	target, _ := url.Parse(cfg.URL)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := auth.New(cfg)
+			if err := service.Init(); err != nil {
+				t.Fatalf("error init auth service: %s\n", err)
+			}
+
+			browser := mock.MakeMockBrowser()
+			browser.FindFormsFn = func(ctx context.Context) ([]*browserk.HTMLFormElement, error) {
+				return []*browserk.HTMLFormElement{tt.form}, nil
+			}
+
+			var submitted *browserk.HTMLFormElement
+			browser.ExecuteActionFn = func(ctx context.Context, nav *browserk.Navigation) ([]byte, bool, error) {
+				if nav.Action.Type == browserk.ActFillForm {
+					submitted = nav.Action.Form
+				}
+				return nil, false, nil
+			}
+
+			bctx := mock.MakeMockContext(context.Background(), target)
+			if err := service.Login(bctx, browser); err != nil {
+				t.Fatalf("error logging in: %s\n", err)
+			}
+
+			if submitted != tt.form {
+				t.Fatalf("expected the login form to be submitted\n")
+			}
+
+			for _, child := range submitted.ChildElements {
+				name := child.GetAttribute("name")
+				if expected, ok := tt.expected[name]; ok && child.Value != expected {
+					t.Fatalf("expected %s to be filled with %s got %s\n", name, expected, child.Value)
+				}
+
+				if name == tt.submit && !bytes.Equal(submitted.SubmitButtonID, child.Hash()) {
+					t.Fatalf("expected %s to submit the form\n", name)
+				}
+			}
+		})
+	}
+}
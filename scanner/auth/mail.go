@@ -0,0 +1,358 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	linkRe = regexp.MustCompile(`https?://[^\s"'<>]+`)
+	// links in verification mails usually contain one of these
+	verificationLinkRe = regexp.MustCompile(`(?i)verif|confirm|activat|token|validat|register`)
+)
+
+var (
+	// sinks shared by every auth service of the scan, keyed by the configured address
+	sinksLock = &sync.Mutex{}
+	sinks     = make(map[string]*MailSink)
+)
+
+// maxMailSize we will accept, verification mails are small
+const maxMailSize = 10 * 1024 * 1024
+
+// Mail received by the MailSink
+type Mail struct {
+	From     string
+	To       []string
+	Subject  string
+	Body     string // decoded text and html parts
+	Received time.Time
+}
+
+// Links in the mail body
+func (m *Mail) Links() []string {
+	links := make([]string, 0)
+	for _, link := range linkRe.FindAllString(m.Body, -1) {
+		links = append(links, html.UnescapeString(strings.TrimRight(link, ".,;)")))
+	}
+	return links
+}
+
+// VerificationLink returns the first link matching linkRegex, or if nil the first link
+// that looks like a verification link falling back to the first link in the mail
+func (m *Mail) VerificationLink(linkRegex *regexp.Regexp) string {
+	links := m.Links()
+	re := linkRegex
+	if re == nil {
+		re = verificationLinkRe
+	}
+
+	for _, link := range links {
+		if re.MatchString(link) {
+			return link
+		}
+	}
+
+	if linkRegex == nil && len(links) > 0 {
+		return links[0]
+	}
+	return ""
+}
+
+// MailSink is a minimal SMTP server that accepts all mail so we can read verification links
+// sent by the target. It does not support auth or TLS, it's only meant to be a local stand in.
+type MailSink struct {
+	addr     string
+	listener net.Listener
+
+	refs int // auth services sharing the sink, guarded by sinksLock
+
+	lock     *sync.Mutex
+	mails    []*Mail
+	received chan struct{} // closed and replaced every time a mail is received
+}
+
+// NewMailSink that will listen on addr (host:port)
+func NewMailSink(addr string) *MailSink {
+	return &MailSink{addr: addr, lock: &sync.Mutex{}, received: make(chan struct{})}
+}
+
+// Start listening for mail
+func (m *MailSink) Start() error {
+	listener, err := net.Listen("tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	m.listener = listener
+	go m.serve()
+	return nil
+}
+
+// Addr the sink is listening on
+func (m *MailSink) Addr() string {
+	if m.listener == nil {
+		return m.addr
+	}
+	return m.listener.Addr().String()
+}
+
+// Close the listener
+func (m *MailSink) Close() error {
+	if m.listener == nil {
+		return nil
+	}
+	return m.listener.Close()
+}
+
+// acquireMailSink returns the sink listening on addr, starting it for the first auth service
+// so roles registering in the same scan don't all try to bind the same address
+func acquireMailSink(addr string) (*MailSink, error) {
+	sinksLock.Lock()
+	defer sinksLock.Unlock()
+
+	if sink, ok := sinks[addr]; ok {
+		sink.refs++
+		return sink, nil
+	}
+
+	sink := NewMailSink(addr)
+	if err := sink.Start(); err != nil {
+		return nil, err
+	}
+	sink.refs = 1
+	sinks[addr] = sink
+	return sink, nil
+}
+
+// releaseMailSink closes the sink once the last auth service using it is done
+func releaseMailSink(sink *MailSink) error {
+	sinksLock.Lock()
+	defer sinksLock.Unlock()
+
+	sink.refs--
+	if sink.refs > 0 {
+		return nil
+	}
+	delete(sinks, sink.addr)
+	return sink.Close()
+}
+
+// WaitFor the next mail sent to recipient that was received after since
+func (m *MailSink) WaitFor(ctx context.Context, recipient string, since time.Time) (*Mail, error) {
+	for {
+		m.lock.Lock()
+		received := m.received
+		for _, mail := range m.mails {
+			if mail.Received.Before(since) {
+				continue
+			}
+			for _, to := range mail.To {
+				if strings.EqualFold(to, recipient) {
+					m.lock.Unlock()
+					return mail, nil
+				}
+			}
+		}
+		m.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for mail to %s: %w", recipient, ctx.Err())
+		case <-received:
+		}
+	}
+}
+
+func (m *MailSink) add(mail *Mail) {
+	m.lock.Lock()
+	m.mails = append(m.mails, mail)
+	close(m.received)
+	m.received = make(chan struct{})
+	m.lock.Unlock()
+}
+
+func (m *MailSink) serve() {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			return
+		}
+		go m.handle(conn)
+	}
+}
+
+// handle an SMTP session, just enough of RFC 5321 for an application to deliver mail
+func (m *MailSink) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute * 2))
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(w, format+"\r\n", args...)
+		w.Flush()
+	}
+
+	reply("220 browserker ESMTP ready")
+	var from string
+	var to []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(line)
+		if idx := strings.Index(verb, " "); idx != -1 {
+			verb = verb[:idx]
+		}
+
+		switch verb {
+		case "HELO":
+			reply("250 browserker")
+		case "EHLO":
+			reply("250-browserker")
+			reply("250 SIZE %d", maxMailSize)
+		case "MAIL":
+			from = smtpAddress(line)
+			to = nil
+			reply("250 OK")
+		case "RCPT":
+			to = append(to, smtpAddress(line))
+			reply("250 OK")
+		case "DATA":
+			if len(to) == 0 {
+				reply("503 need RCPT first")
+				continue
+			}
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data, err := readData(r)
+			if err != nil {
+				return
+			}
+			mail, err := parseMail(from, to, data)
+			if err != nil {
+				log.Warn().Err(err).Msg("failed to parse received mail")
+				reply("554 unable to parse message")
+				continue
+			}
+			log.Info().Str("from", from).Strs("to", to).Str("subject", mail.Subject).Msg("received mail")
+			m.add(mail)
+			reply("250 OK")
+		case "RSET":
+			from, to = "", nil
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// smtpAddress from MAIL FROM:<addr> or RCPT TO:<addr> SIZE=...
+func smtpAddress(line string) string {
+	start := strings.Index(line, "<")
+	end := strings.Index(line, ">")
+	if start != -1 && end > start {
+		return line[start+1 : end]
+	}
+
+	if idx := strings.Index(line, ":"); idx != -1 {
+		return strings.Fields(line[idx+1:] + " ")[0]
+	}
+	return ""
+}
+
+// readData until the terminating . line, undoing dot stuffing
+func readData(r *bufio.Reader) ([]byte, error) {
+	data := &bytes.Buffer{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.TrimRight(line, "\r\n") == "." {
+			return data.Bytes(), nil
+		}
+
+		if data.Len() > maxMailSize {
+			continue
+		}
+		data.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+func parseMail(from string, to []string, data []byte) (*Mail, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	body, err := decodePart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Mail{From: from, To: to, Subject: subject, Body: body, Received: time.Now()}, nil
+}
+
+// decodePart returns the decoded text of a message part, concatenating all parts of multipart messages
+func decodePart(contentType, encoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		parts := make([]string, 0)
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+
+			text, err := decodePart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, text)
+		}
+		return strings.Join(parts, "\n"), nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+
+	data, err := ioutil.ReadAll(body)
+	return string(data), err
+}
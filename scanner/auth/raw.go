@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// rawSession is what we extracted from the raw login response
+type rawSession struct {
+	cookies      []*browserk.Cookie
+	token        string
+	refreshToken string
+}
+
+// templateData is available to RawAuth Header and Body templates
+type templateData struct {
+	*browserk.Credentials
+	Token        string
+	RefreshToken string
+}
+
+var rawTemplateFuncs = template.FuncMap{
+	// json encodes the value so credentials can be safely used in JSON bodies
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// rawLogin sends the configured RawAuth request and installs the session cookies in the browser,
+// bearer tokens are added to all in scope requests via AddAuthHeader
+func (s *Service) rawLogin(ctx context.Context, bctx *browserk.Context, browser browserk.Browser) error {
+	s.step(StepBeforeSubmit, browser)
+	session, err := s.rawExchange(ctx, s.cfg.RawAuth)
+	if err != nil {
+		return err
+	}
+
+	if len(session.cookies) == 0 && session.token == "" {
+		return browserk.ErrLoginFailed
+	}
+	s.setTokens(session.token, session.refreshToken)
+
+	if err := browser.SetCookies(ctx, session.cookies); err != nil {
+		return err
+	}
+	s.setSession(&browserk.SessionSnapshot{Cookies: session.cookies, Taken: time.Now()})
+	s.step(StepAfterSubmit, browser)
+	bctx.Log.Info().Int("cookies", len(session.cookies)).Bool("token", session.token != "").Msg("login successful")
+	return nil
+}
+
+// rawExchange sends the raw request and extracts the session from the response
+func (s *Service) rawExchange(ctx context.Context, raw *browserk.RawAuth) (*rawSession, error) {
+	req, err := s.buildRawRequest(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if s.cfg.Proxy != "" {
+		proxyURL, err := url.Parse(s.cfg.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   time.Second * 30,
+		// login endpoints commonly set the session cookie in a redirect
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%w: raw login returned status %d", browserk.ErrLoginFailed, resp.StatusCode)
+	}
+
+	session := &rawSession{cookies: s.extractCookies(raw, req.URL, resp.Cookies())}
+	if raw.TokenPath != "" {
+		if session.token, err = lookupJSONPath(body, raw.TokenPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw.RefreshTokenPath != "" {
+		if session.refreshToken, err = lookupJSONPath(body, raw.RefreshTokenPath); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+func (s *Service) buildRawRequest(ctx context.Context, raw *browserk.RawAuth) (*http.Request, error) {
+	method := raw.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	body, err := s.executeTemplate("body", raw.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, raw.URL, bytes.NewBufferString(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range raw.Headers {
+		header, err := s.executeTemplate(name, value)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(name, header)
+	}
+
+	if req.Header.Get("Content-Type") == "" && body != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if strings.HasPrefix(strings.TrimSpace(body), "{") {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+	return req, nil
+}
+
+func (s *Service) executeTemplate(name, text string) (string, error) {
+	tmpl, err := template.New(name).Funcs(rawTemplateFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	buf := &bytes.Buffer{}
+	token, refreshToken := s.tokens()
+	data := &templateData{Credentials: s.cfg.Credentials, Token: token, RefreshToken: refreshToken}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// extractCookies converts the response cookies, defaulting their domain to the login host
+func (s *Service) extractCookies(raw *browserk.RawAuth, loginURL *url.URL, respCookies []*http.Cookie) []*browserk.Cookie {
+	cookies := make([]*browserk.Cookie, 0)
+	for _, c := range respCookies {
+		if !keepCookie(raw, c.Name) {
+			continue
+		}
+
+		cookie := &browserk.Cookie{
+			Name:         c.Name,
+			Value:        c.Value,
+			Domain:       c.Domain,
+			Path:         c.Path,
+			HTTPOnly:     c.HttpOnly,
+			Secure:       c.Secure,
+			Session:      c.Expires.IsZero() && c.MaxAge == 0,
+			ObservedTime: time.Now(),
+		}
+
+		if cookie.Domain == "" {
+			cookie.Domain = loginURL.Hostname()
+		}
+
+		if !c.Expires.IsZero() {
+			cookie.Expires = float64(c.Expires.Unix())
+		} else if c.MaxAge > 0 {
+			cookie.Expires = float64(time.Now().Add(time.Second * time.Duration(c.MaxAge)).Unix())
+		}
+
+		switch c.SameSite {
+		case http.SameSiteLaxMode:
+			cookie.SameSite = "Lax"
+		case http.SameSiteStrictMode:
+			cookie.SameSite = "Strict"
+		case http.SameSiteNoneMode:
+			cookie.SameSite = "None"
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
+func keepCookie(raw *browserk.RawAuth, name string) bool {
+	if len(raw.Cookies) == 0 {
+		return true
+	}
+
+	for _, keep := range raw.Cookies {
+		if keep == name {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupJSONPath walks the dot separated path (numeric parts index arrays) and returns the string value
+func lookupJSONPath(body []byte, path string) (string, error) {
+	var current interface{}
+	if err := json.Unmarshal(body, &current); err != nil {
+		return "", err
+	}
+
+	for _, part := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[part]
+			if !ok {
+				return "", fmt.Errorf("token path %s: %s not found", path, part)
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return "", fmt.Errorf("token path %s: invalid index %s", path, part)
+			}
+			current = v[idx]
+		default:
+			return "", fmt.Errorf("token path %s: unable to traverse %s", path, part)
+		}
+	}
+
+	token, ok := current.(string)
+	if !ok {
+		return "", fmt.Errorf("token path %s: value is not a string", path)
+	}
+	return token, nil
+}
@@ -0,0 +1,97 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/wirepair/gcd/v2/gcdapi"
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/mock"
+	"gitlab.com/browserker/scanner/auth"
+)
+
+func TestRawLogin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		login := make(map[string]string)
+		if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
+			t.Fatalf("failed to decode login body: %s\n", err)
+		}
+
+		if r.Header.Get("X-Tenant") != "acme" || login["user"] != "admin" || login["pass"] != `pa"ss` {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abcd", HttpOnly: true})
+		http.SetCookie(w, &http.Cookie{Name: "tracking", Value: "1"})
+		w.Write([]byte(`{"data": {"tokens": [{"access_token": "jwt.token.here"}]}}`))
+	}))
+	defer srv.Close()
+
+	cfg := &browserk.Config{
+		URL:         srv.URL,
+		AuthType:    browserk.Raw,
+		Credentials: &browserk.Credentials{Username: "admin", Password: `pa"ss`},
+		RawAuth: &browserk.RawAuth{
+			URL:       srv.URL + "/api/login",
+			Headers:   map[string]string{"X-Tenant": "acme"},
+			Body:      `{"user": {{json .Username}}, "pass": {{json .Password}}}`,
+			TokenPath: "data.tokens.0.access_token",
+			Cookies:   []string{"session"},
+		},
+	}
+
+	service := auth.New(cfg)
+	if err := service.Init(); err != nil {
+		t.Fatalf("error init auth service: %s\n", err)
+	}
+
+	if !service.MustLogin() {
+		t.Fatalf("expected MustLogin for raw auth\n")
+	}
+
+	target, _ := url.Parse(srv.URL)
+	bctx := mock.MakeMockContext(context.Background(), target)
+	browser := mock.MakeMockBrowser()
+
+	var installed []*browserk.Cookie
+	browser.SetCookiesFn = func(ctx context.Context, cookies []*browserk.Cookie) error {
+		installed = cookies
+		return nil
+	}
+
+	if err := service.Login(bctx, browser); err != nil {
+		t.Fatalf("error logging in: %s\n", err)
+	}
+
+	if len(installed) != 1 || installed[0].Name != "session" || installed[0].Domain != target.Hostname() || !installed[0].HTTPOnly {
+		t.Fatalf("expected only the session cookie to be installed got %#v\n", installed)
+	}
+
+	if session := service.Session(); session == nil || len(session.Cookies) != 1 {
+		t.Fatalf("expected session to be captured for restoring into new browsers got %#v\n", session)
+	}
+
+	req := &browserk.InterceptedHTTPRequest{
+		Request:        &gcdapi.NetworkRequest{Url: srv.URL + "/api/users"},
+		RequestHeaders: []*gcdapi.FetchHeaderEntry{{Name: "Accept", Value: "*/*"}, {Name: "authorization", Value: "Bearer old"}},
+		Modified:       &browserk.HTTPModifiedRequest{},
+	}
+	service.AddAuthHeader(bctx, browser, req)
+
+	if len(req.Modified.Headers) != 2 {
+		t.Fatalf("expected original headers to be kept with authorization replaced got %#v\n", req.Modified.Headers)
+	}
+
+	if req.Modified.Headers[1].Name != "Authorization" || req.Modified.Headers[1].Value != "Bearer jwt.token.here" {
+		t.Fatalf("expected bearer token to be added got %#v\n", req.Modified.Headers[1])
+	}
+
+	cfg.Credentials.Password = "wrong"
+	if err := service.Login(bctx, browser); err == nil {
+		t.Fatalf("expected login to fail with invalid credentials\n")
+	}
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// Registration steps passed to the StepHandler
+const (
+	StepRegistrationPageLoaded = "registration_page_loaded"
+	StepVerificationReceived   = "verification_received"
+	StepVerified               = "verified"
+)
+
+const (
+	defaultSMTPAddr    = "127.0.0.1:2525"
+	defaultMailTimeout = 120
+)
+
+// registrationCredentials creates unique credentials out of the FormData so we can register
+// a new account every scan. The email uses plus addressing, the mail sink accepts any recipient.
+func registrationCredentials(formData *browserk.FormData) *browserk.Credentials {
+	suffix := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 36)
+
+	email := formData.Email
+	if at := strings.LastIndex(email, "@"); at != -1 {
+		email = email[:at] + "+" + suffix + email[at:]
+	} else {
+		email = "browserker+" + suffix + "@example.com"
+	}
+
+	return &browserk.Credentials{
+		Username: formData.UserName + suffix,
+		Email:    email,
+		Password: formData.Password,
+	}
+}
+
+// initRegistration generates the credentials to register with (unless configured) and starts
+// the mail sink, or shares the one another role already started
+func (s *Service) initRegistration() error {
+	reg := s.cfg.Registration
+	if reg == nil {
+		return nil
+	}
+
+	if reg.LinkRegex != "" {
+		re, err := regexp.Compile(reg.LinkRegex)
+		if err != nil {
+			return err
+		}
+		s.linkRe = re
+	}
+
+	if s.cfg.Credentials == nil {
+		formData := browserk.DefaultFormValues
+		if s.cfg.FormData != nil {
+			formData = *s.cfg.FormData
+		}
+		// don't modify the shared config
+		cfg := *s.cfg
+		cfg.Credentials = registrationCredentials(&formData)
+		s.cfg = &cfg
+	}
+
+	addr := reg.SMTPAddr
+	if addr == "" {
+		addr = defaultSMTPAddr
+	}
+	sink, err := acquireMailSink(addr)
+	if err != nil {
+		return err
+	}
+	s.mailSink = sink
+	return nil
+}
+
+// Credentials we login with, generated for Register based authentication if none were configured
+func (s *Service) Credentials() *browserk.Credentials {
+	return s.cfg.Credentials
+}
+
+// MailSink receiving mail for Register based authentication, nil for other auth types
+func (s *Service) MailSink() *MailSink {
+	return s.mailSink
+}
+
+// Close the mail sink once no other role uses it
+func (s *Service) Close() error {
+	if s.mailSink == nil {
+		return nil
+	}
+	sink := s.mailSink
+	s.mailSink = nil
+	return releaseMailSink(sink)
+}
+
+// mailTimeout for waiting on the verification mail
+func (s *Service) mailTimeout() time.Duration {
+	if s.cfg.Registration == nil || s.cfg.Registration.MailTimeout <= 0 {
+		return time.Second * defaultMailTimeout
+	}
+	return time.Second * time.Duration(s.cfg.Registration.MailTimeout)
+}
+
+// registerLogin registers a new account unless we already did, then logs in with the new credentials.
+// If no AuthURL was configured we assume opening the verification link logs us in, later logins
+// restore the session captured after verifying as registering the same address again would fail.
+// The session is captured here, before waiting logins can try to restore it.
+func (s *Service) registerLogin(ctx context.Context, bctx *browserk.Context, browser browserk.Browser) error {
+	// held while registering so concurrent logins don't register twice
+	s.registerLock.Lock()
+	if s.registered {
+		s.registerLock.Unlock()
+		login := s.restoreRegistered
+		if s.cfg.AuthURL != "" {
+			login = s.formLogin
+		}
+		if err := login(ctx, bctx, browser); err != nil {
+			return err
+		}
+		s.captureSession(ctx, bctx, browser)
+		return nil
+	}
+	defer s.registerLock.Unlock()
+
+	if err := s.register(ctx, bctx, browser); err != nil {
+		return err
+	}
+	s.registered = true
+
+	if s.cfg.AuthURL != "" {
+		if err := s.formLogin(ctx, bctx, browser); err != nil {
+			return err
+		}
+	} else {
+		if !s.IsLoggedIn(bctx, browser) {
+			return browserk.ErrLoginFailed
+		}
+		s.captureStorageToken(bctx, browser)
+		bctx.Log.Info().Msg("login successful")
+	}
+	s.captureSession(ctx, bctx, browser)
+	return nil
+}
+
+// restoreRegistered logs in to the account we registered by restoring the session captured after
+// the verification link logged us in
+func (s *Service) restoreRegistered(ctx context.Context, bctx *browserk.Context, browser browserk.Browser) error {
+	snapshot := s.Session()
+	if snapshot == nil {
+		return fmt.Errorf("%w: already registered %s but no session was captured, configure AuthURL to login again", browserk.ErrLoginFailed, s.cfg.Credentials.Email)
+	}
+
+	if err := browser.RestoreSession(ctx, snapshot); err != nil {
+		return err
+	}
+
+	loadNav := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(s.cfg.URL))
+	if _, _, err := browser.ExecuteAction(ctx, loadNav); err != nil {
+		return err
+	}
+
+	if !s.IsLoggedIn(bctx, browser) {
+		return fmt.Errorf("%w: restored session of %s is no longer valid, configure AuthURL to login again", browserk.ErrLoginFailed, s.cfg.Credentials.Email)
+	}
+	bctx.Log.Info().Msg("restored registered session")
+	return nil
+}
+
+// register loads the registration page, fills in and submits the registration form and then opens
+// the verification link sent to our email address
+func (s *Service) register(ctx context.Context, bctx *browserk.Context, browser browserk.Browser) error {
+	started := time.Now()
+	loadNav := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(s.cfg.Registration.URL))
+	if _, _, err := browser.ExecuteAction(ctx, loadNav); err != nil {
+		return err
+	}
+	s.step(StepRegistrationPageLoaded, browser)
+
+	forms, err := browser.FindForms(ctx)
+	if err != nil {
+		return err
+	}
+
+	form := findRegistrationForm(forms)
+	if form == nil {
+		return browserk.ErrRegistrationFormNotFound
+	}
+	form.FormType = browserk.FormUserRegistration
+	s.formHandler.Fill(form)
+	if form.SubmitButtonID == nil {
+		form.SubmitButtonID = defaultSubmitButton(form)
+	}
+
+	bctx.Log.Info().Str("url", s.cfg.Registration.URL).Str("email", s.cfg.Credentials.Email).Msg("submitting registration form")
+	registerNav := browserk.NewNavigationFromForm(loadNav, browserk.TrigInitial, form)
+	s.step(StepBeforeSubmit, browser)
+	if _, _, err := browser.ExecuteAction(ctx, registerNav); err != nil {
+		return err
+	}
+	s.step(StepAfterSubmit, browser)
+
+	mailCtx, cancel := context.WithTimeout(ctx, s.mailTimeout())
+	defer cancel()
+	mail, err := s.mailSink.WaitFor(mailCtx, s.cfg.Credentials.Email, started)
+	if err != nil {
+		return err
+	}
+	s.step(StepVerificationReceived, browser)
+
+	link := mail.VerificationLink(s.linkRe)
+	if link == "" {
+		return fmt.Errorf("%w: no verification link found in mail %s", browserk.ErrLoginFailed, mail.Subject)
+	}
+
+	bctx.Log.Info().Str("link", link).Msg("opening verification link")
+	verifyNav := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(link))
+	if _, _, err := browser.ExecuteAction(ctx, verifyNav); err != nil {
+		return err
+	}
+	s.step(StepVerified, browser)
+	return nil
+}
+
+// findRegistrationForm returns the visible form with a password input and the most inputs, so
+// we don't pick the login form if both are on the same page
+func findRegistrationForm(forms []*browserk.HTMLFormElement) *browserk.HTMLFormElement {
+	var found *browserk.HTMLFormElement
+	most := 0
+	for _, form := range forms {
+		if form.Hidden {
+			continue
+		}
+
+		inputs := 0
+		hasPassword := false
+		for _, child := range form.ChildElements {
+			if child.Type != browserk.INPUT || child.Hidden {
+				continue
+			}
+			inputs++
+			if child.GetAttribute("type") == "password" {
+				hasPassword = true
+			}
+		}
+
+		if hasPassword && inputs > most {
+			found = form
+			most = inputs
+		}
+	}
+	return found
+}
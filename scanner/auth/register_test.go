@@ -0,0 +1,169 @@
+package auth_test
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/mock"
+	"gitlab.com/browserker/scanner/auth"
+)
+
+const verificationMail = "From: noreply@example.com\r\n" +
+	"To: %s\r\n" +
+	"Subject: =?UTF-8?Q?Confirm_your_account?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<a href=3D\"http://localhost/unsubscribe\">unsubscribe</a> <a href=3D\"http://localhost/account/con=\r\n" +
+	"firm?token=3Dabc&amp;id=3D1\">confirm</a>\r\n" +
+	"--b1--\r\n"
+
+func TestRegisterLogin(t *testing.T) {
+	cfg := &browserk.Config{
+		URL:      "http://localhost/",
+		AuthType: browserk.Register,
+		FormData: &browserk.DefaultFormValues,
+		Registration: &browserk.Registration{
+			URL:         "http://localhost/register",
+			SMTPAddr:    "127.0.0.1:0",
+			MailTimeout: 5,
+		},
+	}
+
+	service := auth.New(cfg)
+	if err := service.Init(); err != nil {
+		t.Fatalf("error init auth service: %s\n", err)
+	}
+	defer service.Close()
+
+	if !service.MustLogin() {
+		t.Fatalf("expected MustLogin for register auth\n")
+	}
+
+	if cfg.Credentials != nil {
+		t.Fatalf("expected the generated credentials to not modify the config\n")
+	}
+
+	email := service.Credentials().Email
+	if !strings.HasPrefix(email, "testuser+") || !strings.HasSuffix(email, "@test.com") {
+		t.Fatalf("expected a unique email address got %s\n", email)
+	}
+
+	loginForm := &browserk.HTMLFormElement{
+		Type:       browserk.FORM,
+		Attributes: map[string]string{"action": "/login"},
+		ChildElements: []*browserk.HTMLElement{
+			{Type: browserk.INPUT, Attributes: map[string]string{"type": "text", "name": "username"}},
+			{Type: browserk.INPUT, Attributes: map[string]string{"type": "password", "name": "password"}},
+		},
+	}
+
+	registerForm := &browserk.HTMLFormElement{
+		Type:       browserk.FORM,
+		Attributes: map[string]string{"action": "/register"},
+		ChildElements: []*browserk.HTMLElement{
+			{Type: browserk.INPUT, Attributes: map[string]string{"type": "email", "name": "email"}},
+			{Type: browserk.INPUT, Attributes: map[string]string{"type": "text", "name": "username"}},
+			{Type: browserk.INPUT, Attributes: map[string]string{"type": "password", "name": "password"}},
+			{Type: browserk.INPUT, Attributes: map[string]string{"type": "password", "name": "password_confirm"}},
+			{Type: browserk.BUTTON, InnerText: "Sign up"},
+		},
+	}
+
+	target, _ := url.Parse(cfg.URL)
+	bctx := mock.MakeMockContext(context.Background(), target)
+	browser := mock.MakeMockBrowser()
+	browser.FindFormsFn = func(ctx context.Context) ([]*browserk.HTMLFormElement, error) {
+		return []*browserk.HTMLFormElement{loginForm, registerForm}, nil
+	}
+
+	var submitted *browserk.HTMLFormElement
+	var verified string
+	browser.ExecuteActionFn = func(ctx context.Context, nav *browserk.Navigation) ([]byte, bool, error) {
+		switch nav.Action.Type {
+		case browserk.ActFillForm:
+			submitted = nav.Action.Form
+			to := nav.Action.Form.ChildElements[0].Value
+			msg := strings.Replace(verificationMail, "%s", to, 1)
+			if err := smtp.SendMail(service.MailSink().Addr(), nil, "noreply@example.com", []string{to}, []byte(msg)); err != nil {
+				t.Fatalf("failed to send verification mail: %s\n", err)
+			}
+		case browserk.ActLoadURL:
+			if strings.Contains(string(nav.Action.Input), "confirm") {
+				verified = string(nav.Action.Input)
+			}
+		}
+		return nil, false, nil
+	}
+
+	if err := service.Login(bctx, browser); err != nil {
+		t.Fatalf("error registering: %s\n", err)
+	}
+
+	if submitted != registerForm {
+		t.Fatalf("expected the registration form to be submitted\n")
+	}
+
+	if registerForm.ChildElements[0].Value != email || registerForm.ChildElements[2].Value != cfg.FormData.Password || registerForm.SubmitButtonID == nil {
+		t.Fatalf("expected registration form to be filled with the generated credentials got %#v\n", registerForm.ChildElements)
+	}
+
+	if verified != "http://localhost/account/confirm?token=abc&id=1" {
+		t.Fatalf("expected verification link to be opened got %s\n", verified)
+	}
+
+	// logging in again restores the session instead of registering the same address
+	submitted = nil
+	relogin := mock.MakeMockBrowser()
+	relogin.ExecuteActionFn = browser.ExecuteActionFn
+	if err := service.Login(bctx, relogin); err != nil {
+		t.Fatalf("error logging in again: %s\n", err)
+	}
+
+	if submitted != nil || relogin.FindFormsCalled || !relogin.RestoreSessionCalled {
+		t.Fatalf("expected the registered session to be restored\n")
+	}
+}
+
+func TestRegisterSharedMailSink(t *testing.T) {
+	cfg := &browserk.Config{
+		URL:          "http://localhost/",
+		AuthType:     browserk.Register,
+		Registration: &browserk.Registration{URL: "http://localhost/register", SMTPAddr: "127.0.0.1:0"},
+	}
+
+	admin := auth.NewForRole(cfg, &browserk.Role{Name: "admin"})
+	user := auth.NewForRole(cfg, &browserk.Role{Name: "user"})
+	for _, service := range []*auth.Service{admin, user} {
+		if err := service.Init(); err != nil {
+			t.Fatalf("error init auth service: %s\n", err)
+		}
+	}
+
+	if admin.MailSink() != user.MailSink() {
+		t.Fatalf("expected roles to share the mail sink\n")
+	}
+
+	addr := admin.MailSink().Addr()
+	admin.Close()
+	if conn, err := net.Dial("tcp", addr); err != nil {
+		t.Fatalf("expected the sink to be open while a role uses it: %s\n", err)
+	} else {
+		conn.Close()
+	}
+
+	user.Close()
+	if conn, err := net.Dial("tcp", addr); err == nil {
+		conn.Close()
+		t.Fatalf("expected the sink to be closed after every role is done\n")
+	}
+}
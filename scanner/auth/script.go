@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/require"
+	"gitlab.com/browserker/browserk"
+)
+
+var (
+	// ErrScriptTimeout the login script did not complete in time
+	ErrScriptTimeout = errors.New("login script timed out")
+	// ErrNoTOTPSecret the login script called totp() but the credentials have no TOTPSecret
+	ErrNoTOTPSecret = errors.New("no TOTPSecret configured")
+)
+
+// scriptBrowser is the browser API exposed to login scripts as `browser`
+type scriptBrowser struct {
+	ctx     context.Context
+	bctx    *browserk.Context
+	browser browserk.Browser
+	nav     *browserk.Navigation // last navigation executed, new ones originate from it
+}
+
+// Navigate to the url and wait for the page to load
+func (b *scriptBrowser) Navigate(url string) error {
+	b.nav = browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(url))
+	_, _, err := b.browser.ExecuteAction(b.ctx, b.nav)
+	return err
+}
+
+// Find all elements matching the CSS selector
+func (b *scriptBrowser) Find(querySelector string) ([]*browserk.HTMLElement, error) {
+	return b.browser.FindElements(b.ctx, querySelector, true)
+}
+
+// Click the first visible element matching the CSS selector
+func (b *scriptBrowser) Click(querySelector string) error {
+	return b.execute(querySelector, browserk.ActLeftClick, "")
+}
+
+// Type the text into the first visible element matching the CSS selector
+func (b *scriptBrowser) Type(querySelector, text string) error {
+	return b.execute(querySelector, browserk.ActSendKeys, text)
+}
+
+// WaitFor an element matching the CSS selector to become visible, timeout is in milliseconds
+func (b *scriptBrowser) WaitFor(querySelector string, timeout int) error {
+	if timeout <= 0 {
+		timeout = 10000
+	}
+	ctx, cancel := context.WithTimeout(b.ctx, time.Millisecond*time.Duration(timeout))
+	defer cancel()
+
+	for {
+		if ele, _ := b.visible(ctx, querySelector); ele != nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s", querySelector)
+		case <-time.After(time.Millisecond * 250):
+		}
+	}
+}
+
+// GetCookies of the current page
+func (b *scriptBrowser) GetCookies() ([]*browserk.Cookie, error) {
+	return b.browser.GetCookies()
+}
+
+// GetURL of the current page
+func (b *scriptBrowser) GetURL() (string, error) {
+	return b.browser.GetURL()
+}
+
+func (b *scriptBrowser) execute(querySelector string, actionType browserk.ActionType, input string) error {
+	ele, err := b.visible(b.ctx, querySelector)
+	if err != nil {
+		return err
+	}
+	if ele == nil {
+		return fmt.Errorf("no visible element found for %s", querySelector)
+	}
+
+	if b.nav == nil {
+		url, _ := b.browser.GetURL()
+		b.nav = browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(url))
+	}
+	nav := browserk.NewNavigationFromElement(b.nav, browserk.TrigInitial, ele, actionType)
+	nav.Action.Input = []byte(input)
+	_, _, err = b.browser.ExecuteAction(b.ctx, nav)
+	b.nav = nav
+	return err
+}
+
+func (b *scriptBrowser) visible(ctx context.Context, querySelector string) (*browserk.HTMLElement, error) {
+	elements, err := b.browser.FindElements(ctx, querySelector, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ele := range elements {
+		if !ele.Hidden {
+			return ele, nil
+		}
+	}
+	return nil, nil
+}
+
+// scriptLogger is exposed to login scripts as `log`
+type scriptLogger struct {
+	bctx *browserk.Context
+}
+
+func (l *scriptLogger) Debug(msg string) { l.bctx.Log.Debug().Str("source", "auth_script").Msg(msg) }
+func (l *scriptLogger) Info(msg string)  { l.bctx.Log.Info().Str("source", "auth_script").Msg(msg) }
+func (l *scriptLogger) Warn(msg string)  { l.bctx.Log.Warn().Str("source", "auth_script").Msg(msg) }
+func (l *scriptLogger) Error(msg string) { l.bctx.Log.Error().Str("source", "auth_script").Msg(msg) }
+
+// scriptLogin runs the AuthScript in a fresh goja runtime bound to the provided browser.
+// Scripts have access to `browser`, `credentials`, `log` and `totp()`, any exception thrown
+// fails the login.
+func (s *Service) scriptLogin(ctx context.Context, bctx *browserk.Context, browser browserk.Browser) error {
+	vm := goja.New()
+	new(require.Registry).Enable(vm)
+	vm.SetFieldNameMapper(goja.UncapFieldNameMapper())
+
+	vm.Set("browser", &scriptBrowser{ctx: ctx, bctx: bctx, browser: browser})
+	vm.Set("credentials", s.cfg.Credentials)
+	vm.Set("log", &scriptLogger{bctx: bctx})
+	vm.Set("totp", func() (string, error) {
+		if s.cfg.Credentials == nil || s.cfg.Credentials.TOTPSecret == "" {
+			return "", ErrNoTOTPSecret
+		}
+		return GenerateTOTP(s.cfg.Credentials.TOTPSecret, time.Now())
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			vm.Interrupt(ErrScriptTimeout)
+		case <-done:
+		}
+	}()
+
+	s.step(StepBeforeSubmit, browser)
+	if _, err := vm.RunScript(s.cfg.AuthScript, s.authScript); err != nil {
+		return err
+	}
+	s.step(StepAfterSubmit, browser)
+
+	if !s.IsLoggedIn(bctx, browser) {
+		return browserk.ErrLoginFailed
+	}
+	s.captureStorageToken(bctx, browser)
+	bctx.Log.Info().Msg("login successful")
+	return nil
+}
+
+func (s *Service) loadScript() error {
+	src, err := ioutil.ReadFile(s.cfg.AuthScript)
+	if err != nil {
+		return err
+	}
+	// make sure it at least compiles before we start scanning
+	if _, err := goja.Compile(s.cfg.AuthScript, string(src), false); err != nil {
+		return err
+	}
+	s.authScript = string(src)
+	return nil
+}
@@ -0,0 +1,157 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/mock"
+	"gitlab.com/browserker/scanner/auth"
+)
+
+const loginScript = `
+browser.navigate("http://localhost/login");
+browser.waitFor("#username", 2000);
+browser.type("#username", credentials ? credentials.username : "scripted");
+browser.type("#password", credentials ? credentials.password : "secret");
+if (credentials && credentials.tOTPSecret) {
+	browser.type("#otp", totp());
+}
+browser.click("button[type=submit]");
+log.info("submitted login form");
+`
+
+// scriptBrowser records what the login script did, #username only becomes visible after the first lookup
+func scriptBrowser(typed map[string]string, clicked *string) *mock.Browser {
+	lookups := 0
+	browser := mock.MakeMockBrowser()
+	browser.FindElementsFn = func(ctx context.Context, querySelector string, canRefreshDoc bool) ([]*browserk.HTMLElement, error) {
+		switch querySelector {
+		case "#username":
+			lookups++
+			return []*browserk.HTMLElement{{Type: browserk.INPUT, Attributes: map[string]string{"id": "username"}, Hidden: lookups < 2}}, nil
+		case "#password", "#otp":
+			return []*browserk.HTMLElement{{Type: browserk.INPUT, Attributes: map[string]string{"id": querySelector[1:]}}}, nil
+		case "button[type=submit]":
+			return []*browserk.HTMLElement{{Type: browserk.BUTTON, Attributes: map[string]string{"type": "submit"}}}, nil
+		}
+		return nil, nil
+	}
+	browser.ExecuteActionFn = func(ctx context.Context, nav *browserk.Navigation) ([]byte, bool, error) {
+		switch nav.Action.Type {
+		case browserk.ActSendKeys:
+			typed[nav.Action.Element.GetAttribute("id")] = string(nav.Action.Input)
+		case browserk.ActLeftClick:
+			*clicked = nav.Action.Element.GetAttribute("type")
+		case browserk.ActLoadURL:
+			typed["url"] = string(nav.Action.Input)
+		}
+		return nil, false, nil
+	}
+	return browser
+}
+
+func TestScriptLogin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "authscript")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	scriptPath := filepath.Join(dir, "login.js")
+	if err := ioutil.WriteFile(scriptPath, []byte(loginScript), 0644); err != nil {
+		t.Fatalf("failed to write script: %s\n", err)
+	}
+
+	loopPath := filepath.Join(dir, "loop.js")
+	if err := ioutil.WriteFile(loopPath, []byte(`while (true) {}`), 0644); err != nil {
+		t.Fatalf("failed to write script: %s\n", err)
+	}
+
+	target, _ := url.Parse("http://localhost/")
+
+	t.Run("credentials", func(t *testing.T) {
+		cfg := &browserk.Config{
+			URL:         target.String(),
+			AuthType:    browserk.Script,
+			AuthScript:  scriptPath,
+			Credentials: &browserk.Credentials{Username: "user", Password: "pass", TOTPSecret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+		}
+		service := auth.New(cfg)
+		if err := service.Init(); err != nil {
+			t.Fatalf("error init auth service: %s\n", err)
+		}
+
+		typed := make(map[string]string)
+		var clicked string
+		bctx := mock.MakeMockContext(context.Background(), target)
+		if err := service.Login(bctx, scriptBrowser(typed, &clicked)); err != nil {
+			t.Fatalf("error running login script: %s\n", err)
+		}
+
+		if typed["url"] != "http://localhost/login" || typed["username"] != "user" || typed["password"] != "pass" || len(typed["otp"]) != 6 {
+			t.Fatalf("expected the script to navigate and type the credentials got %v\n", typed)
+		}
+
+		if clicked != "submit" {
+			t.Fatalf("expected the script to click the submit button\n")
+		}
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		cfg := &browserk.Config{URL: target.String(), AuthType: browserk.Script, AuthScript: scriptPath}
+		service := auth.New(cfg)
+		if err := service.Init(); err != nil {
+			t.Fatalf("error init auth service: %s\n", err)
+		}
+
+		if !service.MustLogin() {
+			t.Fatalf("expected the script alone to enable login\n")
+		}
+
+		typed := make(map[string]string)
+		var clicked string
+		bctx := mock.MakeMockContext(context.Background(), target)
+		if err := service.Login(bctx, scriptBrowser(typed, &clicked)); err != nil {
+			t.Fatalf("error running login script: %s\n", err)
+		}
+
+		if typed["username"] != "scripted" || typed["password"] != "secret" {
+			t.Fatalf("expected the script's own secrets to be typed got %v\n", typed)
+		}
+
+		anonymous := auth.NewForRole(cfg, &browserk.Role{Name: "anonymous"})
+		if err := anonymous.Init(); err != nil {
+			t.Fatalf("error init auth service: %s\n", err)
+		}
+
+		if anonymous.MustLogin() {
+			t.Fatalf("expected roles without credentials to stay anonymous\n")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		cfg := &browserk.Config{URL: target.String(), AuthType: browserk.Script, AuthScript: loopPath}
+		service := auth.New(cfg)
+		if err := service.Init(); err != nil {
+			t.Fatalf("error init auth service: %s\n", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+		defer cancel()
+		bctx := mock.MakeMockContext(ctx, target)
+
+		err := service.Login(bctx, mock.MakeMockBrowser())
+		var interrupted *goja.InterruptedError
+		if !errors.As(err, &interrupted) || interrupted.Value() != auth.ErrScriptTimeout {
+			t.Fatalf("expected the script to be interrupted got %v\n", err)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"gitlab.com/browserker/browserk"
+)
+
+// ErrRefreshUnsupported we have no way of refreshing the token without a browser, the caller should Login
+var ErrRefreshUnsupported = errors.New("token refresh requires login")
+
+// AuthHeaders returns the Authorization header for our current bearer token, if any
+func (s *Service) AuthHeaders() map[string]interface{} {
+	token := s.bearerToken()
+	if token == "" {
+		return nil
+	}
+	return map[string]interface{}{"Authorization": "Bearer " + token}
+}
+
+// TokenExpiry returns when the current bearer token expires, false if there is no token
+// or it isn't a JWT with an exp claim
+func (s *Service) TokenExpiry() (time.Time, bool) {
+	return tokenExpiry(s.bearerToken())
+}
+
+// RefreshToken requests a new bearer token from the RefreshAuth endpoint, or by replaying the
+// Raw login. Returns ErrRefreshUnsupported if the only way to get a new token is to Login with a browser.
+func (s *Service) RefreshToken(ctx context.Context) error {
+	raw := s.cfg.RefreshAuth
+	if raw == nil && s.authType == browserk.Raw && s.MustLogin() {
+		raw = s.cfg.RawAuth
+	}
+
+	if raw == nil {
+		return ErrRefreshUnsupported
+	}
+
+	session, err := s.rawExchange(ctx, raw)
+	if err != nil {
+		return err
+	}
+
+	if session.token == "" {
+		return browserk.ErrLoginFailed
+	}
+	s.setTokens(session.token, session.refreshToken)
+	return nil
+}
+
+// captureStorageToken uses the value written to the configured TokenStorageKey of local/sessionStorage
+// during login as our bearer token
+func (s *Service) captureStorageToken(bctx *browserk.Context, browser browserk.Browser) {
+	if s.cfg.TokenStorageKey == "" {
+		return
+	}
+
+	for _, evt := range browser.GetStorageEvents() {
+		// some applications JSON encode the value
+		token := strings.Trim(evt.NewValue, `"`)
+		if evt.Key != s.cfg.TokenStorageKey || token == "" {
+			continue
+		}
+		bctx.Log.Info().Str("key", evt.Key).Msg("found bearer token in storage")
+		s.setTokens(token, "")
+	}
+}
+
+// tokenFromHeaders uses a bearer token provided in the CustomHeaders
+func (s *Service) tokenFromHeaders(headers map[string]interface{}) {
+	for name, value := range headers {
+		header, ok := value.(string)
+		if !ok || !strings.EqualFold(name, "authorization") {
+			continue
+		}
+
+		if strings.HasPrefix(strings.ToLower(header), "bearer ") {
+			s.setTokens(strings.TrimSpace(header[len("bearer "):]), "")
+		}
+	}
+}
+
+// WithoutAuthorization copies the headers leaving out the Authorization header
+func WithoutAuthorization(headers map[string]interface{}) map[string]interface{} {
+	if headers == nil {
+		return nil
+	}
+
+	copied := make(map[string]interface{}, len(headers))
+	for name, value := range headers {
+		if !strings.EqualFold(name, "authorization") {
+			copied[name] = value
+		}
+	}
+	return copied
+}
+
+func (s *Service) setTokens(token, refreshToken string) {
+	s.tokenLock.Lock()
+	defer s.tokenLock.Unlock()
+
+	if token != "" {
+		s.token = token
+	}
+
+	if refreshToken != "" {
+		s.refreshToken = refreshToken
+	}
+}
+
+func (s *Service) tokens() (string, string) {
+	s.tokenLock.RLock()
+	defer s.tokenLock.RUnlock()
+	return s.token, s.refreshToken
+}
+
+func (s *Service) bearerToken() string {
+	token, _ := s.tokens()
+	return token
+}
+
+// tokenExpiry parses, but does not validate the JWT, returning the exp claim
+func tokenExpiry(token string) (time.Time, bool) {
+	if token == "" {
+		return time.Time{}, false
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return time.Time{}, false
+	}
+
+	switch exp := claims["exp"].(type) {
+	case float64:
+		return time.Unix(int64(exp), 0), true
+	case json.Number:
+		v, err := exp.Int64()
+		return time.Unix(v, 0), err == nil
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,159 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/mock"
+	"gitlab.com/browserker/scanner/auth"
+)
+
+func makeToken(t *testing.T, expires time.Time) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": expires.Unix()})
+	signed, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %s\n", err)
+	}
+	return signed
+}
+
+func TestRefreshToken(t *testing.T) {
+	expires := time.Now().Add(time.Minute).Truncate(time.Second)
+	oldToken := makeToken(t, expires)
+	newToken := makeToken(t, expires.Add(time.Minute*15))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refresh := make(map[string]string)
+		json.NewDecoder(r.Body).Decode(&refresh)
+		if r.Header.Get("Authorization") != "Bearer "+oldToken || refresh["refresh"] != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token": "%s", "refresh_token": "r1"}`, newToken)
+	}))
+	defer srv.Close()
+
+	cfg := &browserk.Config{
+		URL:           srv.URL,
+		CustomHeaders: map[string]interface{}{"authorization": "Bearer " + oldToken},
+		RefreshAuth: &browserk.RawAuth{
+			URL:              srv.URL + "/token/refresh",
+			Headers:          map[string]string{"Authorization": "Bearer {{.Token}}"},
+			Body:             `{"refresh": {{json .RefreshToken}}}`,
+			TokenPath:        "access_token",
+			RefreshTokenPath: "refresh_token",
+		},
+	}
+
+	service := auth.New(cfg)
+	if err := service.Init(); err != nil {
+		t.Fatalf("error init auth service: %s\n", err)
+	}
+
+	if exp, ok := service.TokenExpiry(); !ok || !exp.Equal(expires) {
+		t.Fatalf("expected token from custom headers to expire at %s got %s %v\n", expires, exp, ok)
+	}
+
+	if err := service.RefreshToken(context.Background()); err != nil {
+		t.Fatalf("failed to refresh token: %s\n", err)
+	}
+
+	if service.AuthHeaders()["Authorization"] != "Bearer "+newToken {
+		t.Fatalf("expected refreshed token in auth headers got %v\n", service.AuthHeaders())
+	}
+
+	cfg.RefreshAuth = nil
+	if err := service.RefreshToken(context.Background()); err != auth.ErrRefreshUnsupported {
+		t.Fatalf("expected ErrRefreshUnsupported without a refresh endpoint got %v\n", err)
+	}
+}
+
+func TestRoleIgnoresHeaderToken(t *testing.T) {
+	token := makeToken(t, time.Now().Add(time.Hour))
+	cfg := &browserk.Config{
+		URL:           "http://example.com",
+		CustomHeaders: map[string]interface{}{"authorization": "Bearer " + token, "X-Tenant": "1"},
+	}
+
+	for _, role := range []*browserk.Role{{Name: "admin", Credentials: &browserk.Credentials{Username: "admin"}}, {Name: "anonymous"}} {
+		service := auth.NewForRole(cfg, role)
+		if err := service.Init(); err != nil {
+			t.Fatalf("error init auth service: %s\n", err)
+		}
+
+		if headers := service.AuthHeaders(); headers != nil {
+			t.Fatalf("expected %s to not use the custom header token got %v\n", role.Name, headers)
+		}
+	}
+
+	headers := auth.WithoutAuthorization(cfg.CustomHeaders)
+	if len(headers) != 1 || headers["X-Tenant"] != "1" || len(cfg.CustomHeaders) != 2 {
+		t.Fatalf("expected only the authorization header to be removed from a copy got %v\n", headers)
+	}
+}
+
+func TestStorageToken(t *testing.T) {
+	token := makeToken(t, time.Now().Add(time.Hour))
+	cfg := &browserk.Config{
+		URL:             "http://localhost/",
+		AuthType:        browserk.Form,
+		AuthURL:         "http://localhost/login",
+		Credentials:     &browserk.Credentials{Username: "user", Password: "pass"},
+		TokenStorageKey: "access_token",
+	}
+
+	service := auth.New(cfg)
+	if err := service.Init(); err != nil {
+		t.Fatalf("error init auth service: %s\n", err)
+	}
+
+	target, _ := url.Parse(cfg.URL)
+	bctx := mock.MakeMockContext(context.Background(), target)
+	browser := mock.MakeMockBrowser()
+	browser.FindFormsFn = func(ctx context.Context) ([]*browserk.HTMLFormElement, error) {
+		return []*browserk.HTMLFormElement{{
+			Type: browserk.FORM,
+			ChildElements: []*browserk.HTMLElement{
+				{Type: browserk.INPUT, Attributes: map[string]string{"type": "text", "name": "username"}},
+				{Type: browserk.INPUT, Attributes: map[string]string{"type": "password", "name": "password"}},
+			},
+		}}, nil
+	}
+	browser.GetStorageEventsFn = func() []*browserk.StorageEvent {
+		return []*browserk.StorageEvent{
+			// JWTs the application stores for other purposes are not our bearer token
+			{Type: browserk.StorageAddedEvt, IsLocalStorage: true, Key: "consent", NewValue: makeToken(t, time.Now().Add(time.Hour*24))},
+			{Type: browserk.StorageAddedEvt, IsLocalStorage: true, Key: "access_token", NewValue: `"` + token + `"`},
+		}
+	}
+
+	if err := service.Login(bctx, browser); err != nil {
+		t.Fatalf("error logging in: %s\n", err)
+	}
+
+	if service.AuthHeaders()["Authorization"] != "Bearer "+token {
+		t.Fatalf("expected the token stored under the configured key got %v\n", service.AuthHeaders())
+	}
+
+	cfg.TokenStorageKey = ""
+	service = auth.New(cfg)
+	if err := service.Init(); err != nil {
+		t.Fatalf("error init auth service: %s\n", err)
+	}
+
+	if err := service.Login(bctx, browser); err != nil {
+		t.Fatalf("error logging in: %s\n", err)
+	}
+
+	if service.AuthHeaders() != nil {
+		t.Fatalf("expected no bearer token without a TokenStorageKey got %v\n", service.AuthHeaders())
+	}
+}
@@ -0,0 +1,45 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/browserker/scanner/auth"
+)
+
+func TestGenerateTOTP(t *testing.T) {
+	// RFC 6238 Appendix B SHA1 test vectors (secret "12345678901234567890"), truncated to 6 digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	var inputs = []struct {
+		in       int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, in := range inputs {
+		code, err := auth.GenerateTOTP(secret, time.Unix(in.in, 0))
+		if err != nil {
+			t.Fatalf("error generating totp: %s\n", err)
+		}
+		if code != in.expected {
+			t.Fatalf("time %d expected %s got %s\n", in.in, in.expected, code)
+		}
+	}
+
+	// secrets are commonly displayed lower cased and grouped with spaces
+	code, err := auth.GenerateTOTP("gezd gnbv gy3t qojq gezd gnbv gy3t qojq", time.Unix(59, 0))
+	if err != nil || code != "287082" {
+		t.Fatalf("expected formatted secret to generate 287082 got %s %v\n", code, err)
+	}
+
+	if _, err := auth.GenerateTOTP("not!base32", time.Now()); err == nil {
+		t.Fatalf("expected error for invalid secret\n")
+	}
+}
@@ -29,6 +29,12 @@ type Container struct {
 
 	consoleLock   sync.RWMutex
 	consoleEvents []*browserk.ConsoleEvent
+
+	dialogLock   sync.RWMutex
+	dialogEvents []*browserk.DialogEvent
+
+	openedLock sync.RWMutex
+	openedURLs []string
 }
 
 // NewContainer for holding request/responses, storage and console events
@@ -75,6 +81,40 @@ func (c *Container) GetConsoleEvents() []*browserk.ConsoleEvent {
 	return evts
 }
 
+// AddDialogEvent to the container
+func (c *Container) AddDialogEvent(evt *browserk.DialogEvent) {
+	c.dialogLock.Lock()
+	c.dialogEvents = append(c.dialogEvents, evt)
+	c.dialogLock.Unlock()
+}
+
+// GetDialogEvents and clear the container
+func (c *Container) GetDialogEvents() []*browserk.DialogEvent {
+	c.dialogLock.Lock()
+	evts := make([]*browserk.DialogEvent, len(c.dialogEvents))
+	copy(evts, c.dialogEvents)
+	c.dialogEvents = make([]*browserk.DialogEvent, 0)
+	c.dialogLock.Unlock()
+	return evts
+}
+
+// AddOpenedURL of a window the page opened
+func (c *Container) AddOpenedURL(openedURL string) {
+	c.openedLock.Lock()
+	c.openedURLs = append(c.openedURLs, openedURL)
+	c.openedLock.Unlock()
+}
+
+// GetOpenedURLs and clear the container
+func (c *Container) GetOpenedURLs() []string {
+	c.openedLock.Lock()
+	urls := make([]string, len(c.openedURLs))
+	copy(urls, c.openedURLs)
+	c.openedURLs = make([]string, 0)
+	c.openedLock.Unlock()
+	return urls
+}
+
 // SetLoadRequest uses the requestID of the *first* request as
 // our key to return the httpresponse in GetResponses.
 func (c *Container) SetLoadRequest(request *browserk.HTTPRequest) {
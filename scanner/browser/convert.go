@@ -40,6 +40,7 @@ func ElementToHTMLElement(ele *Element) *browserk.HTMLElement {
 	b.Attributes, _ = ele.GetAttributes()
 	b.NodeDepth = ele.Depth()
 	b.InnerText = ele.GetInnerText()
+	b.Hosts = ele.tab.hostChain(ele)
 
 	listeners, err := ele.GetEventListeners()
 	// no listeners
@@ -75,6 +76,7 @@ func ElementToHTMLFormElement(ele *Element) *browserk.HTMLFormElement {
 
 	b.Attributes, _ = ele.GetAttributes()
 	b.NodeDepth = ele.Depth()
+	b.Hosts = ele.tab.hostChain(ele)
 	listeners, err := ele.GetEventListeners()
 	if err == nil {
 		for _, listener := range listeners {
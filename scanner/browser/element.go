@@ -171,10 +171,19 @@ func (e *Element) GetURLForElement() string {
 	root := e.root
 	e.lock.RUnlock()
 
-	if root != nil {
-		return root.DocumentURL
+	if root == nil {
+		return ""
+	}
+
+	// shadow roots don't have a url, use their host's
+	if root.DocumentURL == "" {
+		if hostID, _, ok := e.tab.getHostNodeID(root.NodeId); ok {
+			if host, ok := e.tab.getElement(hostID); ok {
+				return host.GetURLForElement()
+			}
+		}
 	}
-	return ""
+	return root.DocumentURL
 }
 
 // WaitForReady If we are ready, just return, if we are not, wait for the readyGate
@@ -261,6 +270,24 @@ func (e *Element) GetFrameDocumentNodeID() (int, error) {
 	return -1, &ErrIncorrectElementType{ExpectedName: "(i)frame", NodeName: e.nodeName}
 }
 
+// GetShadowRootNodeID if this element hosts an open shadow root, return the shadow root node id
+func (e *Element) GetShadowRootNodeID() (int, error) {
+	if !e.IsReady() {
+		return -1, &ErrElementNotReady{}
+	}
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	if e.node != nil {
+		for _, shadowRoot := range e.node.ShadowRoots {
+			if shadowRoot.ShadowRootType == "open" {
+				return shadowRoot.NodeId, nil
+			}
+		}
+	}
+	return -1, &ErrIncorrectElementType{ExpectedName: "shadow host", NodeName: e.nodeName}
+}
+
 // NodeID returns the underlying chrome debugger node id of this Element
 func (e *Element) NodeID() int {
 	e.lock.RLock()
@@ -322,6 +349,16 @@ func (e *Element) removeAttribute(name string) {
 	delete(e.attributes, name)
 }
 
+// adds a shadow root pushed after the node was populated
+func (e *Element) addShadowRoot(shadowRoot *gcdapi.DOMNode) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.node != nil {
+		e.node.ShadowRoots = append(e.node.ShadowRoots, shadowRoot)
+	}
+}
+
 // updates character data
 func (e *Element) updateCharacterData(newValue string) {
 	e.lock.Lock()
@@ -479,6 +516,71 @@ func (e *Element) IsSelected() (bool, error) {
 	return false, nil
 }
 
+// SetInputFiles of a file input element
+func (e *Element) SetInputFiles(files ...string) error {
+	e.lock.RLock()
+	id := e.ID
+	e.lock.RUnlock()
+
+	_, err := e.tab.t.DOM.SetFileInputFilesWithParams(e.tab.ctx.Ctx, &gcdapi.DOMSetFileInputFilesParams{Files: files, NodeId: id})
+	return err
+}
+
+// IsChecked returns the current checked property of a radio/checkbox, unlike IsSelected which only
+// looks at the checked attribute
+func (e *Element) IsChecked() (bool, error) {
+	rro, err := e.callFunctionOn("function() { return this.checked === true; }")
+	if err != nil {
+		return false, err
+	}
+	checked, _ := rro.Value.(bool)
+	return checked, nil
+}
+
+// SelectOption of a select element by value, dispatching the input and change events a user selecting
+// it would
+func (e *Element) SelectOption(value string) error {
+	_, err := e.callFunctionOn(`function(value) {
+		this.value = value;
+		this.dispatchEvent(new Event('input', {bubbles: true}));
+		this.dispatchEvent(new Event('change', {bubbles: true}));
+	}`, value)
+	return err
+}
+
+// callFunctionOn the element's javascript object, this is the element
+func (e *Element) callFunctionOn(declaration string, args ...interface{}) (*gcdapi.RuntimeRemoteObject, error) {
+	e.lock.RLock()
+	id := e.ID
+	e.lock.RUnlock()
+
+	rro, err := e.tab.t.DOM.ResolveNodeWithParams(e.tab.ctx.Ctx, &gcdapi.DOMResolveNodeParams{NodeId: id})
+	if err != nil {
+		return nil, err
+	}
+
+	arguments := make([]*gcdapi.RuntimeCallArgument, len(args))
+	for i, arg := range args {
+		arguments[i] = &gcdapi.RuntimeCallArgument{Value: arg}
+	}
+
+	params := &gcdapi.RuntimeCallFunctionOnParams{
+		FunctionDeclaration: declaration,
+		ObjectId:            rro.ObjectId,
+		Arguments:           arguments,
+		ReturnByValue:       true,
+	}
+	result, exp, err := e.tab.t.Runtime.CallFunctionOnWithParams(e.tab.ctx.Ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if exp != nil {
+		return nil, fmt.Errorf("function call failed: %s", exp.Text)
+	}
+	return result, nil
+}
+
 // GetCSSInlineStyleText returns the CSS Style Text of the element, returns the inline style first
 // and the attribute style second, or error.
 func (e *Element) GetCSSInlineStyleText() (string, string, error) {
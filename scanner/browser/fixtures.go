@@ -0,0 +1,282 @@
+package browser
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/wirepair/gcd/v2/gcdapi"
+)
+
+// fixtureExtensions in the order they are preferred when an accept attribute matches more than one
+var fixtureExtensions = []string{".png", ".jpg", ".pdf", ".txt", ".csv", ".docx"}
+
+// fixtureMimeTypes of the generated fixtures, mime.TypeByExtension depends on the system's mime.types
+var fixtureMimeTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".pdf":  "application/pdf",
+	".txt":  "text/plain",
+	".csv":  "text/csv",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+}
+
+var (
+	generateLock sync.Mutex
+	generated    map[string]string
+	generatedDir string
+)
+
+// Fixtures are the files we upload to file inputs, file extension -> path
+type Fixtures map[string]string
+
+// NewFixtures generates (once until RemoveFixtures is called) small fixture files of each type in a temp directory,
+// custom files (extension -> path) replace the generated ones or add new types
+func NewFixtures(custom map[string]string) (Fixtures, error) {
+	generateLock.Lock()
+	if generated == nil {
+		dir, files, err := generateFixtures()
+		if err != nil {
+			generateLock.Unlock()
+			return nil, err
+		}
+		generated, generatedDir = files, dir
+	}
+
+	f := make(Fixtures, len(generated)+len(custom))
+	for ext, path := range generated {
+		f[ext] = path
+	}
+	generateLock.Unlock()
+
+	for ext, path := range custom {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		f[ext] = path
+	}
+	return f, nil
+}
+
+// ForAccept returns the fixture matching the input's accept attribute (.ext, type/subtype or type/*), falling
+// back to the text file as accept is only a hint to the file chooser
+func (f Fixtures) ForAccept(accept string) string {
+	extensions := f.extensions()
+	for _, token := range strings.Split(strings.ToLower(accept), ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		if token == ".jpeg" {
+			token = ".jpg"
+		}
+
+		if strings.HasPrefix(token, ".") {
+			if path, ok := f[token]; ok {
+				return path
+			}
+			continue
+		}
+
+		for _, ext := range extensions {
+			mimeType := fixtureMimeType(ext)
+			if mimeType == token || strings.HasSuffix(token, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(token, "*")) {
+				return f[ext]
+			}
+		}
+	}
+	return f[".txt"]
+}
+
+// extensions of the fixtures, generated ones in preferred order followed by any custom ones
+func (f Fixtures) extensions() []string {
+	extensions := make([]string, 0, len(f))
+	custom := make([]string, 0)
+	for _, ext := range fixtureExtensions {
+		if _, ok := f[ext]; ok {
+			extensions = append(extensions, ext)
+		}
+	}
+
+	for ext := range f {
+		if _, ok := fixtureMimeTypes[ext]; !ok {
+			custom = append(custom, ext)
+		}
+	}
+	sort.Strings(custom)
+	return append(extensions, custom...)
+}
+
+func fixtureMimeType(ext string) string {
+	if mimeType, ok := fixtureMimeTypes[ext]; ok {
+		return mimeType
+	}
+	// strip any parameters (text/plain; charset=utf-8)
+	return strings.SplitN(mime.TypeByExtension(ext), ";", 2)[0]
+}
+
+// RemoveFixtures deletes the generated fixture files, call once the browsers are shutdown
+func RemoveFixtures() error {
+	generateLock.Lock()
+	defer generateLock.Unlock()
+
+	if generatedDir == "" {
+		return nil
+	}
+
+	err := os.RemoveAll(generatedDir)
+	generated, generatedDir = nil, ""
+	return err
+}
+
+// generateFixtures writes each fixture type to a new temp directory, returning the directory and the files
+func generateFixtures() (string, map[string]string, error) {
+	dir, err := ioutil.TempDir("", "browserk_fixtures")
+	if err != nil {
+		return "", nil, err
+	}
+
+	contents := map[string]func() ([]byte, error){
+		".png":  fixtureImage(png.Encode),
+		".jpg":  fixtureImage(func(w io.Writer, img image.Image) error { return jpeg.Encode(w, img, nil) }),
+		".pdf":  fixturePDF,
+		".txt":  fixtureText("browserker upload\n"),
+		".csv":  fixtureText("name,value\nbrowserker,1\n"),
+		".docx": fixtureDOCX,
+	}
+
+	files := make(map[string]string, len(contents))
+	for ext, content := range contents {
+		data, err := content()
+		if err == nil {
+			path := filepath.Join(dir, "browserk"+ext)
+			err = ioutil.WriteFile(path, data, 0644)
+			files[ext] = path
+		}
+
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", nil, err
+		}
+	}
+	return dir, files, nil
+}
+
+func fixtureText(text string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		return []byte(text), nil
+	}
+}
+
+func fixtureImage(encode func(w io.Writer, img image.Image) error) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		for x := 0; x < 8; x++ {
+			for y := 0; y < 8; y++ {
+				img.Set(x, y, color.RGBA{R: 0xe6, G: 0x64, B: 0x65, A: 0xff})
+			}
+		}
+
+		buf := &bytes.Buffer{}
+		if err := encode(buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// fixturePDF is a single empty page document
+func fixturePDF() ([]byte, error) {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] >>",
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes(), nil
+}
+
+// fixtureDOCX is the minimal set of parts word processors need to open a document
+func fixtureDOCX() ([]byte, error) {
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/></Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/></Relationships>`},
+		{"word/document.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p><w:r><w:t>browserker upload</w:t></w:r></w:p></w:body></w:document>`},
+	}
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for _, part := range parts {
+		f, err := w.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := f.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// uploadFixture to the file input a file chooser was opened for, matching its accept attribute
+func (t *Tab) uploadFixture(backendNodeID int) {
+	node, err := t.t.DOM.DescribeNodeWithParams(t.ctx.Ctx, &gcdapi.DOMDescribeNodeParams{BackendNodeId: backendNodeID})
+	if err != nil {
+		t.ctx.Log.Warn().Err(err).Msg("failed to describe file chooser input")
+		return
+	}
+
+	accept := ""
+	for i := 0; i+1 < len(node.Attributes); i += 2 {
+		if node.Attributes[i] == "accept" {
+			accept = node.Attributes[i+1]
+		}
+	}
+
+	upload := t.fixtures.ForAccept(accept)
+	params := &gcdapi.DOMSetFileInputFilesParams{Files: []string{upload}, BackendNodeId: backendNodeID}
+	if _, err := t.t.DOM.SetFileInputFilesWithParams(t.ctx.Ctx, params); err != nil {
+		t.ctx.Log.Warn().Err(err).Msg("failed to upload file to file chooser")
+		return
+	}
+	t.ctx.Log.Info().Str("file", upload).Msg("uploaded file to file chooser")
+}
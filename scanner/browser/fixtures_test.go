@@ -0,0 +1,83 @@
+package browser_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/browserker/scanner/browser"
+)
+
+func TestFixturesForAccept(t *testing.T) {
+	custom, err := ioutil.TempFile("", "custom*.svg")
+	if err != nil {
+		t.Fatalf("error creating custom fixture: %s\n", err)
+	}
+	custom.Close()
+	defer os.Remove(custom.Name())
+
+	fixtures, err := browser.NewFixtures(map[string]string{"SVG": custom.Name()})
+	if err != nil {
+		t.Fatalf("error generating fixtures: %s\n", err)
+	}
+
+	var tests = []struct {
+		accept   string
+		expected string
+	}{
+		{"", ".txt"},
+		{"image/*", ".png"},
+		{".jpeg", ".jpg"},
+		{"image/jpeg", ".jpg"},
+		{"application/pdf,.docx", ".pdf"},
+		{".doc, .docx", ".docx"},
+		{"text/csv", ".csv"},
+		{".svg", ".svg"},
+		{"video/*", ".txt"},
+	}
+
+	for _, tt := range tests {
+		if ext := filepath.Ext(fixtures.ForAccept(tt.accept)); ext != tt.expected {
+			t.Fatalf("accept %s expected %s got %s\n", tt.accept, tt.expected, ext)
+		}
+	}
+
+	// generated files must be what their extension says
+	contentTypes := map[string]string{
+		".png":  "image/png",
+		".jpg":  "image/jpeg",
+		".pdf":  "application/pdf",
+		".txt":  "text/plain; charset=utf-8",
+		".docx": "application/zip",
+	}
+	for ext, contentType := range contentTypes {
+		data, err := ioutil.ReadFile(fixtures[ext])
+		if err != nil {
+			t.Fatalf("error reading %s fixture: %s\n", ext, err)
+		}
+
+		if detected := http.DetectContentType(data); detected != contentType {
+			t.Fatalf("expected %s fixture to be %s got %s\n", ext, contentType, detected)
+		}
+	}
+
+	if err := browser.RemoveFixtures(); err != nil {
+		t.Fatalf("error removing fixtures: %s\n", err)
+	}
+
+	if _, err := os.Stat(filepath.Dir(fixtures[".txt"])); !os.IsNotExist(err) {
+		t.Fatalf("expected generated fixtures to be removed got %v\n", err)
+	}
+
+	regenerated, err := browser.NewFixtures(nil)
+	if err != nil {
+		t.Fatalf("error regenerating fixtures: %s\n", err)
+	}
+	defer browser.RemoveFixtures()
+
+	if _, err := os.Stat(regenerated[".txt"]); err != nil {
+		t.Fatalf("expected fixtures to be generated again after removal got %s\n", err)
+	}
+}
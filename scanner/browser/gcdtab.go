@@ -49,11 +49,19 @@ type Tab struct {
 	stabilityTimeout      time.Duration          // amount of time to give up waiting for stability
 	stableAfter           time.Duration          // amount of time of no activity to consider the DOM stable
 	lastNodeChangeTimeVal atomic.Value           // timestamp of when the last node change occurred atomic because multiple go routines will modify
+	lastNodeInsertTimeVal atomic.Value           // timestamp of when nodes were last added, tells us if scrolling loaded more content
 	domChangeHandler      DomChangeHandlerFunc   // allows the caller to be notified of DOM change events.
 	docWasUpdated         atomic.Value           // for tracking if an execution caused a new page load/transition
 
-	frameMutex *sync.RWMutex
-	frames     map[string]int // frames
+	frameMutex  *sync.RWMutex
+	frames      map[string]int // frames
+	frameHosts  map[int]int    // frame document node id -> (i)frame node id
+	shadowRoots map[int]int    // open shadow root node id -> host node id
+
+	bindingMutex *sync.RWMutex
+	bindings     map[string]browserk.BindingHandler // binding name -> handler called by Runtime.bindingCalled
+
+	fixtures Fixtures // files uploaded to file inputs
 }
 
 // NewTab to use
@@ -69,7 +77,16 @@ func NewTab(bctx *browserk.Context, gcdBrowser *gcd.Gcd, tab *gcd.ChromeTarget)
 	t.elements = make(map[int]*Element)
 
 	t.frames = make(map[string]int)
+	t.frameHosts = make(map[int]int)
+	t.shadowRoots = make(map[int]int)
 	t.frameMutex = &sync.RWMutex{}
+	t.bindingMutex = &sync.RWMutex{}
+	t.bindings = make(map[string]browserk.BindingHandler)
+	if fixtures, err := NewFixtures(nil); err == nil {
+		t.fixtures = fixtures
+	} else {
+		bctx.Log.Warn().Err(err).Msg("failed to generate upload fixtures")
+	}
 
 	t.nodeChange = make(chan *NodeChangeEvent)
 	t.navigationCh = make(chan int, 1)  // for signaling navigation complete
@@ -98,6 +115,18 @@ func (t *Tab) defaultDisconnectedHandler(tab *Tab, reason string) {
 }
 
 func (t *Tab) Init(cfg *browserk.Config) error {
+	if err := t.installHooks(t.ctx.Ctx); err != nil {
+		return err
+	}
+
+	if cfg.FormData != nil && len(cfg.FormData.UploadFiles) != 0 {
+		fixtures, err := NewFixtures(cfg.FormData.UploadFiles)
+		if err != nil {
+			return err
+		}
+		t.fixtures = fixtures
+	}
+
 	if cfg.CustomHeaders != nil && len(cfg.CustomHeaders) != 0 {
 		_, err := t.t.Network.SetExtraHTTPHeaders(t.ctx.Ctx, cfg.CustomHeaders)
 		if err != nil {
@@ -105,28 +134,56 @@ func (t *Tab) Init(cfg *browserk.Config) error {
 		}
 	}
 
+	// restore the last login's session so we don't have to login again
+	if t.ctx.Auth != nil && t.ctx.Auth.Session() != nil {
+		if err := t.RestoreSession(t.ctx.Ctx, t.ctx.Auth.Session()); err != nil {
+			return err
+		}
+	}
+
 	if cfg.CustomCookies == nil || len(cfg.CustomCookies) == 0 {
 		return nil
 	}
 
+	cookies := make([]*browserk.Cookie, 0)
 	for cookieName, cookieValue := range cfg.CustomCookies {
 		val := cookieValue.(string)
 		for _, host := range cfg.AllowedHosts {
-			param := &gcdapi.NetworkSetCookieParams{
+			cookies = append(cookies, &browserk.Cookie{
 				Name:   cookieName,
 				Value:  val,
 				Domain: host,
-			}
-			set, err := t.t.Network.SetCookieWithParams(t.ctx.Ctx, param)
-			if err != nil {
-				return err
-			}
-			if !set {
-				t.ctx.Log.Warn().Str("cookie_name", cookieName).Str("cookie_value", val).Msg("failed to set cookie")
-			}
-
+			})
+		}
+	}
+	return t.SetCookies(t.ctx.Ctx, cookies)
+}
+
+// SetCookies in the browser, cookies must have a domain set
+func (t *Tab) SetCookies(ctx context.Context, cookies []*browserk.Cookie) error {
+	for _, cookie := range cookies {
+		param := &gcdapi.NetworkSetCookieParams{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HTTPOnly,
+			SameSite: cookie.SameSite,
+			Expires:  cookie.Expires,
+			Priority: cookie.Priority,
+		}
+		// chrome reports session cookies as expiring at -1, which would set them as already expired
+		if cookie.Session || param.Expires < 0 {
+			param.Expires = 0
+		}
+		set, err := t.t.Network.SetCookieWithParams(ctx, param)
+		if err != nil {
+			return err
+		}
+		if !set {
+			t.ctx.Log.Warn().Str("cookie_name", cookie.Name).Str("cookie_value", cookie.Value).Msg("failed to set cookie")
 		}
-
 	}
 	return nil
 }
@@ -169,6 +226,41 @@ func (t *Tab) InjectRequest(ctx context.Context, method, URI string) error {
 	return err
 }
 
+// AddBinding calls the handler whenever window[name](payload) is called by any document. The script is evaluated
+// in every new document and the current one so it can install hooks that call the binding. Adding a binding
+// that was already added does nothing, so JS handlers can call this before every action.
+func (t *Tab) AddBinding(ctx context.Context, name, script string, handler browserk.BindingHandler) error {
+	t.bindingMutex.Lock()
+	defer t.bindingMutex.Unlock()
+
+	if _, ok := t.bindings[name]; ok {
+		return nil
+	}
+
+	// bindingCalled notifications are only sent with the runtime domain enabled
+	if len(t.bindings) == 0 {
+		if _, err := t.t.Runtime.Enable(ctx); err != nil {
+			return err
+		}
+	}
+
+	if _, err := t.t.Runtime.AddBinding(ctx, name, 0); err != nil {
+		return err
+	}
+
+	if script != "" {
+		if _, err := t.t.Page.AddScriptToEvaluateOnNewDocument(ctx, script, ""); err != nil {
+			return err
+		}
+
+		if _, err := t.InjectJS(script); err != nil {
+			t.ctx.Log.Warn().Err(err).Str("binding", name).Msg("failed to inject binding script into current document")
+		}
+	}
+	t.bindings[name] = handler
+	return nil
+}
+
 // ExecuteAction for this browser, calling js handler after it is called
 func (t *Tab) ExecuteAction(ctx context.Context, nav *browserk.Navigation) ([]byte, bool, error) {
 	var err error
@@ -183,7 +275,8 @@ func (t *Tab) ExecuteAction(ctx context.Context, nav *browserk.Navigation) ([]by
 	actionType := browserk.ActionTypeMap[act.Type]
 	errMsg := fmt.Sprintf("unable to find element for %s", browserk.ActionTypeMap[act.Type])
 
-	if act.Type > browserk.ActExecuteJS && act.Type < browserk.ActFillForm {
+	// scrolling without an element scrolls the window
+	if act.Type > browserk.ActExecuteJS && act.Type < browserk.ActFillForm && !(act.Type == browserk.ActScroll && act.Element == nil) {
 		findCtx, cancel := context.WithTimeout(ctx, time.Second*2)
 		ele, err = t.FindByHTMLElement(findCtx, act.Element, true)
 		cancel()
@@ -221,10 +314,17 @@ func (t *Tab) ExecuteAction(ctx context.Context, nav *browserk.Navigation) ([]by
 		}
 		waitFor = time.Millisecond * 2000
 	case browserk.ActRightClick:
-	case browserk.ActScroll:
-		ele.ScrollTo()
+	case browserk.ActScroll, browserk.ActMouseWheel:
+		if _, err = t.scroll(ele); err != nil {
+			t.ctx.Log.Warn().Err(err).Msg("failed to scroll")
+		}
+		waitFor = time.Millisecond * 1000
 	case browserk.ActSendKeys, browserk.ActKeyUp, browserk.ActKeyDown:
-		ele.SendRawKeys(keymap.Enter)
+		if act.Type == browserk.ActSendKeys && len(act.Input) > 0 {
+			err = ele.SendKeys(string(act.Input))
+		} else {
+			ele.SendRawKeys(keymap.Enter)
+		}
 	case browserk.ActHover:
 		ele.ScrollTo()
 		ele.MouseOver()
@@ -237,8 +337,6 @@ func (t *Tab) ExecuteAction(ctx context.Context, nav *browserk.Navigation) ([]by
 		ele.ScrollTo()
 		ele.MouseOver()
 		t.MoveMouse(0, 0)
-	case browserk.ActMouseWheel:
-
 	}
 
 	//if t.IsTransitioning() {
@@ -299,6 +397,15 @@ func (t *Tab) FillForm(ctx context.Context, act *browserk.Action) error {
 			t.ctx.Log.Error().Err(err).Str("type", browserk.HTMLTypeToStrMap[formChild.Type]).Msg("failed to find")
 			continue
 		}
+		if formChild.Type == browserk.INPUT && formChild.GetAttribute("type") == "file" {
+			upload := t.fixtures.ForAccept(formChild.GetAttribute("accept"))
+			t.ctx.Log.Info().Str("file", upload).Msg("uploading file")
+			if err := actualElement.SetInputFiles(upload); err != nil {
+				t.ctx.Log.Error().Err(err).Msg("failed to set input files")
+			}
+			continue
+		}
+
 		if formChild.Type == browserk.INPUT && formChild.Value != "" {
 			actualElement.Focus()
 			actualElement.SendRawKeys(keymap.Backspace) // clear anything that might be in the way
@@ -309,11 +416,20 @@ func (t *Tab) FillForm(ctx context.Context, act *browserk.Action) error {
 			continue
 		}
 
-		if formChild.Type == browserk.SELECT {
+		if formChild.Type == browserk.SELECT && formChild.Value != "" {
+			if err := actualElement.SelectOption(formChild.Value); err != nil {
+				t.ctx.Log.Error().Err(err).Str("value", formChild.Value).Msg("failed to select option")
+			}
+		} else if formChild.Type == browserk.SELECT {
 			// down twice in case it's a 'option disabled' style select list
 			actualElement.SendRawKeys(keymap.ArrowDown + keymap.ArrowDown + keymap.Enter)
 		} else if formChild.Type == browserk.INPUT && formChild.GetAttribute("list") != "" {
 			actualElement.SendRawKeys(keymap.ArrowDown + keymap.ArrowDown + keymap.Enter)
+		} else if act.Form.Variant != "" && formChild.Type == browserk.INPUT && (formChild.GetAttribute("type") == "radio" || formChild.GetAttribute("type") == "checkbox") {
+			// variants choose exactly which radios/checkboxes are checked
+			if checked, err := actualElement.IsChecked(); err == nil && checked != formChild.Checked {
+				actualElement.Click()
+			}
 			// ghetto, as there could be multiple groups of radio/checkboxes, todo make this better
 		} else if formChild.Type == browserk.INPUT && formChild.GetAttribute("type") == "radio" && !radioClicked {
 			actualElement.Click()
@@ -392,7 +508,14 @@ func (t *Tab) FindByHTMLElement(ctx context.Context, toFind browserk.ActHTMLElem
 	}
 	tag := toFind.Tag()
 
-	foundElements, err := t.GetElementsBySelector(ctx, tag, refreshDocument)
+	var err error
+	var foundElements []*Element
+	if hosts := toFind.HostChain(); len(hosts) > 0 {
+		foundElements, err = t.findInHosts(ctx, hosts, tag, refreshDocument)
+	} else {
+		foundElements, err = t.GetElementsBySelector(ctx, tag, refreshDocument)
+	}
+
 	if err != nil {
 		t.ctx.Log.Error().Err(err).Msgf("searching for tag: %s failed", tag)
 		return nil, err
@@ -469,7 +592,7 @@ func (t *Tab) FindInteractables() ([]*browserk.HTMLElement, error) {
 	for _, ele := range allElements {
 		cElement := ElementToHTMLElement(ele)
 		if cElement != nil && len(cElement.Events) > 0 {
-			cElements = append(cElements)
+			cElements = append(cElements, cElement)
 		}
 	}
 	return cElements, nil
@@ -770,6 +893,16 @@ func (t *Tab) GetConsoleEvents() []*browserk.ConsoleEvent {
 	return t.container.GetConsoleEvents()
 }
 
+// GetDialogEvents and clear the container
+func (t *Tab) GetDialogEvents() []*browserk.DialogEvent {
+	return t.container.GetDialogEvents()
+}
+
+// GetOpenedURLs of windows the page opened and clear the container
+func (t *Tab) GetOpenedURLs() []string {
+	return t.container.GetOpenedURLs()
+}
+
 // EvaluateScript in the global context.
 func (t *Tab) EvaluateScript(scriptSource string) (*gcdapi.RuntimeRemoteObject, error) {
 	return t.evaluateScript(scriptSource, false)
@@ -982,15 +1115,15 @@ func (t *Tab) GetElementsBySelector(ctx context.Context, selector string, refres
 		}
 	}
 
-	// search frames too
-	frameNodeIDs := t.getFrameNodeIDs()
-	for _, id := range frameNodeIDs {
+	// search same origin frames and open shadow roots too
+	rootNodeIDs := t.getSearchRootIDs()
+	for _, id := range rootNodeIDs {
 		frameElements, err := t.GetDocumentElementsBySelector(ctx, id, selector)
 		if err != nil {
-			t.ctx.Log.Warn().Msg("failed to search frame for elements")
+			t.ctx.Log.Warn().Msg("failed to search frame/shadow root for elements")
 			continue
 		}
-		t.ctx.Log.Debug().Int("found", len(frameElements)).Str("selector", selector).Msg("found in frames")
+		t.ctx.Log.Debug().Int("found", len(frameElements)).Str("selector", selector).Msg("found in frames/shadow roots")
 		elements = append(elements, frameElements...)
 	}
 	return elements, err
@@ -1224,10 +1357,15 @@ func (t *Tab) addNodes(root, node *gcdapi.DOMNode, depth int) {
 	if node.ContentDocument != nil {
 		t.frameMutex.Lock()
 		t.frames[node.FrameId] = node.ContentDocument.NodeId
+		t.frameHosts[node.ContentDocument.NodeId] = node.NodeId
 		t.frameMutex.Unlock()
 
 		t.addNodes(node.ContentDocument, node.ContentDocument, depth+1)
 	}
+
+	if node.ShadowRoots != nil {
+		t.addShadowRoots(node, depth)
+	}
 	t.lastNodeChangeTimeVal.Store(time.Now())
 }
 
@@ -1278,6 +1416,13 @@ func (t *Tab) handleDocumentUpdated() {
 	t.elements = make(map[int]*Element)
 	t.eleMutex.Unlock()
 
+	// node ids of frames and shadow roots are no longer valid either
+	t.frameMutex.Lock()
+	t.frames = make(map[string]int)
+	t.frameHosts = make(map[int]int)
+	t.shadowRoots = make(map[int]int)
+	t.frameMutex.Unlock()
+
 	t.documentUpdated()
 	// notify if navigating that we received the document update event.
 	if t.IsNavigating() {
@@ -1335,6 +1480,8 @@ func (t *Tab) handleNodeChange(change *NodeChangeEvent) {
 		t.handleChildNodeInserted(change.ParentNodeID, change.Node)
 	case ChildNodeRemovedEvent:
 		t.handleChildNodeRemoved(change.ParentNodeID, change.NodeID)
+	case ShadowRootPushedEvent:
+		t.handleShadowRootPushed(change.ParentNodeID, change.Node)
 	}
 
 }
@@ -1353,12 +1500,14 @@ func (t *Tab) handleSetChildNodes(parentNodeID int, nodes []*gcdapi.DOMNode) {
 		}
 	}
 	t.lastNodeChangeTimeVal.Store(time.Now())
+	t.lastNodeInsertTimeVal.Store(time.Now())
 
 }
 
 // update parent with new child node and add the new nodes.
 func (t *Tab) handleChildNodeInserted(parentNodeID int, node *gcdapi.DOMNode) {
 	t.lastNodeChangeTimeVal.Store(time.Now())
+	t.lastNodeInsertTimeVal.Store(time.Now())
 	if node == nil {
 		return
 	}
@@ -1415,6 +1564,21 @@ func (t *Tab) invalidateChildren(node *gcdapi.DOMNode) {
 			t.invalidateRemove(ele)
 			t.invalidateChildren(node.ContentDocument)
 		}
+		t.frameMutex.Lock()
+		delete(t.frameHosts, node.ContentDocument.NodeId)
+		t.frameMutex.Unlock()
+	}
+
+	// invalidate & remove shadow roots and children
+	for _, shadowRoot := range node.ShadowRoots {
+		ele, ok := t.getElement(shadowRoot.NodeId)
+		if ok {
+			t.invalidateRemove(ele)
+			t.invalidateChildren(shadowRoot)
+		}
+		t.frameMutex.Lock()
+		delete(t.shadowRoots, shadowRoot.NodeId)
+		t.frameMutex.Unlock()
 	}
 
 	if node.Children == nil {
@@ -1505,9 +1669,11 @@ func (t *Tab) subscribeBrowserEvents(ctx *browserk.Context, intercept bool) {
 	t.subscribeChildNodeCountUpdated()
 	t.subscribeChildNodeInserted()
 	t.subscribeChildNodeRemoved()
+	t.subscribeShadowRootPushed()
 
 	// events
 	t.subscribeStorageEvents()
 	t.subscribeConsoleEvents()
 	t.subscribeDialogEvents()
+	t.subscribeBindingEvents()
 }
@@ -0,0 +1,221 @@
+package browser
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/wirepair/gcd/v2/gcdapi"
+	"gitlab.com/browserker/browserk"
+)
+
+// addShadowRoots of the host node, only open shadow roots are crawled as closed ones can't be reached
+// by the page's own scripts either
+func (t *Tab) addShadowRoots(host *gcdapi.DOMNode, depth int) {
+	for _, shadowRoot := range host.ShadowRoots {
+		if shadowRoot.ShadowRootType != "open" {
+			continue
+		}
+
+		t.frameMutex.Lock()
+		t.shadowRoots[shadowRoot.NodeId] = host.NodeId
+		t.frameMutex.Unlock()
+
+		t.addNodes(shadowRoot, shadowRoot, depth+1)
+	}
+}
+
+// handleShadowRootPushed adds a shadow root attached to a host we already know about
+func (t *Tab) handleShadowRootPushed(hostNodeID int, shadowRoot *gcdapi.DOMNode) {
+	host, ok := t.getElement(hostNodeID)
+	if !ok || shadowRoot == nil {
+		return
+	}
+
+	if err := host.WaitForReady(); err != nil {
+		return
+	}
+
+	host.addShadowRoot(shadowRoot)
+	t.addShadowRoots(&gcdapi.DOMNode{NodeId: hostNodeID, ShadowRoots: []*gcdapi.DOMNode{shadowRoot}}, host.Depth())
+}
+
+// getHostNodeID of the (i)frame or shadow host for a frame document/shadow root node id
+func (t *Tab) getHostNodeID(rootNodeID int) (int, bool, bool) {
+	t.frameMutex.RLock()
+	defer t.frameMutex.RUnlock()
+
+	if hostID, ok := t.frameHosts[rootNodeID]; ok {
+		return hostID, true, true
+	}
+
+	hostID, ok := t.shadowRoots[rootNodeID]
+	return hostID, false, ok
+}
+
+// getSearchRootIDs returns the node ids of same origin frame documents and open shadow roots, querySelector
+// doesn't cross into either so they need to be searched separately
+func (t *Tab) getSearchRootIDs() []int {
+	nodeIDs := t.getSameOriginFrameNodeIDs()
+	t.frameMutex.RLock()
+	for rootID := range t.shadowRoots {
+		nodeIDs = append(nodeIDs, rootID)
+	}
+	t.frameMutex.RUnlock()
+	return nodeIDs
+}
+
+// getSameOriginFrameNodeIDs returns the document node ids of frames with the same origin as the top document
+func (t *Tab) getSameOriginFrameNodeIDs() []int {
+	nodeIDs := make([]int, 0)
+	topURL, err := t.GetDocumentCurrentURL(t.getTopNodeID())
+	if err != nil {
+		return nodeIDs
+	}
+
+	for _, id := range t.getFrameNodeIDs() {
+		frameURL, err := t.GetDocumentCurrentURL(id)
+		if err != nil || !sameOrigin(topURL, frameURL) {
+			continue
+		}
+		nodeIDs = append(nodeIDs, id)
+	}
+	return nodeIDs
+}
+
+// sameOrigin of the two urls, about:blank/srcdoc frames share the origin of their parent
+func sameOrigin(topURL, frameURL string) bool {
+	if strings.HasPrefix(frameURL, "about:") {
+		return true
+	}
+
+	top, err := url.Parse(topURL)
+	if err != nil {
+		return false
+	}
+
+	frame, err := url.Parse(frameURL)
+	if err != nil {
+		return false
+	}
+	return top.Scheme == frame.Scheme && top.Host == frame.Host
+}
+
+// hostChain of frames and shadow hosts the element is nested in, outermost first
+func (t *Tab) hostChain(ele *Element) []*browserk.ElementHost {
+	var hosts []*browserk.ElementHost
+
+	root := ele.GetRootNode()
+	for root != nil {
+		hostID, isFrame, ok := t.getHostNodeID(root.NodeId)
+		if !ok {
+			break
+		}
+
+		host, ok := t.getElement(hostID)
+		if !ok || host.WaitForReady() != nil {
+			break
+		}
+
+		hostRoot := host.GetRootNode()
+		if hostRoot == nil {
+			break
+		}
+
+		selector, _ := host.GetTagName()
+		if id := host.GetAttribute("id"); id != "" {
+			selector += "[id=\"" + strings.Replace(id, "\"", "\\\"", -1) + "\"]"
+		}
+
+		nodeIDs, err := t.t.DOM.QuerySelectorAll(t.ctx.Ctx, hostRoot.NodeId, selector)
+		if err != nil {
+			break
+		}
+
+		index := -1
+		for i, nodeID := range nodeIDs {
+			if nodeID == hostID {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			break
+		}
+
+		hosts = append([]*browserk.ElementHost{{Frame: isFrame, Selector: selector, Index: index}}, hosts...)
+		root = hostRoot
+	}
+	return hosts
+}
+
+// resolveHosts finds each host of the chain, returning the node id of the innermost frame document/shadow root
+func (t *Tab) resolveHosts(ctx context.Context, hosts []*browserk.ElementHost) (int, error) {
+	rootID := t.getTopNodeID()
+	for _, host := range hosts {
+		nodeIDs, err := t.t.DOM.QuerySelectorAll(ctx, rootID, host.Selector)
+		if err != nil {
+			return 0, err
+		}
+
+		if host.Index >= len(nodeIDs) {
+			return 0, &ErrElementNotFound{Message: "host " + host.String() + " not found"}
+		}
+
+		// unknown nodes are detached or not in our copy of the document yet, the caller can refresh it
+		ele, ok := t.getElementByNodeID(nodeIDs[host.Index])
+		if !ok {
+			return 0, &ErrElementNotFound{Message: "host " + host.String() + " is not ready"}
+		}
+
+		if err := ele.WaitForReady(); err != nil {
+			return 0, err
+		}
+
+		if host.Frame {
+			rootID, err = ele.GetFrameDocumentNodeID()
+		} else {
+			rootID, err = ele.GetShadowRootNodeID()
+		}
+
+		if err != nil {
+			return 0, err
+		}
+	}
+	return rootID, nil
+}
+
+// findInHosts searches only the innermost frame document/shadow root of the host chain for the tag
+func (t *Tab) findInHosts(ctx context.Context, hosts []*browserk.ElementHost, tag string, refreshDocument bool) ([]*Element, error) {
+	rootID, err := t.resolveHosts(ctx, hosts)
+	if err != nil && refreshDocument {
+		t.ctx.Log.Debug().Msg("failed to find element hosts, refreshing document and trying again")
+		t.RefreshDocument()
+		rootID, err = t.resolveHosts(ctx, hosts)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return t.GetDocumentElementsBySelector(ctx, rootID, tag)
+}
+
+// GetFrameDocuments returns the url and serialized DOM of each same origin frame
+func (t *Tab) GetFrameDocuments() ([]*browserk.FrameDocument, error) {
+	docs := make([]*browserk.FrameDocument, 0)
+	for _, id := range t.getSameOriginFrameNodeIDs() {
+		frameURL, err := t.GetDocumentCurrentURL(id)
+		if err != nil {
+			continue
+		}
+
+		dom, err := t.GetPageSource(id)
+		if err != nil {
+			t.ctx.Log.Warn().Err(err).Str("url", frameURL).Msg("failed to get frame document")
+			continue
+		}
+		docs = append(docs, &browserk.FrameDocument{URL: frameURL, DOM: dom})
+	}
+	return docs, nil
+}
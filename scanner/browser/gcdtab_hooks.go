@@ -0,0 +1,133 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// hookBinding is the binding the hook script reports calls to
+const hookBinding = "__browserkHook"
+
+// hookScript overrides js functions that would open windows or block the page waiting on a user. window.open and
+// showModalDialog report their url if they were given one (so it can be crawled) and return a stub window, dialogs report their text and
+// return as if accepted, print and notification permission requests do nothing.
+const hookScript = `(function() {
+	if (window.__browserkHooked) {
+		return;
+	}
+	window.__browserkHooked = true;
+
+	var report = function(type, value) {
+		try {
+			window.__browserkHook(JSON.stringify({type: type, value: value === undefined ? '' : String(value), url: window.location.href}));
+		} catch (e) {}
+	};
+
+	// no target opens about:blank, don't resolve it to <page>/undefined
+	var resolve = function(target) {
+		if (target === undefined || target === null || String(target) === '') {
+			return '';
+		}
+		try {
+			return new URL(target, document.baseURI).href;
+		} catch (e) {
+			return String(target);
+		}
+	};
+
+	window.open = function(target) {
+		var href = resolve(target);
+		if (href) {
+			report('open', href);
+		}
+		var stub = {
+			closed: false,
+			opener: window,
+			location: {href: href},
+			document: {write: function() {}, writeln: function() {}, open: function() {}, close: function() {}},
+			close: function() { stub.closed = true; },
+			focus: function() {},
+			blur: function() {},
+			postMessage: function() {}
+		};
+		return stub;
+	};
+	window.showModalDialog = function(target) {
+		var href = resolve(target);
+		if (href) {
+			report('open', href);
+		}
+	};
+	window.alert = function(message) {
+		report('alert', message);
+	};
+	window.confirm = function(message) {
+		report('confirm', message);
+		return true;
+	};
+	window.prompt = function(message, value) {
+		report('prompt', message);
+		return value || 'browserk';
+	};
+	window.print = function() {};
+	if (window.Notification) {
+		window.Notification.requestPermission = function(callback) {
+			if (typeof callback === 'function') {
+				callback('denied');
+			}
+			return Promise.resolve('denied');
+		};
+	}
+})();`
+
+// hookCall reported by the hook script
+type hookCall struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	URL   string `json:"url"`
+}
+
+// installHooks into every document of this tab
+func (t *Tab) installHooks(ctx context.Context) error {
+	return t.AddBinding(ctx, hookBinding, hookScript, t.hookHandler)
+}
+
+func (t *Tab) hookHandler(nav *browserk.Navigation, payload string) {
+	call := &hookCall{}
+	if err := json.Unmarshal([]byte(payload), call); err != nil {
+		return
+	}
+
+	switch call.Type {
+	case "open":
+		t.openWindow(call.Value)
+	case "alert", "confirm", "prompt":
+		t.container.AddDialogEvent(&browserk.DialogEvent{
+			Type:     call.Type,
+			Message:  call.Value,
+			URL:      call.URL,
+			Observed: time.Now(),
+		})
+	}
+}
+
+// openWindow records the url of a window opened by the page instead of opening a new tab, the crawler creates
+// the navigation for it
+func (t *Tab) openWindow(openURL string) {
+	if openURL == "" || openURL == "about:blank" {
+		return
+	}
+
+	u, err := url.Parse(openURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		t.ctx.Log.Debug().Str("url", openURL).Msg("window opened, but not to a http(s) url")
+		return
+	}
+
+	t.ctx.Log.Info().Str("url", openURL).Msg("window opened!")
+	t.container.AddOpenedURL(openURL)
+}
@@ -51,6 +51,19 @@ func (t *Tab) MoveMouse(x, y float64) error {
 	return err
 }
 
+// MouseWheel at the x, y coords scrolling deltaY pixels down
+func (t *Tab) MouseWheel(x, y, deltaY float64) error {
+	mouseWheelParams := &gcdapi.InputDispatchMouseEventParams{TheType: "mouseWheel",
+		X:      x,
+		Y:      y,
+		DeltaX: 0,
+		DeltaY: deltaY,
+	}
+
+	_, err := t.t.Input.DispatchMouseEventWithParams(t.ctx.Ctx, mouseWheelParams)
+	return err
+}
+
 // SendKeys to whatever is focused, best called from Element.SendKeys which will
 // try to focus on the element first. Use \n for Enter, \b for backspace or \t for Tab.
 func (t *Tab) SendKeys(text string) error {
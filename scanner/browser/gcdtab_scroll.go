@@ -0,0 +1,164 @@
+package browser
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// scrollablesScript returns a css path for each element with overflowing content that can be scrolled
+const scrollablesScript = `(function() {
+	var path = function(ele) {
+		var parts = [];
+		for (; ele && ele.nodeType === 1 && ele !== document.documentElement; ele = ele.parentElement) {
+			if (ele.id) {
+				parts.unshift('#' + CSS.escape(ele.id));
+				return parts.join(' > ');
+			}
+			var index = 1;
+			for (var sibling = ele.previousElementSibling; sibling; sibling = sibling.previousElementSibling) {
+				if (sibling.tagName === ele.tagName) {
+					index++;
+				}
+			}
+			parts.unshift(ele.tagName.toLowerCase() + ':nth-of-type(' + index + ')');
+		}
+		parts.unshift('html');
+		return parts.join(' > ');
+	};
+
+	var found = [];
+	var all = document.body ? document.body.getElementsByTagName('*') : [];
+	for (var i = 0; i < all.length; i++) {
+		var ele = all[i];
+		if (ele.clientHeight === 0 || ele.scrollHeight <= ele.clientHeight) {
+			continue;
+		}
+		var overflow = window.getComputedStyle(ele).overflowY;
+		if (overflow === 'auto' || overflow === 'scroll') {
+			found.push(path(ele));
+		}
+	}
+	return found;
+})()`
+
+// scrollWindowScript scrolls the window down a page, returns false if it couldn't scroll any further
+const scrollWindowScript = `(function() {
+	var y = window.scrollY;
+	window.scrollBy(0, window.innerHeight);
+	return window.scrollY !== y;
+})()`
+
+// nodeCountScript returns the number of elements in the document
+const nodeCountScript = `document.getElementsByTagName('*').length`
+
+// FindScrollables returns elements whose content overflows and can be scrolled (feeds, tables, lists)
+func (t *Tab) FindScrollables(ctx context.Context) ([]*browserk.HTMLElement, error) {
+	bElements := make([]*browserk.HTMLElement, 0)
+	result, err := t.InjectJS(scrollablesScript)
+	if err != nil {
+		return bElements, err
+	}
+
+	paths, ok := result.([]interface{})
+	if !ok {
+		return bElements, nil
+	}
+
+	for _, path := range paths {
+		selector, ok := path.(string)
+		if !ok {
+			continue
+		}
+
+		elements, err := t.GetElementsBySelector(ctx, selector, false)
+		if err != nil || len(elements) == 0 {
+			t.ctx.Log.Debug().Err(err).Str("selector", selector).Msg("failed to find scrollable element")
+			continue
+		}
+
+		if htmlElement := ElementToHTMLElement(elements[0]); htmlElement != nil {
+			bElements = append(bElements, htmlElement)
+		}
+	}
+	return bElements, nil
+}
+
+// Scroll the window (nil) or element one step, waits for the page to be stable and returns true if more
+// content was loaded, either the number of elements grew or nodes were inserted (virtual lists swap them)
+func (t *Tab) Scroll(ctx context.Context, toScroll *browserk.HTMLElement) (bool, error) {
+	var ele *Element
+	var err error
+
+	if toScroll != nil {
+		findCtx, cancel := context.WithTimeout(ctx, time.Second*2)
+		ele, err = t.FindByHTMLElement(findCtx, toScroll, true)
+		cancel()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	before := t.nodeCount()
+	started := time.Now()
+	moved, err := t.scroll(ele)
+	if err != nil || !moved {
+		return false, err
+	}
+
+	// make sure we wait at least stableAfter for anything the scroll triggers
+	t.lastNodeChangeTimeVal.Store(time.Now())
+	if err := t.waitStable(ctx, t.stableAfter); err != nil && err != ErrTimedOut {
+		return false, err
+	}
+
+	if t.nodeCount() > before {
+		return true, nil
+	}
+
+	inserted, ok := t.lastNodeInsertTimeVal.Load().(time.Time)
+	return ok && inserted.After(started), nil
+}
+
+// scroll the window down a page or the element by its height with the mouse wheel, returns false if the
+// window could not scroll any further
+func (t *Tab) scroll(ele *Element) (bool, error) {
+	if ele == nil {
+		moved, err := t.InjectJS(scrollWindowScript)
+		if err != nil {
+			return false, err
+		}
+		scrolled, _ := moved.(bool)
+		return scrolled, nil
+	}
+
+	ele.ScrollTo()
+	points, err := ele.Dimensions()
+	if err != nil {
+		return false, err
+	}
+
+	x, y, err := centroid(points)
+	if err != nil {
+		return false, err
+	}
+
+	// content box quad is clockwise from the top left
+	if len(points) != 8 || points[5]-points[1] <= 0 {
+		return false, nil
+	}
+	height := points[5] - points[1]
+	return true, t.MouseWheel(float64(x), float64(y), height)
+}
+
+// nodeCount of the document, 0 if it could not be evaluated
+func (t *Tab) nodeCount() int {
+	result, err := t.InjectJS(nodeCountScript)
+	if err != nil {
+		return 0
+	}
+
+	count, _ := result.(float64)
+	return int(count)
+}
@@ -0,0 +1,204 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// snapshotScript returns the current origin's storage and IndexedDB object stores as JSON
+const snapshotScript = `(async function() {
+	const snapshot = {origin: location.origin, local: {}, session: {}, indexedDB: []};
+	for (let i = 0; i < localStorage.length; i++) {
+		const key = localStorage.key(i);
+		snapshot.local[key] = localStorage.getItem(key);
+	}
+	for (let i = 0; i < sessionStorage.length; i++) {
+		const key = sessionStorage.key(i);
+		if (key !== "__browserk_restored") {
+			snapshot.session[key] = sessionStorage.getItem(key);
+		}
+	}
+	if (!window.indexedDB || !indexedDB.databases) {
+		return JSON.stringify(snapshot);
+	}
+	const wait = (req) => new Promise((resolve, reject) => {
+		req.onsuccess = () => resolve(req.result);
+		req.onerror = () => reject(req.error);
+	});
+	for (const info of await indexedDB.databases()) {
+		const db = await wait(indexedDB.open(info.name));
+		for (const name of Array.from(db.objectStoreNames)) {
+			const store = db.transaction(name, "readonly").objectStore(name);
+			const keys = await wait(store.getAllKeys());
+			const values = await wait(store.getAll());
+			snapshot.indexedDB.push({
+				database: db.name,
+				version: db.version,
+				store: name,
+				keyPath: JSON.stringify(store.keyPath),
+				autoIncrement: store.autoIncrement,
+				keys: keys.map((k) => JSON.stringify(k)),
+				values: values.map((v) => JSON.stringify(v)),
+			});
+		}
+		db.close();
+	}
+	return JSON.stringify(snapshot);
+})()`
+
+// restoreScript is evaluated on every new document and restores the snapshot once per tab, so
+// logging out or clearing storage during a crawl isn't undone by the next page load
+const restoreScript = `(function(storage, stores) {
+	if (sessionStorage.getItem("__browserk_restored") !== null) {
+		return;
+	}
+	sessionStorage.setItem("__browserk_restored", "1");
+	for (const item of storage || []) {
+		if (item.Origin !== location.origin) {
+			continue;
+		}
+		const target = item.IsLocalStorage ? localStorage : sessionStorage;
+		if (target.getItem(item.Key) === null) {
+			target.setItem(item.Key, item.Value);
+		}
+	}
+	const databases = {};
+	for (const store of stores || []) {
+		if (store.Origin === location.origin) {
+			(databases[store.Database] = databases[store.Database] || []).push(store);
+		}
+	}
+	for (const name in databases) {
+		const req = indexedDB.open(name, databases[name][0].Version);
+		req.onupgradeneeded = () => {
+			for (const store of databases[name]) {
+				if (!req.result.objectStoreNames.contains(store.Store)) {
+					const keyPath = JSON.parse(store.KeyPath);
+					req.result.createObjectStore(store.Store, keyPath === null ? {autoIncrement: store.AutoIncrement} : {keyPath: keyPath, autoIncrement: store.AutoIncrement});
+				}
+			}
+		};
+		req.onsuccess = () => {
+			for (const store of databases[name]) {
+				if (!req.result.objectStoreNames.contains(store.Store)) {
+					continue;
+				}
+				const objectStore = req.result.transaction(store.Store, "readwrite").objectStore(store.Store);
+				for (let i = 0; i < (store.Values || []).length; i++) {
+					const value = JSON.parse(store.Values[i]);
+					if (objectStore.keyPath === null) {
+						objectStore.put(value, JSON.parse(store.Keys[i]));
+					} else {
+						objectStore.put(value);
+					}
+				}
+			}
+		};
+	}
+})(%s, %s);`
+
+// snapshotResult is what snapshotScript returns
+type snapshotResult struct {
+	Origin    string            `json:"origin"`
+	Local     map[string]string `json:"local"`
+	Session   map[string]string `json:"session"`
+	IndexedDB []struct {
+		Database      string   `json:"database"`
+		Version       int      `json:"version"`
+		Store         string   `json:"store"`
+		KeyPath       string   `json:"keyPath"`
+		AutoIncrement bool     `json:"autoIncrement"`
+		Keys          []string `json:"keys"`
+		Values        []string `json:"values"`
+	} `json:"indexedDB"`
+}
+
+// SnapshotSession captures the cookies of the current page along with the origin's local/sessionStorage
+// and IndexedDB object stores. Failing to read storage is logged, the cookies are still returned.
+func (t *Tab) SnapshotSession(ctx context.Context) (*browserk.SessionSnapshot, error) {
+	cookies, err := t.GetCookies()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &browserk.SessionSnapshot{Cookies: cookies, Taken: time.Now()}
+
+	result, err := t.snapshotStorage()
+	if err != nil {
+		t.ctx.Log.Warn().Err(err).Msg("failed to snapshot storage")
+		return snapshot, nil
+	}
+
+	for key, value := range result.Local {
+		snapshot.Storage = append(snapshot.Storage, &browserk.StorageItem{Origin: result.Origin, IsLocalStorage: true, Key: key, Value: value})
+	}
+
+	for key, value := range result.Session {
+		snapshot.Storage = append(snapshot.Storage, &browserk.StorageItem{Origin: result.Origin, Key: key, Value: value})
+	}
+
+	for _, store := range result.IndexedDB {
+		snapshot.IndexedDB = append(snapshot.IndexedDB, &browserk.IndexedDBStore{
+			Origin:        result.Origin,
+			Database:      store.Database,
+			Version:       store.Version,
+			Store:         store.Store,
+			KeyPath:       store.KeyPath,
+			AutoIncrement: store.AutoIncrement,
+			Keys:          store.Keys,
+			Values:        store.Values,
+		})
+	}
+	return snapshot, nil
+}
+
+func (t *Tab) snapshotStorage() (*snapshotResult, error) {
+	r, err := t.EvaluatePromiseScript(snapshotScript)
+	if err != nil {
+		return nil, err
+	}
+
+	if r == nil {
+		return nil, errors.New("snapshot script returned no result")
+	}
+
+	data, ok := r.Value.(string)
+	if !ok {
+		return nil, fmt.Errorf("snapshot script returned %T", r.Value)
+	}
+
+	result := &snapshotResult{}
+	if err := json.Unmarshal([]byte(data), result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RestoreSession sets the snapshot's cookies and adds a script to restore storage as soon as a
+// page of the snapshot's origin is loaded, must be called prior to the first ExecuteAction
+func (t *Tab) RestoreSession(ctx context.Context, snapshot *browserk.SessionSnapshot) error {
+	if err := t.SetCookies(ctx, snapshot.Cookies); err != nil {
+		return err
+	}
+
+	if len(snapshot.Storage) == 0 && len(snapshot.IndexedDB) == 0 {
+		return nil
+	}
+
+	storage, err := json.Marshal(snapshot.Storage)
+	if err != nil {
+		return err
+	}
+
+	stores, err := json.Marshal(snapshot.IndexedDB)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.t.Page.AddScriptToEvaluateOnNewDocument(ctx, fmt.Sprintf(restoreScript, storage, stores), "")
+	return err
+}
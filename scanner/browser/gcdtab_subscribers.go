@@ -43,6 +43,7 @@ func (t *Tab) subscribeTargetDetached() {
 	})
 }
 
+// subscribeWindowOpenEvent catches windows the hook script can't, such as links and forms with target=_blank
 func (t *Tab) subscribeWindowOpenEvent() {
 	t.t.Subscribe("Page.windowOpen", func(target *gcd.ChromeTarget, payload []byte) {
 		opened := &gcdapi.PageWindowOpenEvent{}
@@ -50,23 +51,7 @@ func (t *Tab) subscribeWindowOpenEvent() {
 		if err != nil {
 			return
 		}
-
-		u, err := url.Parse(opened.Params.Url)
-		if err != nil {
-			t.ctx.Log.Error().Err(err).Str("url", opened.Params.Url).Msg("window opened, but failed to parse!")
-			return
-		}
-
-		if t.ctx.Scope.Check(u) == browserk.InScope {
-			action := &browserk.Action{
-				Type:  browserk.ActLoadURL,
-				Input: []byte(opened.Params.Url),
-			}
-			t.ctx.Log.Info().Str("url", opened.Params.Url).Msg("window opened!")
-			t.ctx.Crawl.AddNavigation(browserk.NewNavigationFromBrowser(t.Nav().Copy(), browserk.TrigAutoBrowser, action))
-		} else {
-			t.ctx.Log.Info().Str("url", opened.Params.Url).Msg("window opened was out of scope!")
-		}
+		t.openWindow(opened.Params.Url)
 	})
 }
 
@@ -189,6 +174,16 @@ func (t *Tab) subscribeChildNodeRemoved() {
 		}
 	})
 }
+func (t *Tab) subscribeShadowRootPushed() {
+	t.t.Subscribe("DOM.shadowRootPushed", func(target *gcd.ChromeTarget, payload []byte) {
+		header := &gcdapi.DOMShadowRootPushedEvent{}
+		err := json.Unmarshal(payload, header)
+		if err == nil {
+			event := header.Params
+			t.dispatchNodeChange(&NodeChangeEvent{EventType: ShadowRootPushedEvent, ParentNodeID: event.HostId, Node: event.Root})
+		}
+	})
+}
 
 func (t *Tab) dispatchNodeChange(evt *NodeChangeEvent) {
 	select {
@@ -308,16 +303,45 @@ func (t *Tab) subscribeConsoleEvents() {
 	})
 }
 
+// subscribeDialogEvents handles dialogs the hook script can't stub (beforeunload, dialogs opened before the
+// script was added), recording their text
 func (t *Tab) subscribeDialogEvents() {
 	t.t.Subscribe("Page.javascriptDialogOpening", func(target *gcd.ChromeTarget, payload []byte) {
 		message := &gcdapi.PageJavascriptDialogOpeningEvent{}
 		if err := json.Unmarshal(payload, message); err == nil {
+			t.container.AddDialogEvent(&browserk.DialogEvent{
+				Type:     message.Params.Type,
+				Message:  message.Params.Message,
+				URL:      message.Params.Url,
+				Observed: time.Now(),
+			})
 			t.t.Page.HandleJavaScriptDialog(t.ctx.Ctx, true, "browserk")
 		}
 	})
 
+	// choosers are intercepted, so upload a fixture for inputs clicked outside of FillForm (custom upload buttons)
 	t.t.Subscribe("Page.fileChooserOpened", func(target *gcd.ChromeTarget, payload []byte) {
-		t.ctx.Log.Info().Msgf("fileChooserOpened: %s\n", string(payload))
+		message := &gcdapi.PageFileChooserOpenedEvent{}
+		if err := json.Unmarshal(payload, message); err != nil {
+			return
+		}
+		t.uploadFixture(message.Params.BackendNodeId)
+	})
+}
+
+func (t *Tab) subscribeBindingEvents() {
+	t.t.Subscribe("Runtime.bindingCalled", func(target *gcd.ChromeTarget, payload []byte) {
+		message := &gcdapi.RuntimeBindingCalledEvent{}
+		if err := json.Unmarshal(payload, message); err != nil {
+			return
+		}
+
+		t.bindingMutex.RLock()
+		handler, ok := t.bindings[message.Params.Name]
+		t.bindingMutex.RUnlock()
+		if ok {
+			handler(t.Nav().Copy(), message.Params.Payload)
+		}
 	})
 }
 
@@ -450,7 +474,10 @@ func (t *Tab) interceptedRequest(ctx *browserk.Context, message *gcdapi.FetchReq
 		return
 	}
 
-	if ctx.Scope.Check(u) != browserk.InScope && message.Params.ResourceType == "Document" {
+	// excluded hosts/URIs are never requested, other out of scope resources are still loaded so pages render
+	scope := ctx.Scope.Check(u)
+	if scope == browserk.ExcludedFromScope || scope != browserk.InScope && message.Params.ResourceType == "Document" {
+		t.ctx.Log.Debug().Str("url", modified.Request.Url).Str("type", string(message.Params.ResourceType)).Msg("dropping out of scope request")
 		t.t.Fetch.FailRequestWithParams(t.ctx.Ctx, fail)
 		return
 	}
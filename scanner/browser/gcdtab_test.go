@@ -341,3 +341,269 @@ func testInjectXHRReq(t *testing.T, respCh chan *browserk.InterceptedHTTPMessage
 		return true
 	}
 }
+
+func TestSessionSnapshot(t *testing.T) {
+	pool := browser.NewGCDBrowserPool(2, leaser)
+	if err := pool.Init(); err != nil {
+		t.Fatalf("failed to init pool")
+	}
+
+	defer leaser.Cleanup()
+	ctx := context.Background()
+
+	p, srv := testServer()
+	defer srv.Shutdown(ctx)
+
+	u := fmt.Sprintf("http://localhost:%s/session.html", p)
+	target, _ := url.Parse(u)
+	bCtx := mock.MakeMockContext(ctx, target)
+
+	b, _, err := pool.Take(bCtx)
+	if err != nil {
+		t.Fatalf("error taking browser: %s\n", err)
+	}
+
+	if err := b.Navigate(ctx, u); err != nil {
+		t.Fatalf("error getting url %s\n", err)
+	}
+
+	snapshot, err := b.SnapshotSession(ctx)
+	if err != nil {
+		t.Fatalf("error taking snapshot: %s\n", err)
+	}
+
+	if len(snapshot.Cookies) != 1 || len(snapshot.Storage) != 2 {
+		t.Fatalf("expected session cookie and storage items got %d cookies %d storage\n", len(snapshot.Cookies), len(snapshot.Storage))
+	}
+
+	restored, _, err := pool.Take(bCtx)
+	if err != nil {
+		t.Fatalf("error taking browser: %s\n", err)
+	}
+
+	if err := restored.RestoreSession(ctx, snapshot); err != nil {
+		t.Fatalf("error restoring session: %s\n", err)
+	}
+
+	if err := restored.Navigate(ctx, fmt.Sprintf("http://localhost:%s/index.html", p)); err != nil {
+		t.Fatalf("error getting url %s\n", err)
+	}
+
+	value, err := restored.(*browser.Tab).InjectJS(`document.cookie + "|" + localStorage.getItem("token") + "|" + sessionStorage.getItem("user")`)
+	if err != nil {
+		t.Fatalf("error reading restored session: %s\n", err)
+	}
+
+	if value != "session=abcd|local-token|admin" {
+		t.Fatalf("expected session to be restored got %v\n", value)
+	}
+}
+
+func TestHookBlockingJS(t *testing.T) {
+	pool := browser.NewGCDBrowserPool(1, leaser)
+	if err := pool.Init(); err != nil {
+		t.Fatalf("failed to init pool")
+	}
+
+	defer leaser.Cleanup()
+	ctx := context.Background()
+
+	p, srv := testServer()
+	defer srv.Shutdown(ctx)
+
+	u := fmt.Sprintf("http://localhost:%s/hooks.html", p)
+	target, _ := url.Parse(u)
+	bCtx := mock.MakeMockContext(ctx, target)
+
+	b, _, err := pool.Take(bCtx)
+	if err != nil {
+		t.Fatalf("error taking browser: %s\n", err)
+	}
+
+	if err := b.Init(mock.MakeMockConfig()); err != nil {
+		t.Fatalf("error init browser: %s\n", err)
+	}
+
+	nav := browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction(u))
+	if _, _, err := b.ExecuteAction(ctx, nav); err != nil {
+		t.Fatalf("error executing action: %s\n", err)
+	}
+
+	dialogs := b.GetDialogEvents()
+	if len(dialogs) != 2 || dialogs[0].Type != "alert" || dialogs[0].Message != "hooked" || dialogs[1].Type != "confirm" {
+		t.Fatalf("expected alert and confirm dialogs got %#v\n", dialogs)
+	}
+
+	value, err := b.(*browser.Tab).InjectJS(`document.getElementById('result').innerText`)
+	if err != nil || value != "confirmed" {
+		t.Fatalf("expected confirm to be accepted got %v %v\n", value, err)
+	}
+
+	// window.open() without a url opens about:blank and is not reported
+	opened := b.GetOpenedURLs()
+	if len(opened) != 1 || opened[0] != fmt.Sprintf("http://localhost:%s/window_sub1.html", p) {
+		t.Fatalf("expected window.open url to be recorded got %#v\n", opened)
+	}
+}
+
+func TestScrollLoadsMore(t *testing.T) {
+	pool := browser.NewGCDBrowserPool(1, leaser)
+	if err := pool.Init(); err != nil {
+		t.Fatalf("failed to init pool")
+	}
+
+	defer leaser.Cleanup()
+	ctx := context.Background()
+
+	p, srv := testServer()
+	defer srv.Shutdown(ctx)
+
+	u := fmt.Sprintf("http://localhost:%s/infinite_scroll.html", p)
+	target, _ := url.Parse(u)
+	bCtx := mock.MakeMockContext(ctx, target)
+
+	b, _, err := pool.Take(bCtx)
+	if err != nil {
+		t.Fatalf("error taking browser: %s\n", err)
+	}
+
+	if err := b.Init(mock.MakeMockConfig()); err != nil {
+		t.Fatalf("error init browser: %s\n", err)
+	}
+
+	nav := browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction(u))
+	if _, _, err := b.ExecuteAction(ctx, nav); err != nil {
+		t.Fatalf("error executing action: %s\n", err)
+	}
+
+	// the window itself doesn't scroll
+	if loaded, err := b.Scroll(ctx, nil); err != nil || loaded {
+		t.Fatalf("expected window scroll to not load more %v %v\n", loaded, err)
+	}
+
+	scrollables, err := b.FindScrollables(ctx)
+	if err != nil || len(scrollables) != 1 || scrollables[0].GetAttribute("id") != "feed" {
+		t.Fatalf("expected feed to be scrollable got %#v %v\n", scrollables, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if loaded, err := b.Scroll(ctx, scrollables[0]); err != nil || !loaded {
+			t.Fatalf("expected scroll %d to load more %v %v\n", i, loaded, err)
+		}
+	}
+
+	// the feed only has 3 pages
+	if loaded, _ := b.Scroll(ctx, scrollables[0]); loaded {
+		t.Fatalf("expected last scroll to not load more\n")
+	}
+}
+
+func TestShadowDOMAndFrames(t *testing.T) {
+	pool := browser.NewGCDBrowserPool(1, leaser)
+	if err := pool.Init(); err != nil {
+		t.Fatalf("failed to init pool")
+	}
+
+	defer leaser.Cleanup()
+	ctx := context.Background()
+
+	p, srv := testServer()
+	defer srv.Shutdown(ctx)
+
+	u := fmt.Sprintf("http://localhost:%s/shadow.html", p)
+	target, _ := url.Parse(u)
+	bCtx := mock.MakeMockContext(ctx, target)
+
+	b, _, err := pool.Take(bCtx)
+	if err != nil {
+		t.Fatalf("error taking browser: %s\n", err)
+	}
+
+	if err := b.Navigate(ctx, u); err != nil {
+		t.Fatalf("error getting url %s\n", err)
+	}
+
+	// closed shadow roots are not crawled
+	buttons, err := b.FindElements(ctx, "button", true)
+	if err != nil || len(buttons) != 2 {
+		t.Fatalf("expected buttons of both open shadow roots got %d %v\n", len(buttons), err)
+	}
+
+	for i, button := range buttons {
+		if len(button.Hosts) != 1 || button.Hosts[0].Frame || button.Hosts[0].Selector != "user-card" {
+			t.Fatalf("expected button to be hosted by a user-card got %#v\n", button.Hosts)
+		}
+
+		// replay finds the button in the right shadow root again
+		nav := browserk.NewNavigation(browserk.TrigCrawler, &browserk.Action{Type: browserk.ActLeftClick, Element: button})
+		if _, _, err := b.ExecuteAction(ctx, nav); err != nil {
+			t.Fatalf("error clicking shadow button %d: %s\n", i, err)
+		}
+	}
+
+	if evts := b.GetConsoleEvents(); len(evts) != 2 {
+		t.Fatalf("expected both shadow buttons to be clicked got %d\n", len(evts))
+	}
+
+	links, err := b.FindElements(ctx, "a", true)
+	if err != nil || len(links) != 1 {
+		t.Fatalf("expected link of the same origin frame got %d %v\n", len(links), err)
+	}
+
+	if hosts := links[0].Hosts; len(hosts) != 1 || !hosts[0].Frame || hosts[0].Selector != `iframe[id="same"]` {
+		t.Fatalf("expected link to be hosted by the iframe got %#v\n", hosts)
+	}
+
+	frames, err := b.GetFrameDocuments()
+	if err != nil || len(frames) != 1 || !strings.Contains(frames[0].DOM, "framed link") {
+		t.Fatalf("expected frame document got %#v %v\n", frames, err)
+	}
+}
+
+func TestUploadFiles(t *testing.T) {
+	pool := browser.NewGCDBrowserPool(1, leaser)
+	if err := pool.Init(); err != nil {
+		t.Fatalf("failed to init pool")
+	}
+
+	defer leaser.Cleanup()
+	ctx := context.Background()
+
+	p, srv := testServer()
+	defer srv.Shutdown(ctx)
+
+	u := fmt.Sprintf("http://localhost:%s/upload.html", p)
+	target, _ := url.Parse(u)
+	bCtx := mock.MakeMockContext(ctx, target)
+
+	b, _, err := pool.Take(bCtx)
+	if err != nil {
+		t.Fatalf("error taking browser: %s\n", err)
+	}
+
+	if err := b.Navigate(ctx, u); err != nil {
+		t.Fatalf("error getting url %s\n", err)
+	}
+
+	forms, err := b.FindForms(ctx)
+	if err != nil || len(forms) != 1 {
+		t.Fatalf("expected upload form got %d %v\n", len(forms), err)
+	}
+
+	for _, child := range forms[0].ChildElements {
+		if child.GetAttribute("type") == "submit" {
+			forms[0].SubmitButtonID = child.Hash()
+		}
+	}
+
+	entry := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(u))
+	nav := browserk.NewNavigationFromForm(entry, browserk.TrigCrawler, forms[0])
+	if _, _, err := b.ExecuteAction(ctx, nav); err != nil {
+		t.Fatalf("error filling form: %s\n", err)
+	}
+
+	evts := b.GetConsoleEvents()
+	if len(evts) != 1 || evts[0].Text != "browserk.png image/png" {
+		t.Fatalf("expected generated png to be uploaded got %#v\n", evts)
+	}
+}
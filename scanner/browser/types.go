@@ -131,6 +131,7 @@ const (
 	ChildNodeCountUpdatedEvent  ChangeEventType = 0x6
 	ChildNodeInsertedEvent      ChangeEventType = 0x7
 	ChildNodeRemovedEvent       ChangeEventType = 0x8
+	ShadowRootPushedEvent       ChangeEventType = 0x9
 )
 
 var changeEventMap = map[ChangeEventType]string{
@@ -143,6 +144,7 @@ var changeEventMap = map[ChangeEventType]string{
 	ChildNodeCountUpdatedEvent:  "ChildNodeCountUpdatedEvent",
 	ChildNodeInsertedEvent:      "ChildNodeInsertedEvent",
 	ChildNodeRemovedEvent:       "ChildNodeRemovedEvent",
+	ShadowRootPushedEvent:       "ShadowRootPushedEvent",
 }
 
 func (evt ChangeEventType) String() string {
@@ -163,6 +165,6 @@ type NodeChangeEvent struct {
 	Name           string            // attribute name
 	Value          string            // attribute value
 	CharacterData  string            // new text value for characterDataModified events
-	ParentNodeID   int               // node id for setChildNodesEvent, childNodeInsertedEvent and childNodeRemovedEvent
+	ParentNodeID   int               // node id for setChildNodesEvent, childNodeInsertedEvent, childNodeRemovedEvent and shadowRootPushedEvent (host)
 	PreviousNodeID int               // previous node id for childNodeInsertedEvent
 }
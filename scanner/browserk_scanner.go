@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +19,11 @@ import (
 	"gitlab.com/browserker/scanner/plugin"
 )
 
+const (
+	tokenRefreshWindow  = time.Minute * 2  // refresh tokens this long before they expire
+	minTokenRefreshWait = time.Second * 10 // so we don't hammer the target when refreshing fails
+)
+
 type crawlEvt struct {
 	nav []*browserk.Navigation
 	wg  *sync.WaitGroup
@@ -39,6 +45,9 @@ type Browserk struct {
 	attackCh     chan *attackEvt
 	stateMonitor *time.Ticker
 	mainContext  *browserk.Context
+	auth         []*auth.Service // one per role, or a single service for the configured Credentials
+	safety       *crawler.Safety
+	scheduler    browserk.Scheduler
 
 	idMutex          *sync.RWMutex
 	leasedBrowserIDs map[int64]struct{}
@@ -81,7 +90,7 @@ func (b *Browserk) getLeasedIDs() []int64 {
 
 // Init the browsers and stores
 func (b *Browserk) Init(ctx context.Context) error {
-	target, err := url.Parse(b.cfg.URL)
+	scope, err := NewScopeFromConfig(b.cfg)
 	if err != nil {
 		return err
 	}
@@ -94,8 +103,18 @@ func (b *Browserk) Init(ctx context.Context) error {
 		return err
 	}
 
-	b.mainContext.Auth = auth.New(b.cfg)
-	b.mainContext.Scope = b.scopeService(target)
+	if err := b.initAuth(); err != nil {
+		return err
+	}
+
+	if b.safety, err = crawler.NewSafety(b.cfg.Safety); err != nil {
+		return err
+	}
+	b.scheduler = crawler.NewScheduler(b.cfg.CrawlStrategy)
+
+	b.mainContext.Auth = b.auth[0]
+	b.mainContext.AddReqHandler(addAuthHeader)
+	b.mainContext.Scope = scope
 	b.mainContext.FormHandler = crawler.NewCrawlerFormHandler(b.cfg.FormData)
 	b.mainContext.Crawl = b.crawlGraph
 	b.mainContext.PluginServicer = pluginService
@@ -113,6 +132,18 @@ func (b *Browserk) Init(ctx context.Context) error {
 		b.cfg.MaxDepth = 10
 	}
 
+	if b.cfg.MaxPagesPerSkeleton == 0 {
+		b.cfg.MaxPagesPerSkeleton = 5
+	}
+
+	if b.cfg.MaxFormVariants == 0 {
+		b.cfg.MaxFormVariants = 4
+	}
+
+	if b.cfg.MaxScrolls == 0 {
+		b.cfg.MaxScrolls = 5
+	}
+
 	log.Info().Int("num_browsers", b.cfg.NumBrowsers).Int("max_depth", b.cfg.MaxDepth).Msg("Initializing...")
 
 	log.Logger.Info().Msg("initializing attack graph")
@@ -150,49 +181,205 @@ func (b *Browserk) Init(ctx context.Context) error {
 	return pool.Init()
 }
 
+// initAuth creates an auth service for each configured role, or for the Credentials if there are none
+func (b *Browserk) initAuth() error {
+	b.auth = make([]*auth.Service, 0)
+	if len(b.cfg.Roles) == 0 {
+		b.auth = append(b.auth, auth.New(b.cfg))
+	}
+
+	roles := make([]browserk.AuthService, 0)
+	for _, role := range b.cfg.Roles {
+		service := auth.NewForRole(b.cfg, role)
+		b.auth = append(b.auth, service)
+		roles = append(roles, service)
+	}
+
+	for _, service := range b.auth {
+		if err := service.Init(); err != nil {
+			return err
+		}
+	}
+
+	// browsers are initialized with the CustomHeaders, a bearer token there would be sent as every role
+	// and, once the service took it over, along with the one addAuthHeader adds
+	if len(b.cfg.Roles) > 0 || b.auth[0].AuthHeaders() != nil {
+		b.cfg.CustomHeaders = auth.WithoutAuthorization(b.cfg.CustomHeaders)
+	}
+	b.mainContext.Roles = roles
+	return nil
+}
+
+// authFor returns the auth service for the role that found the navigation
+func (b *Browserk) authFor(nav *browserk.Navigation) *auth.Service {
+	for _, service := range b.auth {
+		if service.Role() != nil && service.Role().Name == nav.Role {
+			return service
+		}
+	}
+	return b.auth[0]
+}
+
+// addAuthHeader adds the bearer token of the role the context is logged in as
+func addAuthHeader(c *browserk.Context, browser browserk.Browser, i *browserk.InterceptedHTTPRequest) bool {
+	if service, ok := c.Auth.(*auth.Service); ok {
+		return service.AddAuthHeader(c, browser, i)
+	}
+	return false
+}
+
 func (b *Browserk) initNavigation() {
 	log.Info().Msgf("ADDING URL %s", b.cfg.URL)
-	nav := browserk.NewNavigation(browserk.TrigInitial, &browserk.Action{
-		Type:   browserk.ActLoadURL,
-		Input:  []byte(b.cfg.URL),
-		Result: nil,
-	})
-	nav.Scope = browserk.InScope
-	nav.Distance = 0
 
 	// reset any inprocess navigations to unvisited because it didn't exit cleanly
 	b.crawlGraph.Find(b.mainContext.Ctx, browserk.NavInProcess, browserk.NavUnvisited, 1000)
 
-	if !b.crawlGraph.NavExists(nav) {
-		b.crawlGraph.AddNavigation(nav)
-		log.Info().Msg("Load URL added to crawl graph")
-	} else {
-		log.Info().Msg("Navigation for Load URL already exists")
+	// each role starts from the Load URL in its own crawl graph
+	for _, role := range b.roleNames() {
+		nav := browserk.NewNavigation(browserk.TrigInitial, &browserk.Action{
+			Type:   browserk.ActLoadURL,
+			Input:  []byte(b.cfg.URL),
+			Result: nil,
+		})
+		nav.Scope = browserk.InScope
+		nav.Distance = 0
+		nav.SetRole(role)
+
+		if !b.crawlGraph.NavExists(nav) {
+			b.crawlGraph.AddNavigation(nav)
+			log.Info().Str("role", role).Msg("Load URL added to crawl graph")
+		} else {
+			log.Info().Str("role", role).Msg("Navigation for Load URL already exists")
+		}
+	}
+}
+
+// roleNames we crawl as, a single empty role if there are no Roles configured
+func (b *Browserk) roleNames() []string {
+	roles := []string{""}
+	if len(b.cfg.Roles) > 0 {
+		roles = roles[:0]
+		for _, role := range b.cfg.Roles {
+			roles = append(roles, role.Name)
+		}
+	}
+	return roles
+}
+
+// seedNavigation fetches robots.txt, sitemaps, security.txt and manifest.json of each in scope origin and adds the
+// urls they list as Load URL navigations for each role. Paths robots.txt disallows are added as excluded navigations
+// so they show up in the report, unless CrawlDisallowed is set.
+func (b *Browserk) seedNavigation() {
+	seedCtx := b.mainContext.Copy()
+	seedCtx.Auth = b.auth[0]
+	seedCtx.Log = &log.Logger
+
+	browser, port, err := b.browsers.Take(seedCtx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to take browser for seeding")
+		return
+	}
+	defer b.browsers.Return(seedCtx.Ctx, port)
+	defer browser.Close()
+
+	if err := browser.Init(b.cfg); err != nil {
+		log.Error().Err(err).Msg("failed to Init browser for seeding")
+		return
+	}
+
+	seeds := crawler.FindSeeds(seedCtx, browser, b.seedOrigins())
+	log.Info().Int("urls", len(seeds.URLs)).Int("disallowed", len(seeds.Disallowed)).Msg("found seed urls")
+
+	for _, role := range b.roleNames() {
+		for _, seedURL := range seeds.URLs {
+			b.addSeed(seedURL, role, "")
+		}
+
+		for _, seedURL := range seeds.Disallowed {
+			reason := "disallowed by robots.txt"
+			if b.cfg.CrawlDisallowed {
+				reason = ""
+			}
+			b.addSeed(seedURL, role, reason)
+		}
+	}
+}
+
+// addSeed as an initial Load URL navigation, excluding it if there's a reason to, it looks like a logout or
+// safe mode blocks it
+func (b *Browserk) addSeed(seedURL, role, excludedReason string) {
+	nav := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(seedURL))
+	nav.Scope = browserk.InScope
+	nav.Distance = 0
+	nav.SetRole(role)
+
+	// sitemaps and robots.txt can list a logout url, loading it would end the role's session
+	if u, err := url.Parse(seedURL); err == nil && excludedReason == "" && !b.cfg.AllowLogout {
+		excludedReason = crawler.LogoutURL(u)
+	}
+
+	if excludedReason != "" {
+		log.Info().Str("url", seedURL).Str("role", role).Str("reason", excludedReason).Msg("excluding seed navigation")
+		nav.Exclude(excludedReason)
+	}
+
+	// same as the navigations the crawler finds, disallowed admin paths are exactly what safe mode blocks
+	if reason := b.safety.CheckNavigation(nav); reason != "" {
+		log.Info().Str("url", seedURL).Str("role", role).Str("reason", reason).Bool("blocked", nav.State == browserk.NavExcluded).Msg("safe mode classified seed navigation as destructive")
+	}
+
+	if b.crawlGraph.NavExists(nav) {
+		return
+	}
+
+	if err := b.crawlGraph.AddNavigation(nav); err != nil {
+		log.Error().Err(err).Str("url", seedURL).Msg("failed to add seed navigation")
 	}
 }
 
-func (b *Browserk) scopeService(target *url.URL) browserk.ScopeService {
-	allowed := b.cfg.AllowedHosts
-	ignored := b.cfg.IgnoredHosts
-	excluded := b.cfg.ExcludedHosts
+// seedOrigins of the target and any allowed hosts that are plain host names (not wildcards or networks)
+func (b *Browserk) seedOrigins() []*url.URL {
+	target, err := url.Parse(b.cfg.URL)
+	if err != nil {
+		return nil
+	}
 
-	scope := NewScopeService(target)
-	scope.AddScope(allowed, browserk.InScope)
-	scope.AddScope(ignored, browserk.OutOfScope)
-	scope.AddScope(excluded, browserk.ExcludedFromScope)
-	if b.cfg.ExcludedURIs != nil {
-		scope.AddExcludedURIs(b.cfg.ExcludedURIs)
+	origins := []*url.URL{{Scheme: target.Scheme, Host: target.Host}}
+	for _, host := range b.cfg.AllowedHosts {
+		if host == "" || host == target.Host || strings.ContainsAny(host, "*/") {
+			continue
+		}
+		origins = append(origins, &url.URL{Scheme: target.Scheme, Host: host})
 	}
-	return scope
+	return origins
 }
 
 // Start the browsers
 func (b *Browserk) Start() error {
+	for _, service := range b.auth {
+		if !service.MustLogin() {
+			continue
+		}
+
+		if err := b.checkLogin(service); err != nil {
+			return err
+		}
+	}
+
+	for _, service := range b.auth {
+		// only tokens with an exp claim need refreshing
+		if _, ok := service.TokenExpiry(); ok {
+			go b.refreshTokens(service)
+		}
+	}
+
+	b.seedNavigation()
+
 	for {
 
 		log.Info().Msg("searching for new navigation entries")
-		entries := b.crawlGraph.Find(b.mainContext.Ctx, browserk.NavUnvisited, browserk.NavInProcess, int64(b.cfg.NumBrowsers))
-		if entries == nil || len(entries) == 0 && b.browsers.Leased() == 0 {
+		entries := b.nextEntries()
+		if len(entries) == 0 && b.browsers.Leased() == 0 {
 			log.Info().Msg("no more crawler entries or active browsers, activating attack phase")
 			break
 		}
@@ -209,6 +396,11 @@ func (b *Browserk) Start() error {
 	}
 
 	log.Info().Msg("Crawler to complete")
+	// plugins comparing roles (authz) wait until every role's crawl is complete
+	completeCtx := b.mainContext.Copy()
+	completeCtx.Log = &log.Logger
+	b.mainContext.PluginServicer.CrawlComplete(completeCtx)
+
 	// if just crawling, we're done
 	if b.cfg.CrawlOnly {
 		return nil
@@ -233,6 +425,185 @@ func (b *Browserk) Start() error {
 	}
 }
 
+// checkLogin makes sure we can actually login before we start crawling, this also captures
+// the session cookies used for replaying requests as this role
+func (b *Browserk) checkLogin(service *auth.Service) error {
+	log.Info().Msg("validating login")
+	authCtx := b.mainContext.Copy()
+	authCtx.Auth = service
+	authCtx.Log = &log.Logger
+
+	browser, port, err := b.browsers.Take(authCtx)
+	if err != nil {
+		return err
+	}
+	defer b.browsers.Return(authCtx.Ctx, port)
+	defer browser.Close()
+
+	if err := browser.Init(b.cfg); err != nil {
+		return err
+	}
+
+	if err := authCtx.Auth.Login(authCtx, browser); err != nil {
+		log.Error().Err(err).Msg("failed to login")
+		return err
+	}
+	return nil
+}
+
+// refreshTokens waits until shortly before our bearer token expires and then refreshes it
+// either via the auth service or by logging in again. Browsers, including the ones in the middle
+// of a crawl, send the new token as addAuthHeader always adds the current token of their role.
+func (b *Browserk) refreshTokens(service *auth.Service) {
+	for {
+		expires, ok := service.TokenExpiry()
+		if !ok {
+			return
+		}
+
+		wait := time.Until(expires) - tokenRefreshWindow
+		if wait < minTokenRefreshWait {
+			wait = minTokenRefreshWait
+		}
+
+		select {
+		case <-b.mainContext.Ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		// token may have been updated by a login in the mean time
+		expires, ok = service.TokenExpiry()
+		if !ok || time.Until(expires) > tokenRefreshWindow {
+			continue
+		}
+
+		log.Info().Time("expires", expires).Msg("refreshing bearer token")
+		ctx, cancel := context.WithTimeout(b.mainContext.Ctx, time.Second*45)
+		err := service.RefreshToken(ctx)
+		if err == auth.ErrRefreshUnsupported {
+			if !service.MustLogin() {
+				// a static token (custom headers) we have no way of renewing, retrying would only repeat this
+				log.Warn().Time("expires", expires).Msg("bearer token is about to expire and can not be refreshed")
+				cancel()
+				return
+			}
+			err = b.checkLogin(service)
+		}
+
+		cancel()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to refresh bearer token")
+		}
+	}
+}
+
+// login the browser if we were configured with credentials. Browsers are initialized with the session
+// of the last successful login, so we only login if the restored session no longer works.
+func (b *Browserk) login(navCtx *browserk.Context, browser browserk.Browser) {
+	if !navCtx.Auth.MustLogin() {
+		return
+	}
+
+	if navCtx.Auth.Session() != nil && b.sessionRestored(navCtx, browser) {
+		navCtx.Log.Debug().Msg("restored session is still logged in")
+		return
+	}
+
+	if err := navCtx.Auth.Login(navCtx, browser); err != nil {
+		navCtx.Log.Warn().Err(err).Msg("failed to login, continuing unauthenticated")
+	}
+}
+
+// sessionRestored loads the target to check if the session restored by browser.Init is still logged in
+func (b *Browserk) sessionRestored(navCtx *browserk.Context, browser browserk.Browser) bool {
+	ctx, cancel := context.WithTimeout(navCtx.Ctx, time.Second*45)
+	defer cancel()
+
+	nav := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(b.cfg.URL))
+	if _, _, err := browser.ExecuteAction(ctx, nav); err != nil {
+		navCtx.Log.Warn().Err(err).Msg("failed to load target to check restored session")
+		return false
+	}
+	return navCtx.Auth.IsLoggedIn(navCtx, browser)
+}
+
+// sessionLost returns true if we were configured with logged in/out indicators and they
+// no longer match the browser's current state
+func (b *Browserk) sessionLost(navCtx *browserk.Context, browser browserk.Browser) bool {
+	if b.cfg.AuthIndicators == nil || !navCtx.Auth.MustLogin() {
+		return false
+	}
+	return !navCtx.Auth.IsLoggedIn(navCtx, browser)
+}
+
+// closedSession excludes the navigation if executing it cleared our session cookie, so we don't keep
+// logging ourselves out. The result is still stored so we can see what happened.
+func (b *Browserk) closedSession(navCtx *browserk.Context, nav *browserk.Navigation, result *browserk.NavigationResult) bool {
+	if !result.SessionClosed || b.cfg.AllowLogout {
+		return false
+	}
+	navCtx.Log.Info().Str("nav", nav.String()).Msg("navigation cleared the session cookie, excluding")
+
+	result.Role = nav.Role
+	if err := b.crawlGraph.AddResult(result); err != nil {
+		navCtx.Log.Error().Err(err).Msg("failed to add result")
+	}
+
+	if err := b.crawlGraph.ExcludeNavigation(nav.ID, "cleared the session cookie"); err != nil {
+		navCtx.Log.Error().Err(err).Msg("failed to exclude navigation")
+	}
+	return true
+}
+
+// skeletonSaturated returns true if enough other pages with the same DOM structure were already crawled, so
+// we explore the structure of the site and not every page rendered from the same template (product details etc)
+func (b *Browserk) skeletonSaturated(navCtx *browserk.Context, nav *browserk.Navigation, result *browserk.NavigationResult) bool {
+	if b.cfg.MaxPagesPerSkeleton < 0 || result.Skeleton == nil {
+		return false
+	}
+
+	pages := b.crawlGraph.SkeletonPages(result.Skeleton, nav.Role)
+	if len(pages) < b.cfg.MaxPagesPerSkeleton {
+		return false
+	}
+
+	// actions on a page we are already expanding (menus etc) keep the page's skeleton
+	for _, page := range pages {
+		if page == result.EndURL {
+			return false
+		}
+	}
+	navCtx.Log.Info().Str("url", result.EndURL).Int("pages", len(pages)).Msg("page skeleton already crawled enough, not expanding")
+	return true
+}
+
+// relogin logs the browser back in and replays the navigation path to get the browser
+// back to the state it was in prior to losing the session. The login and each step of the
+// path get as long as a crawl step.
+func (b *Browserk) relogin(navCtx *browserk.Context, browser browserk.Browser, path []*browserk.Navigation) error {
+	navCtx.Log.Info().Int("path_len", len(path)).Msg("session lost, logging back in")
+	reloginCtx := navCtx.Copy()
+	reloginCtx.Log = navCtx.Log
+	ctx, cancel := context.WithTimeout(navCtx.Ctx, time.Second*45*time.Duration(len(path)+1))
+	defer cancel()
+	reloginCtx.Ctx = ctx
+
+	if err := reloginCtx.Auth.Login(reloginCtx, browser); err != nil {
+		return err
+	}
+
+	for _, nav := range path {
+		ctx, cancel := context.WithTimeout(reloginCtx.Ctx, time.Second*45)
+		_, _, err := browser.ExecuteAction(ctx, nav)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (b *Browserk) processEntries() {
 	for {
 		select {
@@ -253,9 +624,29 @@ func (b *Browserk) processEntries() {
 	}
 }
 
+// nextEntries asks the scheduler which of the unvisited navigations to crawl next, marks them in process and
+// returns the path to each of them
+func (b *Browserk) nextEntries() [][]*browserk.Navigation {
+	candidates := b.crawlGraph.FindNavigations(b.mainContext.Ctx, browserk.NavUnvisited)
+	entries := make([][]*browserk.Navigation, 0)
+	for _, nav := range b.scheduler.Next(candidates, b.cfg.NumBrowsers) {
+		path := b.crawlGraph.FindPathByNavID(b.mainContext.Ctx, nav.ID)
+		if len(path) == 0 {
+			continue
+		}
+
+		if err := b.crawlGraph.SetNavigationState(nav.ID, browserk.NavInProcess); err != nil {
+			log.Error().Err(err).Msg("failed to set navigation in process")
+		}
+		entries = append(entries, path)
+	}
+	return entries
+}
+
 func (b *Browserk) crawl(navs []*browserk.Navigation) {
 
 	navCtx := b.mainContext.Copy()
+	navCtx.Auth = b.authFor(navs[len(navs)-1])
 
 	browser, port, err := b.browsers.Take(navCtx)
 	if err != nil {
@@ -271,6 +662,10 @@ func (b *Browserk) crawl(navs []*browserk.Navigation) {
 	b.addLeased(browser.ID())
 	defer b.removeLeased(browser.ID())
 
+	loginLogger := log.With().Int64("browser_id", browser.ID()).Logger()
+	navCtx.Log = &loginLogger
+	b.login(navCtx, browser)
+
 	crawler := crawler.New(b.cfg)
 	if err := crawler.Init(); err != nil {
 		b.browsers.Return(navCtx.Ctx, port)
@@ -278,15 +673,9 @@ func (b *Browserk) crawl(navs []*browserk.Navigation) {
 		return
 	}
 
-	isFinal := false
-	for i, nav := range navs {
-		// we are on the last navigation of this path so we'll want to capture some stuff
-		if i == len(navs)-1 {
-			isFinal = true
-		}
-
-		ctx, cancel := context.WithTimeout(navCtx.Ctx, time.Second*45)
-		navCtx.Ctx = ctx
+	// each step gets its own timeout, the path's context is kept to return the browser with
+	pathCtx := navCtx.Ctx
+	for i := range navs {
 		logger := log.With().
 			Int64("browser_id", browser.ID()).
 			Str("path", b.printActionStep(navs)).Int("step", i).
@@ -294,34 +683,79 @@ func (b *Browserk) crawl(navs []*browserk.Navigation) {
 			Logger()
 		navCtx.Log = &logger
 
-		defer cancel()
-
-		result, newNavs, err := crawler.Process(navCtx, browser, nav, isFinal)
-		if err != nil {
-			navCtx.Log.Error().Err(err).Msg("failed to process action")
-			b.crawlGraph.SetNavigationState(nav.ID, browserk.NavFailed)
+		if !b.crawlStep(pathCtx, navCtx, browser, crawler, navs, i) {
 			break
 		}
+	}
+	navCtx.Ctx = pathCtx
+	navCtx.Log.Info().Msg("closing browser")
+	browser.Close()
+	b.browsers.Return(pathCtx, port)
+}
 
-		if isFinal {
-			navCtx.Log.Debug().Int("nav_count", len(newNavs)).Str("NEW_NAVS", b.printActionStep(newNavs)).Msg("to be added")
-			if err := b.crawlGraph.AddNavigations(newNavs); err != nil {
-				navCtx.Log.Error().Err(err).Msg("failed to add new navigations")
-			}
+// crawlStep processes the i'th navigation of the path with a timeout derived from pathCtx, returns
+// false if the rest of the path can't be crawled
+func (b *Browserk) crawlStep(pathCtx context.Context, navCtx *browserk.Context, browser browserk.Browser, navCrawler *crawler.BrowserkCrawler, navs []*browserk.Navigation, i int) bool {
+	nav := navs[i]
+	// we are on the last navigation of this path so we'll want to capture some stuff
+	isFinal := i == len(navs)-1
+
+	ctx, cancel := context.WithTimeout(pathCtx, time.Second*45)
+	defer cancel()
+	navCtx.Ctx = ctx
+
+	result, newNavs, err := navCrawler.Process(navCtx, browser, nav, isFinal)
+	if err == nil && b.closedSession(navCtx, nav, result) {
+		if !isFinal {
+			// the rest of the path can't be reached without the session
+			b.crawlGraph.SetNavigationState(navs[len(navs)-1].ID, browserk.NavFailed)
 		}
-		if err := b.crawlGraph.AddResult(result); err != nil {
-			navCtx.Log.Error().Err(err).Msg("failed to add result")
+		return false
+	}
+
+	if err == nil && b.sessionLost(navCtx, browser) {
+		// results from a logged out session are useless, login, get back to where we were and try again.
+		// the step's timeout is mostly spent so relogin and the reprocess each get their own
+		navCtx.Ctx = pathCtx
+		if reloginErr := b.relogin(navCtx, browser, navs[:i]); reloginErr != nil {
+			navCtx.Log.Warn().Err(reloginErr).Msg("failed to restore session")
+		} else {
+			retryCtx, retryCancel := context.WithTimeout(pathCtx, time.Second*45)
+			defer retryCancel()
+			navCtx.Ctx = retryCtx
+			result, newNavs, err = navCrawler.Process(navCtx, browser, nav, isFinal)
 		}
 	}
-	navCtx.Log.Info().Msg("closing browser")
-	browser.Close()
-	b.browsers.Return(navCtx.Ctx, port)
+
+	if err != nil {
+		navCtx.Log.Error().Err(err).Msg("failed to process action")
+		b.crawlGraph.SetNavigationState(nav.ID, browserk.NavFailed)
+		return false
+	}
+
+	if isFinal && b.skeletonSaturated(navCtx, nav, result) {
+		newNavs = nil
+	}
+
+	if isFinal {
+		b.scheduler.Visited(nav, result, newNavs)
+		navCtx.Log.Debug().Int("nav_count", len(newNavs)).Str("NEW_NAVS", b.printActionStep(newNavs)).Msg("to be added")
+		if err := b.crawlGraph.AddNavigations(newNavs); err != nil {
+			navCtx.Log.Error().Err(err).Msg("failed to add new navigations")
+		}
+	}
+	result.Role = nav.Role
+	if err := b.crawlGraph.AddResult(result); err != nil {
+		navCtx.Log.Error().Err(err).Msg("failed to add result")
+	}
+	return true
 }
 
 // attack iterates over the plugin, giving it it's own browser since we need to add
 // the context specific to that plugin
 func (b *Browserk) attack(navs []*browserk.NavigationWithResult) {
 	navCtx := b.mainContext.Copy()
+	navCtx.Auth = b.authFor(navs[len(navs)-1].Navigation)
 
 	browser, port, err := b.browsers.Take(navCtx)
 	if err != nil {
@@ -340,6 +774,12 @@ func (b *Browserk) attack(navs []*browserk.NavigationWithResult) {
 		Logger()
 	navCtx.Log = &logger
 	b.addLeased(browser.ID())
+	b.login(navCtx, browser)
+
+	path := make([]*browserk.Navigation, len(navs))
+	for i, nav := range navs {
+		path[i] = nav.Navigation
+	}
 
 	isFinal := false
 	for i, nav := range navs {
@@ -354,9 +794,21 @@ func (b *Browserk) attack(navs []*browserk.NavigationWithResult) {
 			ctx, cancel := context.WithTimeout(navCtx.Ctx, time.Second*45)
 			browser.ExecuteAction(ctx, nav.Navigation)
 			cancel()
+			if b.sessionLost(navCtx, browser) {
+				if err := b.relogin(navCtx, browser, path[:i+1]); err != nil {
+					navCtx.Log.Warn().Err(err).Msg("failed to restore session")
+				}
+			}
 			continue
 		}
 
+		// script endpoints the browser never sent go through the same scope, safety and audit checks
+		if nav.Result != nil {
+			for _, target := range nav.Result.AttackTargets {
+				nav.Result.Messages = append(nav.Result.Messages, target.Message(nav.Result.EndURL))
+			}
+		}
+
 		// Create request iterator
 		mIt := iterator.NewMessageIter(nav)
 		for mIt.Rewind(); mIt.Valid(); mIt.Next() {
@@ -374,6 +826,11 @@ func (b *Browserk) attack(navs []*browserk.NavigationWithResult) {
 				continue
 			}
 
+			if reason := b.safety.CheckRequest(req.Request.Method, req.Request.Url); reason != "" {
+				navCtx.Log.Info().Str("url", req.Request.Url).Str("reason", reason).Msg("safe mode, not attacking request")
+				continue
+			}
+
 			if state, err := b.pluginStore.SetRequestAudit(req); err != nil || state != browserk.NotAudited {
 				navCtx.Log.Info().Str("url", req.Request.Url).Msgf("already audited this request, skipping")
 				continue
@@ -400,6 +857,13 @@ func (b *Browserk) attack(navs []*browserk.NavigationWithResult) {
 
 				navCtx.PluginServicer.Inject(b.mainContext, injector)
 			}
+
+			// our injections may have logged us out, restore the session before the next request
+			if b.sessionLost(navCtx, browser) {
+				if err := b.relogin(navCtx, browser, path[:i]); err != nil {
+					navCtx.Log.Warn().Err(err).Msg("failed to restore session")
+				}
+			}
 		}
 
 		b.crawlGraph.SetNavigationState(nav.Navigation.ID, browserk.NavAudited)
@@ -418,12 +882,22 @@ func (b *Browserk) Stop() error {
 	log.Info().Msg("Completing Ctx")
 	b.mainContext.CtxComplete()
 
+	for _, service := range b.auth {
+		if err := service.Close(); err != nil {
+			log.Warn().Err(err).Msg("failed to close auth service")
+		}
+	}
+
 	log.Info().Msg("Stopping browsers")
 	err := b.browsers.Shutdown()
 	if err != nil {
 		log.Warn().Err(err).Msg("failed to close browsers")
 	}
 
+	if err := browser.RemoveFixtures(); err != nil {
+		log.Warn().Err(err).Msg("failed to remove upload fixtures")
+	}
+
 	log.Info().Msg("Closing plugin store")
 	err = b.pluginStore.Close()
 	if err != nil {
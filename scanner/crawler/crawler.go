@@ -2,6 +2,7 @@ package crawler
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -10,16 +11,25 @@ import (
 
 // BrowserkCrawler crawls a site
 type BrowserkCrawler struct {
-	cfg *browserk.Config
+	cfg    *browserk.Config
+	safety *Safety
+
+	routeLock *sync.Mutex
+	routes    []string // spa routes the route script reported
 }
 
 // New crawler for a site
 func New(cfg *browserk.Config) *BrowserkCrawler {
-	return &BrowserkCrawler{cfg: cfg}
+	return &BrowserkCrawler{cfg: cfg, routeLock: &sync.Mutex{}}
 }
 
 // Init the crawler, if necessary
 func (b *BrowserkCrawler) Init() error {
+	safety, err := NewSafety(b.cfg.Safety)
+	if err != nil {
+		return err
+	}
+	b.safety = safety
 	return nil
 }
 
@@ -34,9 +44,14 @@ func (b *BrowserkCrawler) Process(bctx *browserk.Context, browser browserk.Brows
 	}
 	startCookies, err := browser.GetCookies()
 
-	//clear out storage and console events before executing our action
+	b.hookRoutes(bctx, browser)
+
+	//clear out storage, console events, opened windows and routes before executing our action
 	browser.GetStorageEvents()
 	browser.GetConsoleEvents()
+	browser.GetDialogEvents()
+	browser.GetOpenedURLs()
+	b.takeRoutes()
 
 	if isFinal {
 		diff = b.snapshot(bctx, browser)
@@ -62,7 +77,7 @@ func (b *BrowserkCrawler) Process(bctx *browserk.Context, browser browserk.Brows
 	}
 
 	// capture results
-	b.buildResult(result, beforeAction, browser)
+	b.buildResult(bctx, result, beforeAction, browser)
 
 	// dispatch new cookie event
 	for _, cookie := range result.Cookies {
@@ -75,12 +90,16 @@ func (b *BrowserkCrawler) Process(bctx *browserk.Context, browser browserk.Brows
 	potentialNavs := make([]*browserk.Navigation, 0)
 	if isFinal {
 		potentialNavs = b.FindNewNav(bctx, diff, entry, browser)
+		foundNavs := b.findScriptNavs(bctx, entry, result)
+		foundNavs = append(foundNavs, b.findURLNavs(bctx, entry, append(b.takeRoutes(), browser.GetOpenedURLs()...))...)
+		b.checkSafety(bctx, foundNavs)
+		potentialNavs = append(potentialNavs, foundNavs...)
 	}
 	return result, potentialNavs, nil
 }
 
 // buildResult captures various data points after we executed an Action
-func (b *BrowserkCrawler) buildResult(result *browserk.NavigationResult, start time.Time, browser browserk.Browser) {
+func (b *BrowserkCrawler) buildResult(bctx *browserk.Context, result *browserk.NavigationResult, start time.Time, browser browserk.Browser) {
 	messages, err := browser.GetMessages()
 	result.AddError(err)
 	result.Messages = browserk.MessagesAfterRequestTime(messages, start)
@@ -88,17 +107,119 @@ func (b *BrowserkCrawler) buildResult(result *browserk.NavigationResult, start t
 	dom, err := browser.GetDOM()
 	result.AddError(err)
 	result.DOM = dom
+	result.Skeleton = SkeletonHash(dom)
 	endURL, err := browser.GetURL()
 	result.AddError(err)
 	result.EndURL = endURL
 	cookies, err := browser.GetCookies()
 	result.AddError(err)
+	if err == nil && b.hasSession(bctx) {
+		result.SessionClosed = SessionClosed(b.sessionCookie(), result.Cookies, cookies)
+	}
 	result.Cookies = browserk.DiffCookies(result.Cookies, cookies)
 	result.StorageEvents = browser.GetStorageEvents()
 	result.ConsoleEvents = browser.GetConsoleEvents()
+	result.DialogEvents = browser.GetDialogEvents()
+	result.FrameDocuments, err = browser.GetFrameDocuments()
+	result.AddError(err)
 	result.Hash()
 }
 
+// sessionCookie we were configured to check for, if any
+func (b *BrowserkCrawler) sessionCookie() string {
+	if b.cfg.AuthIndicators == nil {
+		return ""
+	}
+	return b.cfg.AuthIndicators.SessionCookie
+}
+
+// hasSession returns true if we logged in or a session cookie was configured, otherwise a cookie
+// with a session like name being cleared or rotated is not a session we need to keep
+func (b *BrowserkCrawler) hasSession(bctx *browserk.Context) bool {
+	return b.sessionCookie() != "" || (bctx.Auth != nil && bctx.Auth.MustLogin())
+}
+
+// excludeLogout marks the navigation as excluded if a reason was given, so we don't end our own session
+func (b *BrowserkCrawler) excludeLogout(bctx *browserk.Context, nav *browserk.Navigation, reason string) {
+	if reason == "" || b.cfg.AllowLogout {
+		return
+	}
+	bctx.Log.Info().Str("nav", nav.String()).Str("reason", reason).Msg("excluding likely logout navigation")
+	nav.Exclude(reason)
+}
+
+// excludeElement marks the navigation as excluded if the element matched an excluded element rule or
+// looks like a logout
+func (b *BrowserkCrawler) excludeElement(bctx *browserk.Context, nav *browserk.Navigation, ele *browserk.HTMLElement, selected map[string][]string) {
+	if rule := bctx.Scope.ExcludedElement(ele, selected); rule != "" {
+		bctx.Log.Info().Str("nav", nav.String()).Str("rule", rule).Msg("excluding navigation")
+		nav.Exclude(rule)
+		return
+	}
+	b.excludeLogout(bctx, nav, LogoutElement(ele))
+}
+
+// formVariants fills the form once per value set of its selects, radios and checkboxes, or just once if
+// MaxFormVariants is not set
+func (b *BrowserkCrawler) formVariants(bctx *browserk.Context, form *browserk.HTMLFormElement) []*browserk.HTMLFormElement {
+	if b.cfg.MaxFormVariants <= 0 {
+		bctx.FormHandler.Fill(form)
+		return []*browserk.HTMLFormElement{form}
+	}
+	return bctx.FormHandler.Variants(form, b.cfg.MaxFormVariants)
+}
+
+// excludeForm marks the navigation as excluded if the form matched ExcludedForms, an excluded element
+// rule or looks like a logout
+func (b *BrowserkCrawler) excludeForm(bctx *browserk.Context, nav *browserk.Navigation, form *browserk.HTMLFormElement, selected map[string][]string) {
+	if rule := bctx.Scope.ExcludedForm(form, selected); rule != "" {
+		bctx.Log.Info().Str("nav", nav.String()).Str("rule", rule).Msg("excluding navigation")
+		nav.Exclude(rule)
+		return
+	}
+	b.excludeLogout(bctx, nav, LogoutForm(form))
+}
+
+// checkSafety blocks or restricts the destructive navigations depending on the safe mode level
+func (b *BrowserkCrawler) checkSafety(bctx *browserk.Context, navs []*browserk.Navigation) {
+	if b.safety == nil {
+		return
+	}
+
+	for _, nav := range navs {
+		if reason := b.safety.CheckNavigation(nav); reason != "" {
+			bctx.Log.Info().Str("nav", nav.String()).Str("reason", reason).Bool("blocked", nav.State == browserk.NavExcluded).Msg("safe mode classified navigation as destructive")
+		}
+	}
+}
+
+// selectExcluded looks up the excluded element selectors, returning the selectors each element
+// matched keyed by the element's hash. Forms are keyed by their form hash.
+func (b *BrowserkCrawler) selectExcluded(bctx *browserk.Context, browser browserk.Browser) map[string][]string {
+	selected := make(map[string][]string)
+	for _, selector := range bctx.Scope.ExcludedSelectors() {
+		ctx, cancel := context.WithTimeout(bctx.Ctx, time.Second*3)
+		elements, err := browser.FindElements(ctx, selector, false)
+		cancel()
+		if err != nil {
+			bctx.Log.Warn().Err(err).Str("selector", selector).Msg("failed to find excluded elements")
+			continue
+		}
+
+		for _, ele := range elements {
+			hash := string(ele.Hash())
+			selected[hash] = append(selected[hash], selector)
+			if ele.Type == browserk.FORM {
+				// same fields the form hash uses
+				form := &browserk.HTMLFormElement{Type: ele.Type, Attributes: ele.Attributes, Events: ele.Events, Hidden: ele.Hidden, Hosts: ele.Hosts}
+				formHash := string(form.Hash())
+				selected[formHash] = append(selected[formHash], selector)
+			}
+		}
+	}
+	return selected
+}
+
 func (b *BrowserkCrawler) snapshot(bctx *browserk.Context, browser browserk.Browser) *ElementDiffer {
 	diff := NewElementDiffer()
 	browser.RefreshDocument()
@@ -177,6 +298,7 @@ func (b *BrowserkCrawler) FindNewNav(bctx *browserk.Context, diff *ElementDiffer
 	baseHref := browser.GetBaseHref()
 	//docURL, _ := browser.GetURL()
 	navDiff := NewElementDiffer()
+	selected := b.selectExcluded(bctx, browser)
 	// Pull out forms (highest priority)
 	ctx, cancel := context.WithTimeout(bctx.Ctx, time.Second*3)
 	defer cancel()
@@ -206,9 +328,11 @@ func (b *BrowserkCrawler) FindNewNav(bctx *browserk.Context, diff *ElementDiffer
 
 		scope := bctx.Scope.ResolveBaseHref(baseHref, form.GetAttribute("action"))
 		if scope == browserk.InScope {
-			nav := browserk.NewNavigationFromForm(entry, browserk.TrigCrawler, form)
-			bctx.FormHandler.Fill(form)
-			navs = append(navs, nav)
+			for _, variant := range b.formVariants(bctx, form) {
+				nav := browserk.NewNavigationFromForm(entry, browserk.TrigCrawler, variant)
+				b.excludeForm(bctx, nav, variant, selected)
+				navs = append(navs, nav)
+			}
 		}
 	}
 
@@ -221,15 +345,17 @@ func (b *BrowserkCrawler) FindNewNav(bctx *browserk.Context, diff *ElementDiffer
 	}
 
 	bctx.Log.Debug().Int("button_count", len(bElements)).Msg("found buttons")
-	for _, b := range bElements {
+	for _, button := range bElements {
 		// don't want to re-add the same elements
-		if navDiff.Has(b.ElementType(), b.Hash()) || diff.Has(browserk.BUTTON, b.Hash()) || b.Hidden {
+		if navDiff.Has(button.ElementType(), button.Hash()) || diff.Has(browserk.BUTTON, button.Hash()) || button.Hidden {
 			continue
 		}
-		navDiff.Add(b.ElementType(), b.Hash())
+		navDiff.Add(button.ElementType(), button.Hash())
 
-		bctx.Log.Info().Msgf("adding button %#v", b)
-		navs = append(navs, browserk.NewNavigationFromElement(entry, browserk.TrigCrawler, b, browserk.ActLeftClick))
+		bctx.Log.Info().Msgf("adding button %#v", button)
+		nav := browserk.NewNavigationFromElement(entry, browserk.TrigCrawler, button, browserk.ActLeftClick)
+		b.excludeElement(bctx, nav, button, selected)
+		navs = append(navs, nav)
 	}
 
 	ctx, cancel = context.WithTimeout(bctx.Ctx, time.Second*3)
@@ -255,6 +381,7 @@ func (b *BrowserkCrawler) FindNewNav(bctx *browserk.Context, diff *ElementDiffer
 			bctx.Log.Info().Str("baseHref", baseHref).Str("href", a.Attributes["href"]).Msg("in scope, adding")
 			nav := browserk.NewNavigationFromElement(entry, browserk.TrigCrawler, a, browserk.ActLeftClick)
 			nav.Scope = scope
+			b.excludeElement(bctx, nav, a, selected)
 			navs = append(navs, nav)
 		}
 	}
@@ -303,6 +430,7 @@ func (b *BrowserkCrawler) FindNewNav(bctx *browserk.Context, diff *ElementDiffer
 				log.Info().Msgf("Adding action: %s for eventType: %v", browserk.ActionTypeMap[actType], eventType)
 				nav := browserk.NewNavigationFromElement(entry, browserk.TrigCrawler, ele, actType)
 				nav.Scope = browserk.InScope
+				b.excludeElement(bctx, nav, ele, selected)
 				log.Info().Msgf("nav hash: %s", string(nav.ID))
 				navs = append(navs, nav)
 			}
@@ -328,6 +456,7 @@ func (b *BrowserkCrawler) FindNewNav(bctx *browserk.Context, diff *ElementDiffer
 		navDiff.Add(img.ElementType(), img.Hash())
 		nav := browserk.NewNavigationFromElement(entry, browserk.TrigCrawler, img, browserk.ActLeftClick)
 		nav.Scope = browserk.InScope
+		b.excludeElement(bctx, nav, img, selected)
 		navs = append(navs, nav)
 	}
 
@@ -353,7 +482,13 @@ func (b *BrowserkCrawler) FindNewNav(bctx *browserk.Context, diff *ElementDiffer
 
 		nav := browserk.NewNavigationFromElement(entry, browserk.TrigCrawler, txt, browserk.ActLeftClick)
 		nav.Scope = browserk.InScope
+		b.excludeElement(bctx, nav, txt, selected)
 		navs = append(navs, nav)
 	}
+
+	// last, scrolling changes the page
+	navs = append(navs, b.findScrollNavs(bctx, entry, browser)...)
+
+	b.checkSafety(bctx, navs)
 	return navs
 }
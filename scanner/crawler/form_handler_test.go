@@ -59,3 +59,60 @@ func TestFormContext(t *testing.T) {
 		}
 	}
 }
+
+func TestFormVariants(t *testing.T) {
+	formHandler := crawler.NewCrawlerFormHandler(testFormData)
+	form := mock.MakeMockAddressForm()
+	shipping := mock.MakeMockInput("radio", "shipping", "")
+	shipping.Attributes["value"] = "standard"
+	express := mock.MakeMockInput("radio", "shipping", "")
+	express.Attributes["value"] = "express"
+	form.ChildElements = append(form.ChildElements,
+		&browserk.HTMLElement{Type: browserk.SELECT, Attributes: map[string]string{"name": "country"}},
+		&browserk.HTMLElement{Type: browserk.OPTION, Attributes: map[string]string{"value": ""}, InnerText: "Choose..."},
+		&browserk.HTMLElement{Type: browserk.OPTION, Attributes: map[string]string{"value": "us"}, InnerText: "USA"},
+		&browserk.HTMLElement{Type: browserk.OPTION, Attributes: map[string]string{"value": "jp", "disabled": ""}, InnerText: "Japan"},
+		&browserk.HTMLElement{Type: browserk.OPTION, InnerText: " Canada "},
+		shipping,
+		express,
+		mock.MakeMockInput("checkbox", "gift", ""),
+	)
+
+	variants := formHandler.Variants(form, 3)
+	if len(variants) != 3 {
+		t.Fatalf("expected limit of 3 variants got %d\n", len(variants))
+	}
+
+	entry := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction("http://example.com/"))
+	ids := make(map[string]string, 0)
+	for _, variant := range variants {
+		nav := browserk.NewNavigationFromForm(entry, browserk.TrigCrawler, variant)
+		if other, exist := ids[string(nav.ID)]; exist {
+			t.Fatalf("variant %s collided with %s\n", variant.Variant, other)
+		}
+		ids[string(nav.ID)] = variant.Variant
+	}
+
+	expected := []string{
+		"country=us&shipping=standard&gift=on",
+		"country=Canada&shipping=standard&gift=on",
+		"country=us&shipping=express&gift=on",
+	}
+	for i, variant := range variants {
+		if variant.Variant != expected[i] {
+			t.Fatalf("expected variant %s got %s\n", expected[i], variant.Variant)
+		}
+	}
+
+	if variants[2].ChildElements[len(form.ChildElements)-3].Checked || !variants[2].ChildElements[len(form.ChildElements)-2].Checked {
+		t.Fatalf("expected express shipping to be checked\n")
+	}
+
+	if form.ChildElements[len(form.ChildElements)-2].Checked {
+		t.Fatalf("expected variants to not modify the original form\n")
+	}
+
+	if variants := formHandler.Variants(mock.MakeMockAddressForm(), 3); len(variants) != 1 || variants[0].Variant != "" {
+		t.Fatalf("expected a form without choices to be filled once\n")
+	}
+}
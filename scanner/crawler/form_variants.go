@@ -0,0 +1,148 @@
+package crawler
+
+import (
+	"strconv"
+	"strings"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// choiceGroup is a select, radio group or checkbox of a form and the values it can be set to
+type choiceGroup struct {
+	name     string
+	elements []int // index of the form's child elements in this group
+	values   []string
+}
+
+// apply the choice'th value of this group to the form's child elements
+func (g *choiceGroup) apply(form *browserk.HTMLFormElement, choice int) {
+	for i, idx := range g.elements {
+		ele := form.ChildElements[idx]
+		switch {
+		case ele.Type == browserk.SELECT:
+			ele.Value = g.values[choice]
+		case ele.GetAttribute("type") == "radio":
+			ele.Checked = i == choice
+		case ele.GetAttribute("type") == "checkbox":
+			ele.Checked = choice == 0
+		}
+	}
+}
+
+// isFormControl returns true for elements that end a select's list of options
+func isFormControl(ele *browserk.HTMLElement) bool {
+	switch ele.Type {
+	case browserk.SELECT, browserk.INPUT, browserk.TEXTAREA, browserk.BUTTON, browserk.LABEL, browserk.DATALIST:
+		return true
+	}
+	return false
+}
+
+// groupName for a select/input, falling back to its position in the form
+func groupName(ele *browserk.HTMLElement, idx int) string {
+	if name := ele.GetAttribute("name"); name != "" {
+		return name
+	}
+
+	if id := ele.GetAttribute("id"); id != "" {
+		return id
+	}
+	return strconv.Itoa(idx)
+}
+
+// findChoiceGroups of the form, form children are flattened depth first so a select's options directly follow it
+func findChoiceGroups(form *browserk.HTMLFormElement) []*choiceGroup {
+	groups := make([]*choiceGroup, 0)
+	radios := make(map[string]*choiceGroup, 0)
+
+	for i, ele := range form.ChildElements {
+		if _, disabled := ele.Attributes["disabled"]; disabled || ele.Hidden {
+			continue
+		}
+
+		switch {
+		case ele.Type == browserk.SELECT:
+			group := &choiceGroup{name: groupName(ele, i), elements: []int{i}}
+			for _, option := range form.ChildElements[i+1:] {
+				if isFormControl(option) {
+					break
+				}
+
+				if _, disabled := option.Attributes["disabled"]; disabled || option.Type != browserk.OPTION {
+					continue
+				}
+
+				value, exist := option.Attributes["value"]
+				if !exist {
+					value = strings.TrimSpace(option.InnerText)
+				}
+
+				// most likely a 'please choose' placeholder
+				if value == "" {
+					continue
+				}
+				group.values = append(group.values, value)
+			}
+
+			if len(group.values) > 0 {
+				groups = append(groups, group)
+			}
+		case ele.Type == browserk.INPUT && ele.GetAttribute("type") == "radio":
+			name := groupName(ele, i)
+			group, exist := radios[name]
+			if !exist {
+				group = &choiceGroup{name: name}
+				radios[name] = group
+				groups = append(groups, group)
+			}
+
+			value := ele.GetAttribute("value")
+			if value == "" {
+				value = "on"
+			}
+			group.elements = append(group.elements, i)
+			group.values = append(group.values, value)
+		case ele.Type == browserk.INPUT && ele.GetAttribute("type") == "checkbox":
+			groups = append(groups, &choiceGroup{name: groupName(ele, i), elements: []int{i}, values: []string{"on", "off"}})
+		}
+	}
+	return groups
+}
+
+// Variants fills the form and returns copies of it choosing different select options, radios and checkboxes, up to
+// limit. The first variant takes the first choice of every group, each following variant changes the choice of a
+// single group so forms that branch on one input are covered without trying every combination.
+func (c *CrawlerFormHandler) Variants(form *browserk.HTMLFormElement, limit int) []*browserk.HTMLFormElement {
+	c.Fill(form)
+
+	groups := findChoiceGroups(form)
+	if len(groups) == 0 || limit <= 0 {
+		return []*browserk.HTMLFormElement{form}
+	}
+
+	choices := make([][]int, 0)
+	choices = append(choices, make([]int, len(groups)))
+	for i, group := range groups {
+		for choice := 1; choice < len(group.values); choice++ {
+			if len(choices) == limit {
+				break
+			}
+			variant := make([]int, len(groups))
+			variant[i] = choice
+			choices = append(choices, variant)
+		}
+	}
+
+	variants := make([]*browserk.HTMLFormElement, 0, len(choices))
+	for _, choice := range choices {
+		variant := form.Copy()
+		parts := make([]string, len(groups))
+		for i, group := range groups {
+			group.apply(variant, choice[i])
+			parts[i] = group.name + "=" + group.values[choice[i]]
+		}
+		variant.Variant = strings.Join(parts, "&")
+		variants = append(variants, variant)
+	}
+	return variants
+}
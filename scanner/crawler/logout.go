@@ -0,0 +1,102 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+
+	"gitlab.com/browserker/browserk"
+)
+
+var (
+	// logout, log out, log-off, signout, sign_out etc
+	logoutTextRe = regexp.MustCompile(`(?i)\b(log|sign)[\s_-]?(out|off)\b|\bend[\s_-]?session\b`)
+	logoutHrefRe = regexp.MustCompile(`(?i)(log|sign)[_-]?(out|off)|end[_-]?session`)
+	// cookie names commonly used to store a session id
+	sessionCookieRe = regexp.MustCompile(`(?i)sess|sid|auth|token|jwt`)
+)
+
+// logoutAttributes are checked for logout text in addition to the inner text
+var logoutAttributes = []string{"value", "title", "aria-label", "id", "name"}
+
+// LogoutElement returns why the element looks like it would end our session, or an empty string
+func LogoutElement(ele *browserk.HTMLElement) string {
+	if logoutTextRe.MatchString(ele.InnerText) {
+		return "logout text: " + ele.InnerText
+	}
+
+	for _, attr := range []string{"href", "formaction"} {
+		if value, ok := ele.Attributes[attr]; ok && logoutHrefRe.MatchString(value) {
+			return "logout " + attr + ": " + value
+		}
+	}
+
+	for _, attr := range logoutAttributes {
+		if value, ok := ele.Attributes[attr]; ok && logoutTextRe.MatchString(value) {
+			return "logout " + attr + ": " + value
+		}
+	}
+	return ""
+}
+
+// LogoutForm returns why the form looks like it would end our session, or an empty string
+func LogoutForm(form *browserk.HTMLFormElement) string {
+	if action := form.GetAttribute("action"); logoutHrefRe.MatchString(action) {
+		return "logout form action: " + action
+	}
+
+	for _, attr := range logoutAttributes {
+		if value := form.GetAttribute(attr); logoutTextRe.MatchString(value) {
+			return "logout form " + attr + ": " + value
+		}
+	}
+
+	// forms that only consist of a logout button
+	for _, child := range form.ChildElements {
+		if child.Type != browserk.BUTTON && child.Type != browserk.INPUT {
+			continue
+		}
+		if reason := LogoutElement(child); reason != "" {
+			return "logout form " + reason
+		}
+	}
+	return ""
+}
+
+// LogoutURL returns why loading the url looks like it would end our session, or an empty string. The fragment
+// is checked as well for hash router routes (#/logout).
+func LogoutURL(u *url.URL) string {
+	for _, part := range []string{u.Path, u.Fragment} {
+		if logoutHrefRe.MatchString(part) {
+			return "logout url: " + part
+		}
+	}
+	return ""
+}
+
+// SessionClosed returns true if a session cookie that existed prior to the action was removed or
+// cleared. If sessionCookie is empty, any cookie with a session like name is checked.
+func SessionClosed(sessionCookie string, before, after []*browserk.Cookie) bool {
+	remaining := make(map[string]string, len(after))
+	for _, cookie := range after {
+		remaining[cookie.Name] = cookie.Value
+	}
+
+	for _, cookie := range before {
+		if cookie.Value == "" {
+			continue
+		}
+
+		if sessionCookie != "" && cookie.Name != sessionCookie {
+			continue
+		}
+
+		if sessionCookie == "" && !sessionCookieRe.MatchString(cookie.Name) {
+			continue
+		}
+
+		if value, ok := remaining[cookie.Name]; !ok || value == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+package crawler_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/mock"
+	"gitlab.com/browserker/scanner"
+	"gitlab.com/browserker/scanner/crawler"
+)
+
+func TestLogoutElement(t *testing.T) {
+	var tests = []struct {
+		ele    *browserk.HTMLElement
+		logout bool
+	}{
+		{&browserk.HTMLElement{Type: browserk.A, Attributes: map[string]string{"href": "/logout.php"}}, true},
+		{&browserk.HTMLElement{Type: browserk.A, Attributes: map[string]string{"href": "/users/sign_out"}}, true},
+		{&browserk.HTMLElement{Type: browserk.A, Attributes: map[string]string{"href": "/WebGoat/login?logout"}}, true},
+		{&browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Log Out"}, true},
+		{&browserk.HTMLElement{Type: browserk.BUTTON, Attributes: map[string]string{"aria-label": "Sign off"}}, true},
+		{&browserk.HTMLElement{Type: browserk.A, Attributes: map[string]string{"href": "/login.php"}, InnerText: "Login"}, false},
+		{&browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Checkout"}, false},
+		{&browserk.HTMLElement{Type: browserk.A, Attributes: map[string]string{"href": "/blog/outdoors"}}, false},
+	}
+
+	for _, tt := range tests {
+		if reason := crawler.LogoutElement(tt.ele); (reason != "") != tt.logout {
+			t.Fatalf("expected logout %v for %#v got %s\n", tt.logout, tt.ele, reason)
+		}
+	}
+}
+
+func TestLogoutForm(t *testing.T) {
+	form := &browserk.HTMLFormElement{Attributes: map[string]string{"action": "/account/logout", "method": "post"}}
+	if crawler.LogoutForm(form) == "" {
+		t.Fatalf("expected form posting to logout to be detected\n")
+	}
+
+	form = &browserk.HTMLFormElement{
+		Attributes:    map[string]string{"action": "/session", "method": "post"},
+		ChildElements: []*browserk.HTMLElement{{Type: browserk.BUTTON, InnerText: "Sign out"}},
+	}
+	if crawler.LogoutForm(form) == "" {
+		t.Fatalf("expected form with sign out button to be detected\n")
+	}
+
+	form = &browserk.HTMLFormElement{
+		Attributes:    map[string]string{"action": "/search"},
+		ChildElements: []*browserk.HTMLElement{{Type: browserk.INPUT, Attributes: map[string]string{"name": "q"}}},
+	}
+	if reason := crawler.LogoutForm(form); reason != "" {
+		t.Fatalf("expected search form to not be detected got %s\n", reason)
+	}
+}
+
+func TestSessionClosed(t *testing.T) {
+	before := []*browserk.Cookie{{Name: "PHPSESSID", Value: "abc"}, {Name: "lang", Value: "en"}}
+
+	if !crawler.SessionClosed("", before, []*browserk.Cookie{{Name: "lang", Value: "en"}}) {
+		t.Fatalf("expected removed session cookie to be detected\n")
+	}
+
+	if !crawler.SessionClosed("PHPSESSID", before, []*browserk.Cookie{{Name: "PHPSESSID", Value: ""}}) {
+		t.Fatalf("expected cleared session cookie to be detected\n")
+	}
+
+	if crawler.SessionClosed("", before, []*browserk.Cookie{{Name: "PHPSESSID", Value: "def"}}) {
+		t.Fatalf("did not expect a regenerated session cookie to be detected\n")
+	}
+
+	if crawler.SessionClosed("", before, []*browserk.Cookie{{Name: "PHPSESSID", Value: "abc"}}) {
+		t.Fatalf("did not expect removing a non session cookie to be detected\n")
+	}
+}
+
+func TestFindNewNavExcluded(t *testing.T) {
+	target, _ := url.Parse("http://example.com")
+	scope := scanner.NewScopeService(target)
+	scope.ExcludeForms([]string{"reset"})
+	scope.ExcludeElements([]*browserk.ElementRule{{Name: "delete account", Selector: "#delete"}})
+
+	bctx := mock.MakeMockContext(context.Background(), target)
+	bctx.Scope = scope
+	bctx.FormHandler = crawler.NewCrawlerFormHandler(&browserk.DefaultFormValues)
+	if err := bctx.FormHandler.Init(); err != nil {
+		t.Fatalf("error init form handler: %s\n", err)
+	}
+
+	deleteButton := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Delete", Attributes: map[string]string{"id": "delete"}}
+	saveButton := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Save"}
+	logoutLink := &browserk.HTMLElement{Type: browserk.A, InnerText: "Sign out", Attributes: map[string]string{"href": "/session/end"}}
+	resetForm := &browserk.HTMLFormElement{Type: browserk.FORM, Attributes: map[string]string{"id": "reset", "action": "/reset"}}
+
+	browser := mock.MakeMockBrowser()
+	browser.FindFormsFn = func(ctx context.Context) ([]*browserk.HTMLFormElement, error) {
+		return []*browserk.HTMLFormElement{resetForm}, nil
+	}
+	browser.FindElementsFn = func(ctx context.Context, querySelector string, canRefreshDoc bool) ([]*browserk.HTMLElement, error) {
+		switch querySelector {
+		case "#delete":
+			return []*browserk.HTMLElement{deleteButton}, nil
+		case "button":
+			return []*browserk.HTMLElement{deleteButton, saveButton}, nil
+		case "a":
+			return []*browserk.HTMLElement{logoutLink}, nil
+		}
+		return nil, nil
+	}
+
+	entry := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(target.String()))
+	navs := crawler.New(&browserk.Config{}).FindNewNav(bctx, crawler.NewElementDiffer(), entry, browser)
+	if len(navs) != 4 {
+		t.Fatalf("expected excluded navigations to be returned for the crawl graph got %d\n", len(navs))
+	}
+
+	expected := map[string]string{
+		string(resetForm.Hash()):    "ExcludedForms: reset",
+		string(deleteButton.Hash()): "ExcludedElements: delete account",
+		string(saveButton.Hash()):   "",
+		string(logoutLink.Hash()):   "logout text: Sign out",
+	}
+
+	for _, nav := range navs {
+		var hash string
+		if nav.Action.Form != nil {
+			hash = string(nav.Action.Form.Hash())
+		} else {
+			hash = string(nav.Action.Element.Hash())
+		}
+
+		reason := expected[hash]
+		if nav.ExcludedReason != reason || (reason != "") != (nav.State == browserk.NavExcluded) {
+			t.Fatalf("expected %s to be excluded with %q got %q (state %d)\n", nav, reason, nav.ExcludedReason, nav.State)
+		}
+	}
+}
+
+func TestFindNewNavExcludedHostedForm(t *testing.T) {
+	target, _ := url.Parse("http://example.com")
+	scope := scanner.NewScopeService(target)
+	scope.ExcludeElements([]*browserk.ElementRule{{Name: "delete account", Selector: "#delete"}})
+
+	bctx := mock.MakeMockContext(context.Background(), target)
+	bctx.Scope = scope
+	bctx.FormHandler = crawler.NewCrawlerFormHandler(&browserk.DefaultFormValues)
+	if err := bctx.FormHandler.Init(); err != nil {
+		t.Fatalf("error init form handler: %s\n", err)
+	}
+
+	hosts := []*browserk.ElementHost{{Selector: "account-settings"}}
+	attributes := map[string]string{"id": "delete", "action": "/account/delete"}
+	deleteForm := &browserk.HTMLFormElement{Type: browserk.FORM, Attributes: attributes, Hosts: hosts}
+
+	browser := mock.MakeMockBrowser()
+	browser.FindFormsFn = func(ctx context.Context) ([]*browserk.HTMLFormElement, error) {
+		return []*browserk.HTMLFormElement{deleteForm}, nil
+	}
+	browser.FindElementsFn = func(ctx context.Context, querySelector string, canRefreshDoc bool) ([]*browserk.HTMLElement, error) {
+		if querySelector == "#delete" {
+			return []*browserk.HTMLElement{{Type: browserk.FORM, InnerText: "Delete account", Attributes: attributes, Hosts: hosts}}, nil
+		}
+		return nil, nil
+	}
+
+	entry := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(target.String()))
+	navs := crawler.New(&browserk.Config{}).FindNewNav(bctx, crawler.NewElementDiffer(), entry, browser)
+	if len(navs) != 1 {
+		t.Fatalf("expected the form navigation got %d\n", len(navs))
+	}
+
+	if navs[0].State != browserk.NavExcluded || navs[0].ExcludedReason != "ExcludedElements: delete account" {
+		t.Fatalf("expected form in shadow root to be excluded got %q (state %d)\n", navs[0].ExcludedReason, navs[0].State)
+	}
+}
+
+func TestProcessSessionClosed(t *testing.T) {
+	target, _ := url.Parse("http://example.com")
+
+	var tests = []struct {
+		name     string
+		cfg      *browserk.Config
+		auth     browserk.AuthService
+		expected bool
+	}{
+		{"unauthenticated", &browserk.Config{}, nil, false},
+		{"logged in", &browserk.Config{}, mock.MakeMockAuthService(&browserk.Role{Name: "user", Credentials: &browserk.Credentials{}}, nil), true},
+		{"session cookie", &browserk.Config{AuthIndicators: &browserk.AuthIndicators{SessionCookie: "sid"}}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bctx := mock.MakeMockContext(context.Background(), target)
+			bctx.Auth = tt.auth
+
+			browser := mock.MakeMockBrowser()
+			executed := false
+			browser.ExecuteActionFn = func(ctx context.Context, nav *browserk.Navigation) ([]byte, bool, error) {
+				executed = true
+				return nil, false, nil
+			}
+			// the action rotates the session cookie
+			browser.GetCookiesFn = func() ([]*browserk.Cookie, error) {
+				if !executed {
+					return []*browserk.Cookie{{Name: "sid", Value: "abc"}}, nil
+				}
+				return nil, nil
+			}
+
+			entry := browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction("http://example.com/"))
+			result, _, err := crawler.New(tt.cfg).Process(bctx, browser, entry, false)
+			if err != nil {
+				t.Fatalf("error processing: %s\n", err)
+			}
+
+			if result.SessionClosed != tt.expected {
+				t.Fatalf("expected SessionClosed %v got %v\n", tt.expected, result.SessionClosed)
+			}
+		})
+	}
+}
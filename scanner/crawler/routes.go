@@ -0,0 +1,101 @@
+package crawler
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// RouteBinding is the binding the route script reports route changes to
+const RouteBinding = "__browserkRoute"
+
+// routeScript wraps the history api and listens for popstate/hashchange so client side routers (vue, react,
+// angular) report every route they change to, even if no anchor links to it
+const routeScript = `(function() {
+	if (window.__browserkRouteHooked) {
+		return;
+	}
+	window.__browserkRouteHooked = true;
+
+	var report = function(type) {
+		try {
+			window.__browserkRoute(JSON.stringify({type: type, url: window.location.href}));
+		} catch (e) {}
+	};
+
+	['pushState', 'replaceState'].forEach(function(name) {
+		var original = window.history[name];
+		window.history[name] = function() {
+			var result = original.apply(this, arguments);
+			report(name);
+			return result;
+		};
+	});
+	window.addEventListener('popstate', function() { report('popstate'); });
+	window.addEventListener('hashchange', function() { report('hashchange'); });
+})();`
+
+// hookRoutes installs the route script, route changes are kept until the navigation that caused them is
+// processed so they go through the same exclusion and safety checks as every other navigation we find
+func (b *BrowserkCrawler) hookRoutes(bctx *browserk.Context, browser browserk.Browser) {
+	if err := browser.AddBinding(bctx.Ctx, RouteBinding, routeScript, b.RouteHandler(bctx)); err != nil {
+		bctx.Log.Warn().Err(err).Msg("failed to hook spa routes")
+	}
+}
+
+// RouteHandler for route change payloads of the route script, each in scope route change is dispatched as an
+// EvtURL plugin event and recorded for the crawler
+func (b *BrowserkCrawler) RouteHandler(c *browserk.Context) browserk.BindingHandler {
+	return func(nav *browserk.Navigation, payload string) {
+		route := &browserk.RouteEvent{}
+		if err := json.Unmarshal([]byte(payload), route); err != nil || route.URL == "" {
+			return
+		}
+		route.Observed = time.Now()
+
+		if c.Scope.CheckURL(route.URL) != browserk.InScope {
+			return
+		}
+		c.PluginServicer.DispatchEvent(browserk.URLPluginEvent(c, route.URL, nav, route))
+
+		c.Log.Debug().Str("type", route.Type).Str("url", route.URL).Msg("route changed")
+		b.routeLock.Lock()
+		b.routes = append(b.routes, route.URL)
+		b.routeLock.Unlock()
+	}
+}
+
+// takeRoutes recorded since the last call
+func (b *BrowserkCrawler) takeRoutes() []string {
+	b.routeLock.Lock()
+	defer b.routeLock.Unlock()
+	routes := b.routes
+	b.routes = nil
+	return routes
+}
+
+// findURLNavs creates load url navigations for the in scope urls the browser went to on its own after the entry's
+// action, the routes it changed to and windows it opened
+func (b *BrowserkCrawler) findURLNavs(bctx *browserk.Context, entry *browserk.Navigation, urls []string) []*browserk.Navigation {
+	navs := make([]*browserk.Navigation, 0)
+	added := make(map[string]struct{})
+	for _, rawURL := range urls {
+		u, err := url.Parse(rawURL)
+		if err != nil || bctx.Scope.Check(u) != browserk.InScope {
+			continue
+		}
+
+		nav := browserk.NewNavigationFromBrowser(entry, browserk.TrigAutoBrowser, browserk.NewLoadURLAction(rawURL))
+		nav.Scope = browserk.InScope
+		if _, exist := added[string(nav.ID)]; exist {
+			continue
+		}
+		added[string(nav.ID)] = struct{}{}
+
+		b.excludeLogout(bctx, nav, LogoutURL(u))
+		navs = append(navs, nav)
+	}
+	return navs
+}
@@ -0,0 +1,119 @@
+package crawler_test
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"testing"
+
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/mock"
+	"gitlab.com/browserker/scanner"
+	"gitlab.com/browserker/scanner/crawler"
+)
+
+func TestRouteHandler(t *testing.T) {
+	target, _ := url.Parse("http://example.com")
+	bctx := mock.MakeMockContext(context.Background(), target)
+	bctx.Scope = scanner.NewScopeService(target)
+
+	events := make([]*browserk.PluginEvent, 0)
+	servicer := mock.MakeMockPluginServicer()
+	servicer.DispatchEventFn = func(evt *browserk.PluginEvent) {
+		events = append(events, evt)
+	}
+	bctx.PluginServicer = servicer
+
+	entry := browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction("http://example.com/"))
+	browser := mock.MakeMockBrowser()
+	var handler browserk.BindingHandler
+	browser.AddBindingFn = func(ctx context.Context, name, script string, bindingHandler browserk.BindingHandler) error {
+		if name == crawler.RouteBinding && handler == nil {
+			handler = bindingHandler
+		}
+		return nil
+	}
+	browser.ExecuteActionFn = func(ctx context.Context, nav *browserk.Navigation) ([]byte, bool, error) {
+		handler(nav, `{"type":"pushState","url":"http://example.com/#/users/12"}`)
+		handler(nav, `{"type":"hashchange","url":"http://example.com/#/settings"}`)
+		handler(nav, `{"type":"hashchange","url":"http://example.com/#/logout"}`)
+		handler(nav, `{"type":"pushState","url":"http://other.com/dashboard"}`)
+		handler(nav, `not json`)
+		return nil, false, nil
+	}
+
+	c := crawler.New(&browserk.Config{})
+	_, navs, err := c.Process(bctx, browser, entry, true)
+	if err != nil {
+		t.Fatalf("error processing: %s\n", err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 in scope url events got %d\n", len(events))
+	}
+
+	if events[0].Type != browserk.EvtURL || events[0].Route().Type != "pushState" || !bytes.Equal(events[0].Nav.ID, entry.ID) {
+		t.Fatalf("expected url event for the route change got %#v\n", events[0])
+	}
+
+	routes := make(map[string]*browserk.Navigation)
+	for _, nav := range navs {
+		if nav.TriggeredBy == browserk.TrigAutoBrowser {
+			routes[string(nav.Action.Input)] = nav
+		}
+	}
+
+	if len(routes) != 3 {
+		t.Fatalf("expected hash routes to be separate navigations got %d\n", len(routes))
+	}
+
+	for _, route := range []string{"http://example.com/#/users/12", "http://example.com/#/settings"} {
+		if nav, ok := routes[route]; !ok || nav.State != browserk.NavUnvisited {
+			t.Fatalf("expected load url navigation for %s\n", route)
+		}
+	}
+
+	if logout := routes["http://example.com/#/logout"]; logout == nil || logout.State != browserk.NavExcluded {
+		t.Fatalf("expected logout route to be excluded\n")
+	}
+}
+
+func TestProcessOpenedWindows(t *testing.T) {
+	target, _ := url.Parse("http://example.com")
+	bctx := mock.MakeMockContext(context.Background(), target)
+	bctx.Scope = scanner.NewScopeService(target)
+
+	browser := mock.MakeMockBrowser()
+	executed := false
+	browser.ExecuteActionFn = func(ctx context.Context, nav *browserk.Navigation) ([]byte, bool, error) {
+		executed = true
+		return nil, false, nil
+	}
+	browser.GetOpenedURLsFn = func() []string {
+		if !executed {
+			return []string{"http://example.com/before"}
+		}
+		return []string{"http://example.com/popup", "http://other.com/ad", "http://example.com/signout", "http://example.com/popup"}
+	}
+
+	entry := browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction("http://example.com/"))
+	_, navs, err := crawler.New(&browserk.Config{}).Process(bctx, browser, entry, true)
+	if err != nil {
+		t.Fatalf("error processing: %s\n", err)
+	}
+
+	opened := make(map[string]*browserk.Navigation)
+	for _, nav := range navs {
+		if nav.TriggeredBy == browserk.TrigAutoBrowser {
+			opened[string(nav.Action.Input)] = nav
+		}
+	}
+
+	if len(opened) != 2 || opened["http://example.com/popup"] == nil {
+		t.Fatalf("expected in scope windows opened by the action got %#v\n", opened)
+	}
+
+	if signout := opened["http://example.com/signout"]; signout == nil || signout.State != browserk.NavExcluded {
+		t.Fatalf("expected logout window to be excluded\n")
+	}
+}
@@ -0,0 +1,251 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gitlab.com/browserker/browserk"
+)
+
+var (
+	deletionTextRe = regexp.MustCompile(`(?i)\b(delete|remove|destroy|purge|erase|wipe|drop|truncate)\b|\breset (the )?(database|db|data)\b`)
+	paymentTextRe  = regexp.MustCompile(`(?i)\b(pay|payment|purchase|checkout|check out|buy|place order|order now|donate|transfer|withdraw|refund)\b`)
+	accountTextRe  = regexp.MustCompile(`(?i)\b(change|update|reset|set) (your |my )?(password|email|e-mail|username|phone)\b|\b(deactivate|disable|close|cancel) (your |my )?(account|subscription|profile)\b|\bunsubscribe\b`)
+	// url paths aren't split on \b friendly boundaries (user_delete, /remove-item)
+	deletionURLRe = regexp.MustCompile(`(?i)(^|[^a-z])(delete|remove|destroy|purge|wipe)([^a-z]|$)`)
+	paymentURLRe  = regexp.MustCompile(`(?i)(^|[^a-z])(pay|payment|purchase|checkout|transfer|withdraw)([^a-z]|$)`)
+)
+
+// unsafeMethods change state on the server by definition
+var unsafeMethods = map[string]struct{}{"DELETE": {}, "PUT": {}, "PATCH": {}}
+
+// safetyTextAttributes are checked for destructive text in addition to the inner text
+var safetyTextAttributes = []string{"value", "title", "aria-label", "id", "name"}
+
+// Safety classifies navigations and attack requests that look destructive and decides, depending
+// on the configured level, if they are blocked or restricted to passive plugins
+type Safety struct {
+	level      browserk.SafetyLevel
+	adminPaths []*regexp.Regexp
+	allowlist  []*regexp.Regexp
+}
+
+// NewSafety from the config, a nil config disables the safe mode
+func NewSafety(cfg *browserk.Safety) (*Safety, error) {
+	s := &Safety{level: browserk.SafetyOff}
+	if cfg == nil {
+		return s, nil
+	}
+	s.level = cfg.Level
+
+	adminPaths := cfg.AdminPaths
+	if adminPaths == nil {
+		adminPaths = browserk.DefaultAdminPaths
+	}
+
+	var err error
+	if s.adminPaths, err = compileAll(adminPaths); err != nil {
+		return nil, err
+	}
+
+	if s.allowlist, err = compileAll(cfg.Allowlist); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func compileAll(exprs []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Level of the safe mode
+func (s *Safety) Level() browserk.SafetyLevel {
+	return s.level
+}
+
+// CheckNavigation classifies the navigation and blocks it unless the level allows it. At the passive level the
+// navigation is recorded but never performed, so neither the crawl nor the attacks trigger the destructive action.
+// Returns the reason it was classified as destructive, or an empty string.
+func (s *Safety) CheckNavigation(nav *browserk.Navigation) string {
+	if s.level == browserk.SafetyOff || nav.State == browserk.NavExcluded {
+		return ""
+	}
+
+	reason, subjects := s.classifyNavigation(nav)
+	if reason == "" || s.allowed(subjects) {
+		return ""
+	}
+
+	if s.level == browserk.SafetyPassive {
+		nav.BlockedReason = reason
+		nav.Exclude("safe mode (passive): " + reason)
+		return reason
+	}
+	nav.Block(reason)
+	return reason
+}
+
+// CheckRequest classifies an attack request, returning why it must not be sent or an empty string. Attacks
+// are active by nature so destructive requests are not sent at any level unless allowlisted.
+func (s *Safety) CheckRequest(method, requestURL string) string {
+	if s.level == browserk.SafetyOff {
+		return ""
+	}
+
+	reason := s.classifyRequest(method, requestURL)
+	if reason == "" || s.allowed([]string{requestURL}) {
+		return ""
+	}
+	return reason
+}
+
+// allowed returns true if we are running with SafetyAllowlist and the url or text matched the allowlist
+func (s *Safety) allowed(subjects []string) bool {
+	if s.level != browserk.SafetyAllowlist {
+		return false
+	}
+
+	for _, re := range s.allowlist {
+		for _, subject := range subjects {
+			if subject != "" && re.MatchString(subject) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// classifyNavigation returns why the navigation looks destructive along with the urls and text that
+// were classified so they can be checked against the allowlist
+func (s *Safety) classifyNavigation(nav *browserk.Navigation) (string, []string) {
+	if nav.Action == nil {
+		return "", nil
+	}
+
+	var methods, urls, text []string
+	switch {
+	case nav.Action.Form != nil:
+		form := nav.Action.Form
+		methods = append(methods, form.GetAttribute("method"))
+		urls = append(urls, form.GetAttribute("action"))
+		text = append(text, attributeText(form.Attributes)...)
+		for _, child := range form.ChildElements {
+			// frameworks emulate DELETE/PUT/PATCH forms with a hidden _method input
+			if child.GetAttribute("name") == "_method" {
+				methods = append(methods, child.GetAttribute("value"))
+			}
+
+			if child.Type == browserk.BUTTON || child.GetAttribute("type") == "submit" {
+				methods = append(methods, child.GetAttribute("formmethod"))
+				urls = append(urls, child.GetAttribute("formaction"))
+				text = append(text, elementText(child)...)
+			}
+		}
+	case nav.Action.Element != nil:
+		ele := nav.Action.Element
+		// rails style data-method links
+		methods = append(methods, ele.GetAttribute("data-method"), ele.GetAttribute("formmethod"))
+		urls = append(urls, ele.GetAttribute("href"), ele.GetAttribute("formaction"))
+		text = append(text, elementText(ele)...)
+	case nav.Action.Type == browserk.ActLoadURL:
+		urls = append(urls, string(nav.Action.Input))
+	}
+
+	subjects := append(urls, text...)
+	for _, method := range methods {
+		if reason := unsafeMethod(method); reason != "" {
+			return reason, subjects
+		}
+	}
+
+	for _, value := range text {
+		if reason := destructiveText(value); reason != "" {
+			return reason, subjects
+		}
+	}
+
+	for _, u := range urls {
+		if reason := s.classifyURL(u); reason != "" {
+			return reason, subjects
+		}
+	}
+	return "", subjects
+}
+
+// classifyRequest returns why the request looks destructive, or an empty string
+func (s *Safety) classifyRequest(method, requestURL string) string {
+	if reason := unsafeMethod(method); reason != "" {
+		return reason
+	}
+	return s.classifyURL(requestURL)
+}
+
+// classifyURL checks the path for admin endpoints and destructive actions
+func (s *Safety) classifyURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	for _, re := range s.adminPaths {
+		if re.MatchString(u.Path) {
+			return "admin endpoint: " + u.Path
+		}
+	}
+
+	if deletionURLRe.MatchString(u.Path) {
+		return "deletion url: " + u.Path
+	}
+
+	if paymentURLRe.MatchString(u.Path) {
+		return "payment url: " + u.Path
+	}
+	return ""
+}
+
+func unsafeMethod(method string) string {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if _, ok := unsafeMethods[method]; ok {
+		return "method: " + method
+	}
+	return ""
+}
+
+func destructiveText(text string) string {
+	switch {
+	case deletionTextRe.MatchString(text):
+		return "deletion: " + text
+	case paymentTextRe.MatchString(text):
+		return "payment: " + text
+	case accountTextRe.MatchString(text):
+		return "account change: " + text
+	}
+	return ""
+}
+
+func elementText(ele *browserk.HTMLElement) []string {
+	return append([]string{ele.InnerText}, attributeText(ele.Attributes)...)
+}
+
+func attributeText(attributes map[string]string) []string {
+	text := make([]string, 0, len(safetyTextAttributes))
+	for _, attr := range safetyTextAttributes {
+		if value, ok := attributes[attr]; ok {
+			text = append(text, value)
+		}
+	}
+	return text
+}
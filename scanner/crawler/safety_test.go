@@ -0,0 +1,110 @@
+package crawler_test
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/scanner/crawler"
+)
+
+func TestSafetyNavigation(t *testing.T) {
+	entry := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction("http://example.com/"))
+	click := func(ele *browserk.HTMLElement) *browserk.Navigation {
+		return browserk.NewNavigationFromElement(entry, browserk.TrigCrawler, ele, browserk.ActLeftClick)
+	}
+	submit := func(form *browserk.HTMLFormElement) *browserk.Navigation {
+		return browserk.NewNavigationFromForm(entry, browserk.TrigCrawler, form)
+	}
+
+	var inputs = []struct {
+		name   string
+		nav    *browserk.Navigation
+		reason string
+	}{
+		{"profile link", click(&browserk.HTMLElement{Type: browserk.A, InnerText: "Profile", Attributes: map[string]string{"href": "/profile"}}), ""},
+		{"delete button", click(&browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Delete post"}), "deletion: "},
+		{"rails delete link", click(&browserk.HTMLElement{Type: browserk.A, InnerText: "Archive", Attributes: map[string]string{"href": "/posts/1", "data-method": "delete"}}), "method: DELETE"},
+		{"checkout url", click(&browserk.HTMLElement{Type: browserk.A, InnerText: "Continue", Attributes: map[string]string{"href": "/cart/checkout"}}), "payment url: "},
+		{"admin url", browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction("http://example.com/admin/users")), "admin endpoint: "},
+		{"password form", submit(&browserk.HTMLFormElement{Type: browserk.FORM, Attributes: map[string]string{"action": "/settings"}, ChildElements: []*browserk.HTMLElement{
+			{Type: browserk.INPUT, Attributes: map[string]string{"type": "password", "name": "password"}},
+			{Type: browserk.BUTTON, InnerText: "Change password"},
+		}}), "account change: "},
+		{"emulated put form", submit(&browserk.HTMLFormElement{Type: browserk.FORM, Attributes: map[string]string{"action": "/posts/1", "method": "post"}, ChildElements: []*browserk.HTMLElement{
+			{Type: browserk.INPUT, Attributes: map[string]string{"type": "hidden", "name": "_method", "value": "put"}},
+			{Type: browserk.INPUT, Attributes: map[string]string{"type": "submit", "value": "Save"}},
+		}}), "method: PUT"},
+	}
+
+	block, err := crawler.NewSafety(&browserk.Safety{Level: browserk.SafetyBlock})
+	if err != nil {
+		t.Fatalf("error creating safety: %s\n", err)
+	}
+
+	for _, in := range inputs {
+		nav := in.nav.Copy()
+		reason := block.CheckNavigation(nav)
+		if !strings.HasPrefix(reason, in.reason) || (in.reason == "") != (reason == "") {
+			t.Fatalf("%s: expected reason %q got %q\n", in.name, in.reason, reason)
+		}
+
+		if reason != "" && (nav.State != browserk.NavExcluded || nav.BlockedReason != reason) {
+			t.Fatalf("%s: expected navigation to be blocked got state %d reason %q\n", in.name, nav.State, nav.BlockedReason)
+		}
+	}
+
+	passive, _ := crawler.NewSafety(&browserk.Safety{Level: browserk.SafetyPassive})
+	nav := inputs[1].nav.Copy()
+	if passive.CheckNavigation(nav) == "" || nav.State != browserk.NavExcluded || nav.BlockedReason == "" || !strings.HasPrefix(nav.ExcludedReason, "safe mode (passive): ") {
+		t.Fatalf("expected passive level to record but not perform the navigation got state %d reason %q\n", nav.State, nav.BlockedReason)
+	}
+
+	allowlist, _ := crawler.NewSafety(&browserk.Safety{Level: browserk.SafetyAllowlist, Allowlist: []string{"^Delete post$"}})
+	nav = inputs[1].nav.Copy()
+	if reason := allowlist.CheckNavigation(nav); reason != "" || nav.State != browserk.NavUnvisited {
+		t.Fatalf("expected allowlisted navigation to be allowed got %q\n", reason)
+	}
+
+	nav = inputs[3].nav.Copy()
+	if allowlist.CheckNavigation(nav) == "" || nav.State != browserk.NavExcluded {
+		t.Fatalf("expected navigation not in the allowlist to be blocked\n")
+	}
+
+	off, _ := crawler.NewSafety(nil)
+	nav = inputs[1].nav.Copy()
+	if reason := off.CheckNavigation(nav); reason != "" || nav.BlockedReason != "" {
+		t.Fatalf("expected safe mode to be off by default got %q\n", reason)
+	}
+}
+
+func TestSafetyRequest(t *testing.T) {
+	safety, err := crawler.NewSafety(&browserk.Safety{Level: browserk.SafetyPassive, AdminPaths: []string{"^/internal/"}})
+	if err != nil {
+		t.Fatalf("error creating safety: %s\n", err)
+	}
+
+	var inputs = []struct {
+		method string
+		url    string
+		unsafe bool
+	}{
+		{"GET", "http://example.com/search?q=1", false},
+		{"POST", "http://example.com/comments", false},
+		{"DELETE", "http://example.com/comments/1", true},
+		{"patch", "http://example.com/comments/1", true},
+		{"GET", "http://example.com/internal/stats", true},
+		{"GET", "http://example.com/admin/", false}, // custom AdminPaths replace the defaults
+		{"POST", "http://example.com/user_delete?id=1", true},
+	}
+
+	for _, in := range inputs {
+		if reason := safety.CheckRequest(in.method, in.url); (reason != "") != in.unsafe {
+			t.Fatalf("%s %s: expected unsafe %v got %q\n", in.method, in.url, in.unsafe, reason)
+		}
+	}
+
+	if _, err := crawler.NewSafety(&browserk.Safety{Level: browserk.SafetyBlock, Allowlist: []string{"("}}); err == nil {
+		t.Fatalf("expected invalid allowlist to fail\n")
+	}
+}
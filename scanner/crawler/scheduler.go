@@ -0,0 +1,206 @@
+package crawler
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// Novelty scores, higher scores are crawled first
+const (
+	formScore          = 3 // forms usually lead to new functionality
+	revealScore        = 2 // elements of a kind that revealed new elements before (menus, tabs)
+	unseenPatternScore = 3 // links/forms to a url pattern we haven't visited
+)
+
+// NewScheduler for the crawl strategy
+func NewScheduler(strategy browserk.CrawlStrategy) browserk.Scheduler {
+	switch strategy {
+	case browserk.DepthFirst:
+		return &DepthFirstScheduler{}
+	case browserk.NoveltyFirst:
+		return NewNoveltyScheduler()
+	}
+	return &BreadthFirstScheduler{}
+}
+
+// BreadthFirstScheduler crawls navigations with the lowest Distance first
+type BreadthFirstScheduler struct{}
+
+// Next navigations with the lowest distance
+func (s *BreadthFirstScheduler) Next(candidates []*browserk.Navigation, limit int) []*browserk.Navigation {
+	return pick(candidates, limit, func(a, b *browserk.Navigation) bool {
+		return a.Distance < b.Distance
+	})
+}
+
+// Visited is not used
+func (s *BreadthFirstScheduler) Visited(nav *browserk.Navigation, result *browserk.NavigationResult, found []*browserk.Navigation) {
+}
+
+// DepthFirstScheduler crawls navigations with the highest Distance first
+type DepthFirstScheduler struct{}
+
+// Next navigations with the highest distance
+func (s *DepthFirstScheduler) Next(candidates []*browserk.Navigation, limit int) []*browserk.Navigation {
+	return pick(candidates, limit, func(a, b *browserk.Navigation) bool {
+		return a.Distance > b.Distance
+	})
+}
+
+// Visited is not used
+func (s *DepthFirstScheduler) Visited(nav *browserk.Navigation, result *browserk.NavigationResult, found []*browserk.Navigation) {
+}
+
+// NoveltyScheduler scores navigations by how likely they are to find something new: forms, elements of the
+// same kind as elements that revealed new elements before and urls of patterns we haven't visited yet. Ties
+// are broken breadth first.
+type NoveltyScheduler struct {
+	lock     *sync.Mutex
+	revealed map[string]int      // element kind -> navigations found by acting on them without a page load
+	patterns map[string]struct{} // url patterns visited so far
+	learner  *browserk.URLPatternLearner
+}
+
+// NewNoveltyScheduler with nothing visited
+func NewNoveltyScheduler() *NoveltyScheduler {
+	return &NoveltyScheduler{
+		lock:     &sync.Mutex{},
+		revealed: make(map[string]int),
+		patterns: make(map[string]struct{}),
+		learner:  browserk.NewURLPatternLearner(),
+	}
+}
+
+// Next navigations with the highest novelty score
+func (s *NoveltyScheduler) Next(candidates []*browserk.Navigation, limit int) []*browserk.Navigation {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	scores := make(map[string]int, len(candidates))
+	for _, nav := range candidates {
+		scores[string(nav.ID)] = s.score(nav)
+	}
+
+	return pick(candidates, limit, func(a, b *browserk.Navigation) bool {
+		if scores[string(a.ID)] != scores[string(b.ID)] {
+			return scores[string(a.ID)] > scores[string(b.ID)]
+		}
+		return a.Distance < b.Distance
+	})
+}
+
+// Score of the navigation, exported for debugging/tuning
+func (s *NoveltyScheduler) Score(nav *browserk.Navigation) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.score(nav)
+}
+
+func (s *NoveltyScheduler) score(nav *browserk.Navigation) int {
+	if nav.Action == nil {
+		return 0
+	}
+
+	score := 0
+	if nav.Action.Form != nil {
+		score += formScore
+	}
+
+	if nav.Action.Element != nil && s.revealed[elementKind(nav.Action.Element)] > 0 {
+		score += revealScore
+	}
+
+	if pattern := s.navigationPattern(nav); pattern != "" {
+		if _, ok := s.patterns[pattern]; !ok {
+			score += unseenPatternScore
+		}
+	}
+	return score
+}
+
+// Visited records the patterns we have now seen and if acting on the element revealed new navigations
+func (s *NoveltyScheduler) Visited(nav *browserk.Navigation, result *browserk.NavigationResult, found []*browserk.Navigation) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if pattern := s.navigationPattern(nav); pattern != "" {
+		s.patterns[pattern] = struct{}{}
+	}
+
+	if result != nil {
+		if pattern := s.pathPattern(result.EndURL); pattern != "" {
+			s.patterns[pattern] = struct{}{}
+		}
+	}
+
+	// following a link always finds something, we only care about elements that change the current page
+	if result != nil && !result.CausedLoad && nav.Action != nil && nav.Action.Element != nil && len(found) > 0 {
+		s.revealed[elementKind(nav.Action.Element)] += len(found)
+	}
+}
+
+// pick the first limit navigations after sorting them
+func pick(candidates []*browserk.Navigation, limit int, less func(a, b *browserk.Navigation) bool) []*browserk.Navigation {
+	navs := make([]*browserk.Navigation, len(candidates))
+	copy(navs, candidates)
+
+	sort.SliceStable(navs, func(i, j int) bool {
+		return less(navs[i], navs[j])
+	})
+
+	if limit > 0 && len(navs) > limit {
+		navs = navs[:limit]
+	}
+	return navs
+}
+
+// elementKind is the element's tag and attribute names, so similar elements (menu toggles etc) on other
+// pages are considered the same kind
+func elementKind(ele *browserk.HTMLElement) string {
+	names := make([]string, 0, len(ele.Attributes))
+	for name := range ele.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return ele.Tag() + ":" + strings.Join(names, ",")
+}
+
+// navigationPattern of the url the navigation would load, if any
+func (s *NoveltyScheduler) navigationPattern(nav *browserk.Navigation) string {
+	if nav.Action == nil {
+		return ""
+	}
+
+	switch {
+	case nav.Action.Form != nil:
+		return s.pathPattern(nav.Action.Form.GetAttribute("action"))
+	case nav.Action.Element != nil:
+		return s.pathPattern(nav.Action.Element.GetAttribute("href"))
+	case nav.Action.Type == browserk.ActLoadURL:
+		return s.pathPattern(string(nav.Action.Input))
+	}
+	return ""
+}
+
+// pathPattern of the url's path and query so relative and absolute urls can be compared. The scheduler has its
+// own learner so patterns only change with what it has seen.
+func (s *NoveltyScheduler) pathPattern(rawURL string) string {
+	if rawURL == "" || strings.HasPrefix(rawURL, "#") || strings.HasPrefix(rawURL, "javascript:") {
+		return ""
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	relative := &url.URL{Path: u.Path, RawQuery: u.RawQuery}
+	if relative.Path == "" {
+		relative.Path = "/"
+	}
+	return s.learner.Pattern(relative.String())
+}
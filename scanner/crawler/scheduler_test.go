@@ -0,0 +1,231 @@
+package crawler_test
+
+import (
+	"bytes"
+	"testing"
+
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/scanner/crawler"
+)
+
+func link(from *browserk.Navigation, href string) *browserk.Navigation {
+	ele := &browserk.HTMLElement{Type: browserk.A, InnerText: href, Attributes: map[string]string{"href": href}}
+	return browserk.NewNavigationFromElement(from, browserk.TrigCrawler, ele, browserk.ActLeftClick)
+}
+
+func button(from *browserk.Navigation, id string) *browserk.Navigation {
+	ele := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: id, Attributes: map[string]string{"class": "menu", "id": id}}
+	return browserk.NewNavigationFromElement(from, browserk.TrigCrawler, ele, browserk.ActLeftClick)
+}
+
+func form(from *browserk.Navigation, action string) *browserk.Navigation {
+	f := &browserk.HTMLFormElement{Type: browserk.FORM, Attributes: map[string]string{"action": action, "method": "post"}}
+	return browserk.NewNavigationFromForm(from, browserk.TrigCrawler, f)
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	entry := browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction("http://example.com/"))
+	shallow := link(entry, "/about")
+	deep := link(shallow, "/about/team")
+	deeper := link(deep, "/about/team/history")
+	candidates := []*browserk.Navigation{deep, deeper, shallow}
+
+	next := crawler.NewScheduler(browserk.BreadthFirst).Next(candidates, 2)
+	if len(next) != 2 || next[0] != shallow || next[1] != deep {
+		t.Fatalf("expected breadth first to return the lowest distances first\n")
+	}
+
+	next = crawler.NewScheduler(browserk.DepthFirst).Next(candidates, 1)
+	if len(next) != 1 || next[0] != deeper {
+		t.Fatalf("expected depth first to return the highest distance first\n")
+	}
+
+	if len(crawler.NewScheduler(browserk.DepthFirst).Next(candidates, 0)) != 3 {
+		t.Fatalf("expected no limit to return all candidates\n")
+	}
+}
+
+func TestNoveltyScheduler(t *testing.T) {
+	s := crawler.NewNoveltyScheduler()
+	entry := browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction("http://example.com/"))
+	about := link(entry, "/about")
+	search := form(entry, "/search")
+
+	if s.Score(search) <= s.Score(about) {
+		t.Fatalf("expected forms to score higher than links\n")
+	}
+
+	// after visiting /orders/1 another order is no longer novel
+	order := link(entry, "/orders/1")
+	s.Visited(order, &browserk.NavigationResult{EndURL: "http://example.com/orders/1", CausedLoad: true}, nil)
+	if s.Score(link(entry, "/orders/2")) >= s.Score(about) {
+		t.Fatalf("expected visited url patterns to score lower than unseen ones\n")
+	}
+
+	// a menu that opened without loading a page makes other menus interesting
+	menu := button(entry, "account")
+	before := s.Score(button(entry, "help"))
+	s.Visited(menu, &browserk.NavigationResult{EndURL: "http://example.com/"}, []*browserk.Navigation{link(menu, "/account/settings")})
+	if s.Score(button(entry, "help")) <= before {
+		t.Fatalf("expected elements like ones that revealed new navigations to score higher\n")
+	}
+
+	// links always find things, they shouldn't get the reveal bonus
+	before = s.Score(link(entry, "/orders/4"))
+	s.Visited(about, &browserk.NavigationResult{EndURL: "http://example.com/about", CausedLoad: true}, []*browserk.Navigation{link(about, "/about/team")})
+	if s.Score(link(entry, "/orders/4")) != before {
+		t.Fatalf("expected links that loaded a page to not get the reveal bonus\n")
+	}
+
+	next := s.Next([]*browserk.Navigation{link(entry, "/orders/3"), search, link(entry, "/files/d41d8cd98f00b204e9800998ecf8427e")}, 3)
+	if !bytes.Equal(next[0].ID, search.ID) || next[2].Action.Element.GetAttribute("href") != "/orders/3" {
+		t.Fatalf("expected novelty order of form, unseen link then seen link\n")
+	}
+}
+
+// page of the synthetic site used to compare strategies
+type page struct {
+	links []string
+	forms []string
+	menus map[string][]string // menu button id -> links revealed by clicking it
+}
+
+var site = map[string]*page{
+	"/": {
+		links: []string{"/about", "/blog", "/docs", "/orders/1", "/orders/2", "/orders/3"},
+		forms: []string{"/search"},
+		menus: map[string][]string{"account": {"/account/settings", "/account/billing"}},
+	},
+	"/about":                  {links: []string{"/about/team", "/about/history"}},
+	"/about/team":             {links: []string{"/about/team/alumni"}},
+	"/about/team/alumni":      {links: []string{"/about/team/alumni/2019"}},
+	"/about/team/alumni/2019": {links: []string{"/about/team/alumni/2019/photos"}},
+	"/blog":                   {links: []string{"/blog/archive", "/blog/post-a", "/blog/post-b", "/blog/post-c"}},
+	"/blog/archive":           {links: []string{"/blog/archive/older"}},
+	"/blog/archive/older":     {links: []string{"/blog/archive/older/oldest"}},
+	"/blog/post-a":            {forms: []string{"/blog/post-a/comment"}},
+	"/docs": {
+		links: []string{"/docs/intro", "/docs/install", "/docs/api"},
+		menus: map[string][]string{"help": {"/support/ticket"}},
+	},
+	"/support/ticket":   {forms: []string{"/support/ticket/new"}},
+	"/search":           {links: []string{"/search/advanced"}},
+	"/search/advanced":  {forms: []string{"/search/advanced"}},
+	"/orders/1":         {forms: []string{"/orders/1/cancel"}, links: []string{"/orders/1/invoice"}},
+	"/orders/2":         {forms: []string{"/orders/2/cancel"}, links: []string{"/orders/2/invoice"}},
+	"/orders/3":         {forms: []string{"/orders/3/cancel"}, links: []string{"/orders/3/invoice"}},
+	"/account/settings": {forms: []string{"/account/settings/password", "/account/settings/email"}},
+	"/account/billing":  {forms: []string{"/account/billing/card"}},
+}
+
+// visit the final navigation of the path returning the url we ended up on and what we found there
+func visit(path []*browserk.Navigation, current string) (string, *browserk.NavigationResult, []*browserk.Navigation) {
+	nav := path[len(path)-1]
+	action := nav.Action
+
+	endURL := current
+	var revealed []string
+	switch {
+	case action.Type == browserk.ActLoadURL:
+		endURL = "/"
+	case action.Form != nil:
+		endURL = action.Form.GetAttribute("action")
+	case action.Element.GetAttribute("href") != "":
+		endURL = action.Element.GetAttribute("href")
+	default:
+		revealed = site[current].menus[action.Element.GetAttribute("id")]
+	}
+
+	result := &browserk.NavigationResult{EndURL: "http://example.com" + endURL, CausedLoad: endURL != current}
+	found := make([]*browserk.Navigation, 0)
+	if !result.CausedLoad {
+		for _, href := range revealed {
+			found = append(found, link(nav, href))
+		}
+		return endURL, result, found
+	}
+
+	if p, ok := site[endURL]; ok {
+		for _, href := range p.links {
+			found = append(found, link(nav, href))
+		}
+		for _, action := range p.forms {
+			found = append(found, form(nav, action))
+		}
+		for id := range p.menus {
+			found = append(found, button(nav, id))
+		}
+	}
+	return endURL, result, found
+}
+
+// crawl the synthetic site with the strategy for budget actions, returns the number of unique url patterns
+// visited and forms submitted
+func crawlSite(strategy browserk.CrawlStrategy, budget int) (int, int) {
+	s := crawler.NewScheduler(strategy)
+	learner := browserk.NewURLPatternLearner()
+	entry := browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction("http://example.com/"))
+
+	seen := map[string]struct{}{string(entry.ID): {}}
+	unvisited := [][]*browserk.Navigation{{entry}}
+	patterns := make(map[string]struct{})
+	forms := 0
+
+	for actions := 0; actions < budget && len(unvisited) > 0; {
+		candidates := make([]*browserk.Navigation, 0, len(unvisited))
+		for _, path := range unvisited {
+			candidates = append(candidates, path[len(path)-1])
+		}
+		chosen := s.Next(candidates, 1)[0]
+
+		var next []*browserk.Navigation
+		remaining := make([][]*browserk.Navigation, 0, len(unvisited))
+		for _, path := range unvisited {
+			if path[len(path)-1] == chosen {
+				next = path
+				continue
+			}
+			remaining = append(remaining, path)
+		}
+		unvisited = remaining
+
+		// replay the path like the crawler does, each step costs an action
+		current := ""
+		var result *browserk.NavigationResult
+		var found []*browserk.Navigation
+		for _, nav := range next {
+			current, result, found = visit([]*browserk.Navigation{nav}, current)
+			actions++
+		}
+
+		nav := next[len(next)-1]
+		patterns[learner.Pattern(current)] = struct{}{}
+		if nav.Action.Form != nil {
+			forms++
+		}
+
+		s.Visited(nav, result, found)
+		for _, newNav := range found {
+			if _, ok := seen[string(newNav.ID)]; ok {
+				continue
+			}
+			seen[string(newNav.ID)] = struct{}{}
+			path := append(append([]*browserk.Navigation{}, next...), newNav)
+			unvisited = append(unvisited, path)
+		}
+	}
+	return len(patterns), forms
+}
+
+func BenchmarkSchedulerCoverage(b *testing.B) {
+	for _, strategy := range []browserk.CrawlStrategy{browserk.BreadthFirst, browserk.DepthFirst, browserk.NoveltyFirst} {
+		b.Run(browserk.CrawlStrategyMap[strategy], func(b *testing.B) {
+			var patterns, forms int
+			for i := 0; i < b.N; i++ {
+				patterns, forms = crawlSite(strategy, 60)
+			}
+			b.ReportMetric(float64(patterns), "patterns")
+			b.ReportMetric(float64(forms), "forms")
+		})
+	}
+}
@@ -0,0 +1,487 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/parser"
+	"github.com/dop251/goja/token"
+	"gitlab.com/browserker/browserk"
+)
+
+const (
+	// maxScriptEndpoints we take from a single script, minified bundles are full of path like strings
+	maxScriptEndpoints = 200
+	// maxCachedScripts we keep the endpoints of, a scan loads the same few bundles on every page
+	maxCachedScripts = 128
+)
+
+var (
+	// absolute paths (/api/v1/users?active=1), the first segment needs at least 2 characters to skip
+	// regex like and divider strings of minified code
+	pathLiteralRe = regexp.MustCompile(`^/[\w\-.~%:@{}$]{2,}(/[\w\-.~%:@{}$]*)*(\?[^\s"'<>]*)?$`)
+	// relative paths, only taken from call targets (fetch('api/users'))
+	relativePathRe = regexp.MustCompile(`^[\w\-.~%]+(/[\w\-.~%:@{}$]*)*(\?[^\s"'<>]*)?$`)
+	// assets loaded by the page anyways
+	staticPathRe = regexp.MustCompile(`(?i)\.(js|mjs|css|map|png|jpe?g|gif|svg|ico|webp|woff2?|ttf|eot|otf|mp4|mp3)(\?.*)?$`)
+	// route parameters (:id, {id}) replaced with a value
+	routeParamRe = regexp.MustCompile(`(^|/)(:[\w]+\??|\{[\w]+\})`)
+
+	// fallback scanner contexts, the text right before a string literal
+	callContextRe  = regexp.MustCompile(`(?:\bfetch|\bRequest|\baxios|\.(get|post|put|patch|delete|head|options|getJSON|ajax|request))\s*\(\s*$`)
+	routeContextRe = regexp.MustCompile(`\bpath\s*:\s*$`)
+	urlContextRe   = regexp.MustCompile(`\burl\s*:\s*$`)
+	// first argument of XMLHttpRequest.open, tells it apart from window.open
+	xhrMethodRe = regexp.MustCompile(`(?i)^(get|head|post|put|patch|delete|options)$`)
+)
+
+// httpReceivers whose get/post/put etc methods send requests (axios.get, this.$http.post, this.http.get, $.getJSON)
+var httpReceivers = map[string]struct{}{"axios": {}, "$http": {}, "http": {}, "httpClient": {}, "$": {}, "jQuery": {}, "superagent": {}, "request": {}}
+
+// httpMethods calls on an httpReceiver and the method they send
+var httpMethods = map[string]string{
+	"get": "GET", "getJSON": "GET", "head": "HEAD", "options": "OPTIONS", "delete": "DELETE",
+	"post": "POST", "put": "PUT", "patch": "PATCH", "ajax": "", "request": "",
+}
+
+// scriptEndpoints cache keyed by the response body hash, the same bundle is loaded by every page
+var scriptEndpoints = &endpointCache{endpoints: make(map[string][]*ScriptEndpoint)}
+
+// ScriptEndpoint is a url or path found in a javascript response
+type ScriptEndpoint struct {
+	URL    string // absolute or relative to the page that loaded the script
+	Method string // http method of fetch/axios/xhr calls, empty if unknown
+	Line   int    // line of the script it was found on
+}
+
+// endpointExtractor collects the endpoints of a script
+type endpointExtractor struct {
+	fileSet   *file.FileSet
+	endpoints []*ScriptEndpoint
+	found     map[string]struct{}
+}
+
+// ExtractEndpoints of a script: url and path string literals, fetch/axios/XMLHttpRequest call targets and the
+// paths of route tables ({path: 'users/:id'}). ES5 scripts are walked with goja's parser, anything it can't
+// parse (ES2015+ bundles) falls back to scanning the string literals and the text before them.
+func ExtractEndpoints(src string) []*ScriptEndpoint {
+	e := &endpointExtractor{
+		fileSet:   &file.FileSet{},
+		endpoints: make([]*ScriptEndpoint, 0),
+		found:     make(map[string]struct{}),
+	}
+
+	program, err := parser.ParseFile(e.fileSet, "", src, parser.IgnoreRegExpErrors)
+	if err != nil {
+		e.scan(src)
+		return e.endpoints
+	}
+	e.walk(reflect.ValueOf(program))
+
+	// function declarations are walked after the statements of their scope
+	sort.SliceStable(e.endpoints, func(i, j int) bool { return e.endpoints[i].Line < e.endpoints[j].Line })
+	return e.endpoints
+}
+
+// add the endpoint if we haven't already found it with this method. The url argument of a call is walked again
+// as a plain literal, so an endpoint without a method is only added if we haven't found it with any method.
+func (e *endpointExtractor) add(endpoint, method string, line int) {
+	if endpoint == "" || len(e.endpoints) == maxScriptEndpoints {
+		return
+	}
+
+	key := endpoint
+	if method != "" {
+		key = method + " " + endpoint
+	}
+
+	if _, exist := e.found[key]; exist {
+		return
+	}
+	e.found[endpoint] = struct{}{}
+	e.found[method+" "+endpoint] = struct{}{}
+	e.endpoints = append(e.endpoints, &ScriptEndpoint{URL: endpoint, Method: method, Line: line})
+}
+
+func (e *endpointExtractor) line(idx file.Idx) int {
+	if position := e.fileSet.Position(idx); position != nil {
+		return position.Line
+	}
+	return 0
+}
+
+// walk every ast node of the program, goja's ast has no walker so use reflection. Parents are visited before their
+// children so call targets are added with their method before the string literal itself.
+func (e *endpointExtractor) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			e.walk(v.Elem())
+		}
+	case reflect.Ptr:
+		if v.IsNil() || v.Type().Elem().PkgPath() != reflect.TypeOf(ast.Program{}).PkgPath() {
+			return
+		}
+
+		if node, ok := v.Interface().(ast.Node); ok {
+			e.visit(node)
+		}
+		e.walk(v.Elem())
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			e.walk(v.Index(i))
+		}
+	case reflect.Struct:
+		if v.Type().PkgPath() != reflect.TypeOf(ast.Program{}).PkgPath() {
+			return
+		}
+
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanInterface() {
+				e.walk(v.Field(i))
+			}
+		}
+	}
+}
+
+func (e *endpointExtractor) visit(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.CallExpression:
+		e.call(n.Callee, n.ArgumentList, n.LeftParenthesis)
+	case *ast.NewExpression:
+		e.call(n.Callee, n.ArgumentList, n.LeftParenthesis)
+	case *ast.ObjectLiteral:
+		e.object(n)
+	case *ast.StringLiteral:
+		if isEndpointLiteral(n.Value) {
+			e.add(n.Value, "", e.line(n.Idx))
+		}
+	}
+}
+
+// call adds the target of fetch, new Request, axios/$http/jQuery and XMLHttpRequest.open calls
+func (e *endpointExtractor) call(callee ast.Expression, args []ast.Expression, idx file.Idx) {
+	if len(args) == 0 {
+		return
+	}
+
+	receiver, name := calleeName(callee)
+	switch {
+	case receiver == "" && (name == "fetch" || name == "Request" || name == "axios"):
+		method := "GET"
+		if len(args) > 1 {
+			if options, ok := args[1].(*ast.ObjectLiteral); ok {
+				if m := propertyString(options, "method"); m != "" {
+					method = strings.ToUpper(m)
+				}
+			}
+		}
+		e.add(callTarget(args[0]), method, e.line(idx))
+	case name == "open" && len(args) > 1:
+		// xhr.open('POST', '/api/users')
+		if m, ok := args[0].(*ast.StringLiteral); ok && xhrMethodRe.MatchString(m.Value) {
+			e.add(callTarget(args[1]), strings.ToUpper(m.Value), e.line(idx))
+		}
+	default:
+		method, ok := httpMethods[name]
+		if _, isReceiver := httpReceivers[receiver]; !ok || !isReceiver {
+			return
+		}
+		e.add(callTarget(args[0]), method, e.line(idx))
+	}
+}
+
+// object adds the path of route tables (vue/angular/react router) and the url of request options ($.ajax, axios)
+func (e *endpointExtractor) object(obj *ast.ObjectLiteral) {
+	if path := propertyString(obj, "path"); path != "" {
+		e.add(routePath(path), "", e.line(obj.LeftBrace))
+	}
+
+	if target := propertyString(obj, "url"); target != "" && (isEndpointLiteral(target) || relativePathRe.MatchString(target)) {
+		method := propertyString(obj, "method")
+		if method == "" {
+			method = propertyString(obj, "type")
+		}
+		e.add(target, strings.ToUpper(method), e.line(obj.LeftBrace))
+	}
+}
+
+// calleeName returns the receiver and function name of a call, (this.$http.get) -> ($http, get)
+func calleeName(callee ast.Expression) (string, string) {
+	switch c := callee.(type) {
+	case *ast.Identifier:
+		return "", c.Name
+	case *ast.DotExpression:
+		switch left := c.Left.(type) {
+		case *ast.Identifier:
+			return left.Name, c.Identifier.Name
+		case *ast.DotExpression:
+			return left.Identifier.Name, c.Identifier.Name
+		}
+		return "", c.Identifier.Name
+	}
+	return "", ""
+}
+
+// callTarget returns the url argument of a call, or the static prefix of a concatenated one ('/api/users/' + id)
+func callTarget(arg ast.Expression) string {
+	switch a := arg.(type) {
+	case *ast.StringLiteral:
+		if isEndpointLiteral(a.Value) || relativePathRe.MatchString(a.Value) {
+			return a.Value
+		}
+	case *ast.BinaryExpression:
+		if a.Operator == token.PLUS {
+			return callTarget(a.Left)
+		}
+	}
+	return ""
+}
+
+// propertyString returns the string literal value of the object's key
+func propertyString(obj *ast.ObjectLiteral, key string) string {
+	for _, property := range obj.Value {
+		if property.Key != key {
+			continue
+		}
+
+		if value, ok := property.Value.(*ast.StringLiteral); ok {
+			return value.Value
+		}
+	}
+	return ""
+}
+
+// routePath of a router path, parameters are replaced and wildcard (catch all) routes are skipped
+func routePath(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" || strings.Contains(path, "*") || strings.Contains(path, "(") || strings.HasPrefix(path, "http") {
+		return ""
+	}
+
+	path = "/" + strings.TrimPrefix(path, "/")
+	path = routeParamRe.ReplaceAllString(path, "${1}1")
+	if path == "/" || !pathLiteralRe.MatchString(path) {
+		return ""
+	}
+	return path
+}
+
+// isEndpointLiteral returns true for absolute http(s) urls and paths that aren't static assets
+func isEndpointLiteral(value string) bool {
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		if _, err := url.Parse(value); err != nil || strings.ContainsAny(value, " \t\n<>\"'") {
+			return false
+		}
+		return !staticPathRe.MatchString(value)
+	}
+	return pathLiteralRe.MatchString(value) && !staticPathRe.MatchString(value)
+}
+
+// scan the string literals of scripts goja can't parse, using the text before each literal to tell call targets
+// and route paths apart. Regex literals containing quotes may throw this off, which is fine for a fallback.
+func (e *endpointExtractor) scan(src string) {
+	line := 1
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			line++
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end == -1 {
+				return
+			}
+			line += strings.Count(src[i:i+end+4], "\n")
+			i += end + 3
+		case c == '\'' || c == '"' || c == '`':
+			start, startLine := i, line
+			value := &strings.Builder{}
+			static := true
+			for i++; i < len(src) && src[i] != c; i++ {
+				ch := src[i]
+				if ch == '\n' {
+					line++
+					// unterminated string
+					if c != '`' {
+						break
+					}
+				}
+
+				if ch == '\\' && i+1 < len(src) {
+					i++
+					ch = src[i]
+				} else if c == '`' && ch == '$' && i+1 < len(src) && src[i+1] == '{' {
+					// only the static prefix of a template is useful
+					static = false
+				}
+
+				if static {
+					value.WriteByte(ch)
+				}
+			}
+			e.literal(src[:start], value.String(), startLine)
+		}
+	}
+}
+
+// literal found by the scanner, before is the source leading up to it
+func (e *endpointExtractor) literal(before, value string, line int) {
+	if len(before) > 64 {
+		before = before[len(before)-64:]
+	}
+
+	switch {
+	case routeContextRe.MatchString(before):
+		e.add(routePath(value), "", line)
+	case callContextRe.MatchString(before), urlContextRe.MatchString(before):
+		if isEndpointLiteral(value) || relativePathRe.MatchString(value) {
+			method := ""
+			if match := callContextRe.FindStringSubmatch(before); match != nil {
+				method = httpMethods[match[1]]
+			}
+			e.add(value, method, line)
+		}
+	case isEndpointLiteral(value):
+		e.add(value, "", line)
+	}
+}
+
+// findScriptNavs adds a load url navigation for each in scope GET (or unknown method) endpoint extracted from the
+// scripts the result loaded, so the attack phase can reach api routes the UI never called. The script url and line it was found
+// on is recorded as the navigation's Source. Endpoints of other methods can't be loaded, they are added to the result's
+// AttackTargets unless safe mode blocks them.
+func (b *BrowserkCrawler) findScriptNavs(bctx *browserk.Context, entry *browserk.Navigation, result *browserk.NavigationResult) []*browserk.Navigation {
+	navs := make([]*browserk.Navigation, 0)
+	pageURL, err := url.Parse(result.EndURL)
+	if err != nil {
+		return navs
+	}
+
+	added := make(map[string]struct{})
+	// requests the browser already sent are attacked anyways
+	sent := make(map[string]struct{})
+	for _, msg := range result.Messages {
+		if msg.Request != nil && msg.Request.Request != nil {
+			sent[msg.Request.Request.Method+" "+msg.Request.Request.Url] = struct{}{}
+		}
+	}
+
+	for _, msg := range result.Messages {
+		resp := msg.Response
+		if resp == nil || resp.Type != "Script" || resp.Response == nil || len(resp.Body) == 0 {
+			continue
+		}
+
+		// third party scripts (analytics, cdns) won't list our endpoints
+		if bctx.Scope.CheckURL(resp.Response.Url) != browserk.InScope {
+			continue
+		}
+
+		for _, endpoint := range extractResponseEndpoints(resp) {
+			endpointURL, err := pageURL.Parse(endpoint.URL)
+			if err != nil || bctx.Scope.Check(endpointURL) != browserk.InScope {
+				continue
+			}
+			endpointURL.Fragment = ""
+			source := fmt.Sprintf("%s:%d", resp.Response.Url, endpoint.Line)
+
+			// a load url navigation can only send a GET, post/put/delete routes are attacked directly
+			switch endpoint.Method {
+			case "", "GET", "HEAD":
+			default:
+				b.addAttackTarget(bctx, result, sent, &browserk.AttackTarget{Method: endpoint.Method, URL: endpointURL.String(), Source: source})
+				continue
+			}
+
+			nav := browserk.NewNavigationFromBrowser(entry, browserk.TrigScript, browserk.NewLoadURLAction(endpointURL.String()))
+			nav.Source = source
+			if _, exist := added[string(nav.ID)]; exist || bctx.Crawl != nil && bctx.Crawl.NavExists(nav) {
+				continue
+			}
+			added[string(nav.ID)] = struct{}{}
+
+			if logoutHrefRe.MatchString(endpointURL.Path) {
+				b.excludeLogout(bctx, nav, "logout script endpoint: "+endpointURL.Path)
+			}
+			bctx.Log.Debug().Str("url", endpointURL.String()).Str("method", endpoint.Method).Str("source", nav.Source).Msg("found script endpoint")
+			navs = append(navs, nav)
+		}
+	}
+	return navs
+}
+
+// addAttackTarget to the result unless the browser already sent it or safe mode blocks it
+func (b *BrowserkCrawler) addAttackTarget(bctx *browserk.Context, result *browserk.NavigationResult, sent map[string]struct{}, target *browserk.AttackTarget) {
+	key := target.Method + " " + target.URL
+	if _, exist := sent[key]; exist {
+		return
+	}
+	sent[key] = struct{}{}
+
+	if b.safety != nil {
+		if reason := b.safety.CheckRequest(target.Method, target.URL); reason != "" {
+			bctx.Log.Info().Str("url", target.URL).Str("method", target.Method).Str("reason", reason).Msg("safe mode, not adding script endpoint as attack target")
+			return
+		}
+	}
+
+	bctx.Log.Debug().Str("url", target.URL).Str("method", target.Method).Str("source", target.Source).Msg("found script attack target")
+	result.AttackTargets = append(result.AttackTargets, target)
+}
+
+// extractResponseEndpoints of the script, cached by body hash
+func extractResponseEndpoints(resp *browserk.HTTPResponse) []*ScriptEndpoint {
+	if len(resp.BodyHash) == 0 {
+		return ExtractEndpoints(string(resp.Body))
+	}
+
+	if endpoints, ok := scriptEndpoints.get(string(resp.BodyHash)); ok {
+		return endpoints
+	}
+
+	endpoints := ExtractEndpoints(string(resp.Body))
+	scriptEndpoints.put(string(resp.BodyHash), endpoints)
+	return endpoints
+}
+
+// endpointCache of the last maxCachedScripts scripts, the oldest is evicted first
+type endpointCache struct {
+	lock      sync.Mutex
+	endpoints map[string][]*ScriptEndpoint
+	order     []string
+}
+
+func (c *endpointCache) get(hash string) ([]*ScriptEndpoint, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	endpoints, ok := c.endpoints[hash]
+	return endpoints, ok
+}
+
+func (c *endpointCache) put(hash string, endpoints []*ScriptEndpoint) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, exist := c.endpoints[hash]; exist {
+		return
+	}
+
+	if len(c.order) == maxCachedScripts {
+		delete(c.endpoints, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.endpoints[hash] = endpoints
+	c.order = append(c.order, hash)
+}
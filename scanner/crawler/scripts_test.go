@@ -0,0 +1,156 @@
+package crawler_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/wirepair/gcd/v2/gcdapi"
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/mock"
+	"gitlab.com/browserker/scanner"
+	"gitlab.com/browserker/scanner/crawler"
+)
+
+const es5Script = `(function() {
+	var api = "/api/v1";
+	function load(id) {
+		return fetch("/api/v1/users/" + id, {method: "delete"});
+	}
+	axios.post("/api/v1/orders", {item: 1});
+	var xhr = new XMLHttpRequest();
+	xhr.open("PUT", "api/v1/profile");
+	window.open("/popup", "_blank");
+	$.ajax({url: "/legacy/search.php", type: "post"});
+	var routes = [
+		{path: "/dashboard", component: Dashboard},
+		{path: "users/:id/edit", component: UserEdit},
+		{path: "**", component: NotFound}
+	];
+	var logo = "/static/logo.png";
+	var docs = "https://example.com/docs";
+	var split = "/";
+	axios.get("/api/v1/orders");
+})();`
+
+const es2015Script = `const routes = [{ path: '/settings/:tab' }];
+// fetch('/commented/out')
+const load = async (id) => {
+	const resp = await fetch(` + "`/api/items/${id}`" + `);
+	return this.http.get('/api/cart?expand=1');
+};
+/* block
+comment */ export const base = "/graphql";`
+
+func TestExtractEndpoints(t *testing.T) {
+	var tests = []struct {
+		name     string
+		src      string
+		expected []crawler.ScriptEndpoint
+	}{
+		{"es5", es5Script, []crawler.ScriptEndpoint{
+			{URL: "/api/v1", Line: 2},
+			{URL: "/api/v1/users/", Method: "DELETE", Line: 4},
+			{URL: "/api/v1/orders", Method: "POST", Line: 6},
+			{URL: "api/v1/profile", Method: "PUT", Line: 8},
+			{URL: "/popup", Line: 9},
+			{URL: "/legacy/search.php", Method: "POST", Line: 10},
+			{URL: "/dashboard", Line: 12},
+			{URL: "/users/1/edit", Line: 13},
+			{URL: "https://example.com/docs", Line: 17},
+			{URL: "/api/v1/orders", Method: "GET", Line: 19},
+		}},
+		{"es2015", es2015Script, []crawler.ScriptEndpoint{
+			{URL: "/settings/1", Line: 1},
+			{URL: "/api/items/", Line: 4},
+			{URL: "/api/cart?expand=1", Method: "GET", Line: 5},
+			{URL: "/graphql", Line: 8},
+		}},
+	}
+
+	for _, tt := range tests {
+		endpoints := crawler.ExtractEndpoints(tt.src)
+		if len(endpoints) != len(tt.expected) {
+			for _, endpoint := range endpoints {
+				t.Logf("%#v\n", endpoint)
+			}
+			t.Fatalf("%s expected %d endpoints got %d\n", tt.name, len(tt.expected), len(endpoints))
+		}
+
+		for i, endpoint := range endpoints {
+			if *endpoint != tt.expected[i] {
+				t.Fatalf("%s expected %#v got %#v\n", tt.name, tt.expected[i], endpoint)
+			}
+		}
+	}
+}
+
+func TestProcessScriptEndpoints(t *testing.T) {
+	target, _ := url.Parse("http://example.com/app/")
+	bctx := mock.MakeMockContext(context.Background(), target)
+	bctx.Scope = scanner.NewScopeService(target)
+
+	script := func(scriptURL, body string) *browserk.HTTPMessage {
+		return &browserk.HTTPMessage{Response: &browserk.HTTPResponse{
+			Type:     "Script",
+			Response: &gcdapi.NetworkResponse{Url: scriptURL, Status: 200},
+			Body:     []byte(body),
+		}}
+	}
+
+	browser := mock.MakeMockBrowser()
+	browser.GetURLFn = func() (string, error) { return target.String(), nil }
+	browser.GetMessagesFn = func() ([]*browserk.HTTPMessage, error) {
+		return []*browserk.HTTPMessage{
+			script("http://example.com/app/main.js", "fetch('/api/users');\nfetch('api/logout');\nfetch('http://other.com/api/track');\naxios.post('/api/orders');\naxios.delete('/api/users');"),
+			script("http://cdn.other.com/lib.js", "fetch('/api/never');"),
+		}, nil
+	}
+
+	c := crawler.New(&browserk.Config{Safety: &browserk.Safety{Level: browserk.SafetyBlock}})
+	if err := c.Init(); err != nil {
+		t.Fatalf("error init crawler: %s\n", err)
+	}
+
+	entry := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(target.String()))
+	result, navs, err := c.Process(bctx, browser, entry, true)
+	if err != nil {
+		t.Fatalf("error processing: %s\n", err)
+	}
+
+	scriptNavs := make([]*browserk.Navigation, 0)
+	for _, nav := range navs {
+		if nav.TriggeredBy == browserk.TrigScript {
+			scriptNavs = append(scriptNavs, nav)
+		}
+	}
+
+	if len(scriptNavs) != 2 {
+		t.Fatalf("expected in scope endpoints of the first party script got %d\n", len(scriptNavs))
+	}
+
+	users := scriptNavs[0]
+	if string(users.Action.Input) != "http://example.com/api/users" || users.Source != "http://example.com/app/main.js:1" || users.State != browserk.NavUnvisited {
+		t.Fatalf("expected users endpoint with its source got %s %s\n", users, users.Source)
+	}
+
+	logout := scriptNavs[1]
+	if string(logout.Action.Input) != "http://example.com/app/api/logout" || logout.Source != "http://example.com/app/main.js:2" || logout.State != browserk.NavExcluded {
+		t.Fatalf("expected relative logout endpoint to be excluded got %s %s\n", logout, logout.Source)
+	}
+
+	// the delete is blocked by safe mode
+	if len(result.AttackTargets) != 1 {
+		t.Fatalf("expected the post endpoint as the only attack target got %d\n", len(result.AttackTargets))
+	}
+
+	orders := result.AttackTargets[0]
+	if orders.Method != "POST" || orders.URL != "http://example.com/api/orders" || orders.Source != "http://example.com/app/main.js:4" {
+		t.Fatalf("expected orders endpoint with its method and source got %s %s %s\n", orders.Method, orders.URL, orders.Source)
+	}
+
+	msg := orders.Message(result.EndURL)
+	if msg.Request.Request.Method != "POST" || msg.Request.Request.Url != orders.URL || msg.Request.DocumentURL != target.String() {
+		t.Fatalf("expected the attack target to be sent as a post from the page\n")
+	}
+}
@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// maxScrollables we probe per page, each probe waits for the page to become stable
+const maxScrollables = 3
+
+// findScrollNavs probes if scrolling the window or the page's scrollable containers loads more content (feeds,
+// lazy loaded tables) and adds a scroll navigation for each one that does. When the entry is itself a scroll
+// navigation only its target is probed, so the crawl keeps scrolling step by step (re-snapshotting the page with
+// the ElementDiffer before each step) until nothing more loads or MaxScrolls is reached.
+func (b *BrowserkCrawler) findScrollNavs(bctx *browserk.Context, entry *browserk.Navigation, browser browserk.Browser) []*browserk.Navigation {
+	navs := make([]*browserk.Navigation, 0)
+	if b.cfg.MaxScrolls <= 0 {
+		return navs
+	}
+
+	pageURL, err := browser.GetURL()
+	if err != nil {
+		bctx.Log.Warn().Err(err).Msg("failed to get url, not probing scrolling")
+		return navs
+	}
+
+	ctx, cancel := context.WithTimeout(bctx.Ctx, time.Second*20)
+	defer cancel()
+
+	if step := entry.ScrollStep(); step > 0 {
+		if step < b.cfg.MaxScrolls {
+			if nav := b.probeScroll(ctx, bctx, entry, browser, pageURL, entry.Action.Element, step+1); nav != nil {
+				navs = append(navs, nav)
+			}
+		}
+		return navs
+	}
+
+	if nav := b.probeScroll(ctx, bctx, entry, browser, pageURL, nil, 1); nav != nil {
+		navs = append(navs, nav)
+	}
+
+	scrollables, err := browser.FindScrollables(ctx)
+	if err != nil {
+		bctx.Log.Info().Err(err).Msg("error while extracting scrollable elements")
+	}
+
+	probed := 0
+	for _, ele := range scrollables {
+		if ele.Hidden {
+			continue
+		}
+
+		if probed == maxScrollables {
+			break
+		}
+		probed++
+
+		if nav := b.probeScroll(ctx, bctx, entry, browser, pageURL, ele, 1); nav != nil {
+			navs = append(navs, nav)
+		}
+	}
+	return navs
+}
+
+// probeScroll scrolls the window/element and returns the scroll navigation for the step if it loaded more content.
+// Pages we already have the scroll navigation for are not probed again.
+func (b *BrowserkCrawler) probeScroll(ctx context.Context, bctx *browserk.Context, entry *browserk.Navigation, browser browserk.Browser, pageURL string, ele *browserk.HTMLElement, step int) *browserk.Navigation {
+	nav := browserk.NewScrollNavigation(entry, pageURL, ele, step)
+	if bctx.Crawl != nil && bctx.Crawl.NavExists(nav) {
+		return nil
+	}
+
+	loaded, err := browser.Scroll(ctx, ele)
+	if err != nil {
+		bctx.Log.Debug().Err(err).Int("step", step).Msg("failed to scroll")
+		return nil
+	}
+
+	if !loaded {
+		return nil
+	}
+	bctx.Log.Info().Str("url", pageURL).Int("step", step).Bool("element", ele != nil).Msg("scrolling loaded more content")
+	return nav
+}
@@ -0,0 +1,81 @@
+package crawler_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/mock"
+	"gitlab.com/browserker/scanner/crawler"
+	"gitlab.com/browserker/store"
+)
+
+func TestFindNewNavScroll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scroll")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := store.NewCrawlGraph(mock.MakeMockConfig(), dir)
+	if err := g.Init(); err != nil {
+		t.Fatalf("error init graph: %s\n", err)
+	}
+	defer g.Close()
+
+	target, _ := url.Parse("http://example.com/dashboard")
+	bctx := mock.MakeMockContext(context.Background(), target)
+	bctx.Crawl = g
+
+	feed := &browserk.HTMLElement{Type: browserk.DIV, Attributes: map[string]string{"id": "feed"}}
+	sidebar := &browserk.HTMLElement{Type: browserk.DIV, Attributes: map[string]string{"id": "sidebar"}}
+
+	browser := mock.MakeMockBrowser()
+	browser.GetURLFn = func() (string, error) { return target.String(), nil }
+	browser.FindScrollablesFn = func(ctx context.Context) ([]*browserk.HTMLElement, error) {
+		return []*browserk.HTMLElement{feed, sidebar}, nil
+	}
+	// only the feed loads more rows, the window and sidebar don't
+	browser.ScrollFn = func(ctx context.Context, ele *browserk.HTMLElement) (bool, error) {
+		return ele == feed, nil
+	}
+
+	c := crawler.New(&browserk.Config{MaxScrolls: 2})
+	entry := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction(target.String()))
+	navs := c.FindNewNav(bctx, crawler.NewElementDiffer(), entry, browser)
+	if len(navs) != 1 {
+		t.Fatalf("expected one scroll navigation got %d\n", len(navs))
+	}
+
+	scroll := navs[0]
+	if scroll.Action.Type != browserk.ActMouseWheel || scroll.Action.Element != feed || scroll.ScrollStep() != 1 {
+		t.Fatalf("expected first scroll step of the feed got %s\n", scroll)
+	}
+
+	// the same page reached some other way doesn't start another scroll
+	g.AddNavigation(scroll)
+	other := browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction("http://example.com/dashboard?tab=2"))
+	if navs := c.FindNewNav(bctx, crawler.NewElementDiffer(), other, browser); len(navs) != 0 {
+		t.Fatalf("expected existing scroll navigation to not be probed again got %d\n", len(navs))
+	}
+
+	// scroll navigations only keep scrolling their target
+	browser.FindScrollablesCalled = false
+	navs = c.FindNewNav(bctx, crawler.NewElementDiffer(), scroll, browser)
+	if len(navs) != 1 || navs[0].ScrollStep() != 2 || navs[0].Action.Element != feed || browser.FindScrollablesCalled {
+		t.Fatalf("expected second scroll step of the feed\n")
+	}
+
+	if navs := c.FindNewNav(bctx, crawler.NewElementDiffer(), navs[0], browser); len(navs) != 0 {
+		t.Fatalf("expected MaxScrolls to stop scrolling got %d\n", len(navs))
+	}
+
+	browser.ScrollCalled = false
+	crawler.New(&browserk.Config{MaxScrolls: -1}).FindNewNav(bctx, crawler.NewElementDiffer(), entry, browser)
+	if browser.ScrollCalled {
+		t.Fatalf("expected scrolling to be disabled\n")
+	}
+}
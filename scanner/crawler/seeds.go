@@ -0,0 +1,273 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"time"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// maxSitemaps we fetch per origin, sitemap indexes can nest and list thousands of sitemaps
+const maxSitemaps = 20
+
+// Seeds found in the well known files of the in scope origins
+type Seeds struct {
+	URLs       []string // in scope urls listed by robots.txt (Allow), sitemaps, security.txt and manifest.json
+	Disallowed []string // in scope urls robots.txt disallows
+}
+
+// seedFinder fetches the well known files of an origin through the browser
+type seedFinder struct {
+	bctx    *browserk.Context
+	browser browserk.Browser
+	seeds   *Seeds
+	found   map[string]struct{}
+}
+
+// FindSeeds fetches /robots.txt, /sitemap.xml (following sitemap indexes and sitemaps listed in robots.txt),
+// /.well-known/security.txt and /manifest.json of each origin and returns the in scope urls they list.
+func FindSeeds(bctx *browserk.Context, browser browserk.Browser, origins []*url.URL) *Seeds {
+	f := &seedFinder{
+		bctx:    bctx,
+		browser: browser,
+		seeds:   &Seeds{URLs: make([]string, 0), Disallowed: make([]string, 0)},
+		found:   make(map[string]struct{}),
+	}
+
+	for _, origin := range origins {
+		sitemaps := f.robots(origin)
+		f.sitemaps(origin, append([]string{resolveSeed(origin, "/sitemap.xml")}, sitemaps...))
+		f.securityTxt(origin)
+		f.manifest(origin)
+	}
+	return f.seeds
+}
+
+// fetch the url in the browser and return the body of the loaded document, false if it was not found
+func (f *seedFinder) fetch(fetchURL string) ([]byte, bool) {
+	// clear anything captured by earlier navigations
+	f.browser.GetMessages()
+
+	ctx, cancel := context.WithTimeout(f.bctx.Ctx, time.Second*15)
+	defer cancel()
+
+	if err := f.browser.Navigate(ctx, fetchURL); err != nil {
+		f.bctx.Log.Debug().Err(err).Str("url", fetchURL).Msg("failed to fetch seed file")
+		return nil, false
+	}
+
+	msgs, err := f.browser.GetMessages()
+	if err != nil {
+		return nil, false
+	}
+
+	// the last document response is the one we ended up on after any redirects
+	var body []byte
+	found := false
+	for _, msg := range msgs {
+		resp := msg.Response
+		if resp == nil || resp.Type != "Document" || resp.Response == nil {
+			continue
+		}
+		body = resp.Body
+		found = resp.Response.Status >= 200 && resp.Response.Status < 300
+	}
+	return body, found
+}
+
+// add the url if it's in scope and we haven't already
+func (f *seedFinder) add(base *url.URL, ref string, disallowed bool) {
+	seedURL := resolveSeed(base, ref)
+	if seedURL == "" {
+		return
+	}
+
+	if u, err := url.Parse(seedURL); err != nil || f.bctx.Scope.Check(u) != browserk.InScope {
+		return
+	}
+
+	if _, exist := f.found[seedURL]; exist {
+		return
+	}
+	f.found[seedURL] = struct{}{}
+
+	if disallowed {
+		f.seeds.Disallowed = append(f.seeds.Disallowed, seedURL)
+		return
+	}
+	f.seeds.URLs = append(f.seeds.URLs, seedURL)
+}
+
+// robots adds Allow/Disallow paths and returns the sitemaps it lists
+func (f *seedFinder) robots(origin *url.URL) []string {
+	sitemaps := make([]string, 0)
+	robotsURL := resolveSeed(origin, "/robots.txt")
+	body, ok := f.fetch(robotsURL)
+	if !ok {
+		return sitemaps
+	}
+
+	base, _ := url.Parse(robotsURL)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		value := strings.TrimSpace(parts[1])
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case "sitemap":
+			sitemaps = append(sitemaps, resolveSeed(base, value))
+		case "allow":
+			if path := robotsPath(value); path != "" {
+				f.add(base, path, false)
+			}
+		case "disallow":
+			if path := robotsPath(value); path != "" {
+				f.add(base, path, true)
+			}
+		}
+	}
+	return sitemaps
+}
+
+// robotsPath strips wildcard patterns down to the path prefix they match, the root is not interesting
+func robotsPath(value string) string {
+	if idx := strings.IndexAny(value, "*$"); idx != -1 {
+		value = value[:idx]
+	}
+
+	if value == "" || value == "/" || !strings.HasPrefix(value, "/") {
+		return ""
+	}
+	return value
+}
+
+// sitemap is either a urlset or a sitemapindex
+type sitemap struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// sitemaps adds the urls of each sitemap, following sitemap indexes
+func (f *seedFinder) sitemaps(origin *url.URL, queue []string) {
+	fetched := make(map[string]struct{})
+	for len(queue) > 0 && len(fetched) < maxSitemaps {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+
+		if _, exist := fetched[sitemapURL]; exist || sitemapURL == "" {
+			continue
+		}
+
+		// only follow sitemaps of this origin
+		u, err := url.Parse(sitemapURL)
+		if err != nil || u.Scheme != origin.Scheme || u.Host != origin.Host {
+			continue
+		}
+		fetched[sitemapURL] = struct{}{}
+
+		body, ok := f.fetch(sitemapURL)
+		if !ok {
+			continue
+		}
+
+		sm := &sitemap{}
+		if err := xml.Unmarshal(body, sm); err != nil {
+			f.bctx.Log.Debug().Err(err).Str("url", sitemapURL).Msg("failed to parse sitemap")
+			continue
+		}
+
+		for _, loc := range sm.URLs {
+			f.add(u, strings.TrimSpace(loc.Loc), false)
+		}
+
+		for _, loc := range sm.Sitemaps {
+			queue = append(queue, resolveSeed(u, strings.TrimSpace(loc.Loc)))
+		}
+	}
+}
+
+// securityTxt adds the urls of any field (Policy, Acknowledgments, Hiring, Contact etc)
+func (f *seedFinder) securityTxt(origin *url.URL) {
+	securityURL := resolveSeed(origin, "/.well-known/security.txt")
+	body, ok := f.fetch(securityURL)
+	if !ok {
+		return
+	}
+
+	base, _ := url.Parse(securityURL)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 || strings.HasPrefix(parts[0], "#") {
+			continue
+		}
+
+		value := strings.TrimSpace(parts[1])
+		if strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://") {
+			f.add(base, value, false)
+		}
+	}
+}
+
+// webManifest fields that point to pages of the app
+type webManifest struct {
+	StartURL  string `json:"start_url"`
+	Scope     string `json:"scope"`
+	Shortcuts []struct {
+		URL string `json:"url"`
+	} `json:"shortcuts"`
+}
+
+// manifest adds the start url, scope and shortcuts of the web app manifest
+func (f *seedFinder) manifest(origin *url.URL) {
+	manifestURL := resolveSeed(origin, "/manifest.json")
+	body, ok := f.fetch(manifestURL)
+	if !ok {
+		return
+	}
+
+	m := &webManifest{}
+	if err := json.Unmarshal(body, m); err != nil {
+		f.bctx.Log.Debug().Err(err).Str("url", manifestURL).Msg("failed to parse manifest")
+		return
+	}
+
+	base, _ := url.Parse(manifestURL)
+	f.add(base, m.StartURL, false)
+	f.add(base, m.Scope, false)
+	for _, shortcut := range m.Shortcuts {
+		f.add(base, shortcut.URL, false)
+	}
+}
+
+// resolveSeed reference against the base url, empty if it's not a http(s) url
+func resolveSeed(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	u.Fragment = ""
+	return u.String()
+}
@@ -0,0 +1,80 @@
+package crawler_test
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/wirepair/gcd/v2/gcdapi"
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/mock"
+	"gitlab.com/browserker/scanner"
+	"gitlab.com/browserker/scanner/crawler"
+)
+
+var seedFiles = map[string]string{
+	"http://example.com/robots.txt": `User-agent: *
+Disallow: /admin/ # keep out
+Disallow: /*.bak$
+Disallow: /
+Allow: /public
+Sitemap: http://example.com/sitemap_index.xml
+Sitemap: http://other.com/sitemap.xml`,
+	"http://example.com/sitemap.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>http://example.com/about</loc></url>
+	<url><loc>http://other.com/out-of-scope</loc></url>
+</urlset>`,
+	"http://example.com/sitemap_index.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>http://example.com/sitemaps/products.xml</loc></sitemap>
+	<sitemap><loc>http://example.com/sitemap_index.xml</loc></sitemap>
+</sitemapindex>`,
+	"http://example.com/sitemaps/products.xml": `<urlset><url><loc> http://example.com/products?id=1 </loc></url></urlset>`,
+	"http://example.com/.well-known/security.txt": `Contact: mailto:security@example.com
+Policy: http://example.com/security-policy
+Hiring: https://jobs.other.com/`,
+	"http://example.com/manifest.json": `{"start_url": "/app/?source=pwa", "scope": "/app/", "shortcuts": [{"url": "/app/new"}]}`,
+}
+
+func TestFindSeeds(t *testing.T) {
+	target, _ := url.Parse("http://example.com/")
+	bctx := mock.MakeMockContext(context.Background(), target)
+	bctx.Scope = scanner.NewScopeService(target)
+
+	current := ""
+	browser := mock.MakeMockBrowser()
+	browser.NavigateFn = func(ctx context.Context, url string) error {
+		current = url
+		return nil
+	}
+	browser.GetMessagesFn = func() ([]*browserk.HTTPMessage, error) {
+		body, exist := seedFiles[current]
+		status := 200
+		if !exist {
+			status = 404
+		}
+		current = ""
+		resp := &browserk.HTTPResponse{Type: "Document", Response: &gcdapi.NetworkResponse{Status: status}, Body: []byte(body)}
+		return []*browserk.HTTPMessage{{Response: resp}}, nil
+	}
+
+	seeds := crawler.FindSeeds(bctx, browser, []*url.URL{target})
+	expected := []string{
+		"http://example.com/public",
+		"http://example.com/about",
+		"http://example.com/products?id=1",
+		"http://example.com/security-policy",
+		"http://example.com/app/?source=pwa",
+		"http://example.com/app/",
+		"http://example.com/app/new",
+	}
+	if !reflect.DeepEqual(seeds.URLs, expected) {
+		t.Fatalf("expected seeds %v got %v\n", expected, seeds.URLs)
+	}
+
+	if !reflect.DeepEqual(seeds.Disallowed, []string{"http://example.com/admin/"}) {
+		t.Fatalf("expected disallowed admin path got %v\n", seeds.Disallowed)
+	}
+}
@@ -0,0 +1,55 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/md5"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// SkeletonHash of the DOM's structure: tag names, attribute names and their nesting. Text and
+// attribute values are ignored and repeated siblings with the same structure are collapsed, so
+// pages rendered from the same template (product details, list pages of different lengths) share
+// a skeleton. Returns nil if the DOM is empty or can't be parsed.
+func SkeletonHash(dom string) []byte {
+	if strings.TrimSpace(dom) == "" {
+		return nil
+	}
+
+	doc, err := html.Parse(strings.NewReader(dom))
+	if err != nil {
+		return nil
+	}
+	return skeletonNode(doc)
+}
+
+// skeletonNode hashes the node's tag and attribute names along with the skeletons of its children
+func skeletonNode(n *html.Node) []byte {
+	h := md5.New()
+	h.Write([]byte(n.Data))
+	h.Write([]byte{0})
+
+	names := make([]string, 0, len(n.Attr))
+	for _, attr := range n.Attr {
+		names = append(names, attr.Key)
+	}
+	sort.Strings(names)
+	h.Write([]byte(strings.Join(names, ",")))
+
+	var previous []byte
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+
+		skeleton := skeletonNode(child)
+		if bytes.Equal(skeleton, previous) {
+			continue
+		}
+		h.Write(skeleton)
+		previous = skeleton
+	}
+	return h.Sum(nil)
+}
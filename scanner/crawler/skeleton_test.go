@@ -0,0 +1,43 @@
+package crawler_test
+
+import (
+	"bytes"
+	"testing"
+
+	"gitlab.com/browserker/scanner/crawler"
+)
+
+func TestSkeletonHash(t *testing.T) {
+	product := func(name, price string, reviews int) string {
+		dom := `<html><head><title>` + name + `</title></head><body><div class="product" data-id="` + name + `">` +
+			`<h1>` + name + `</h1><span class="price">` + price + `</span><ul>`
+		for i := 0; i < reviews; i++ {
+			dom += `<li class="review"><p>great</p></li>`
+		}
+		return dom + `</ul><a href="/product/` + name + `/buy">buy</a></div></body></html>`
+	}
+
+	shoe := crawler.SkeletonHash(product("shoe", "$10", 1))
+	if shoe == nil {
+		t.Fatalf("expected a skeleton hash\n")
+	}
+
+	if hat := crawler.SkeletonHash(product("hat", "$25", 7)); !bytes.Equal(shoe, hat) {
+		t.Fatalf("expected pages of the same template to share a skeleton\n")
+	}
+
+	other := `<html><head><title>cart</title></head><body><form action="/checkout"><input name="qty"></form></body></html>`
+	if bytes.Equal(shoe, crawler.SkeletonHash(other)) {
+		t.Fatalf("expected different structures to have different skeletons\n")
+	}
+
+	attrs := `<html><head><title>shoe</title></head><body><div class="product"><h1>shoe</h1></div></body></html>`
+	noAttrs := `<html><head><title>shoe</title></head><body><div><h1>shoe</h1></div></body></html>`
+	if bytes.Equal(crawler.SkeletonHash(attrs), crawler.SkeletonHash(noAttrs)) {
+		t.Fatalf("expected attribute names to be part of the skeleton\n")
+	}
+
+	if crawler.SkeletonHash("  ") != nil {
+		t.Fatalf("expected no skeleton for an empty DOM\n")
+	}
+}
@@ -0,0 +1,336 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gitlab.com/browserker/browserk"
+)
+
+var (
+	// digits, dates and times change between requests
+	digitsRe = regexp.MustCompile(`[0-9]+`)
+	// csrf tokens, nonces and session ids
+	tokenRe      = regexp.MustCompile(`[A-Za-z0-9+/_\-]{24,}={0,2}`)
+	whitespaceRe = regexp.MustCompile(`\s+`)
+)
+
+const (
+	// pendingTimeout after which we give up on a request's response, aborted, blocked or failed requests never get one
+	pendingTimeout = time.Minute * 2
+	// replayWorkers send the replays once the crawl is complete
+	replayWorkers = 4
+)
+
+// pendingRequest waiting for its response
+type pendingRequest struct {
+	req   *browserk.HTTPRequest
+	added time.Time
+}
+
+// replayCandidate is a successful response of a role we replay as a less privileged role once the crawl is complete
+type replayCandidate struct {
+	nav   *browserk.Navigation
+	role  *browserk.Role
+	req   *browserk.HTTPRequest
+	resp  *browserk.HTTPResponse
+	lower browserk.AuthService
+}
+
+// Plugin replays requests made by a role with the sessions of less privileged roles and reports
+// if they get an equivalent successful response (BOLA/IDOR). Only GET requests are replayed so
+// we don't modify data as another user. Replays wait until every role finished crawling, until
+// then we don't know which urls the less privileged role can reach itself.
+type Plugin struct {
+	service browserk.PluginServicer
+	client  *http.Client
+
+	lock       *sync.Mutex
+	pending    map[string]*pendingRequest     // GET requests waiting for their response by RequestId
+	accessed   map[string]map[string]struct{} // role -> urls the role requested itself
+	replayed   map[string]struct{}            // role and url pairs that we already queued
+	candidates []*replayCandidate
+}
+
+// New authorization plugin, replays are sent through the configured Proxy like the browsers' requests
+func New(service browserk.PluginServicer, cfg *browserk.Config) *Plugin {
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if cfg != nil && cfg.Proxy != "" {
+		if proxyURL, err := url.Parse(cfg.Proxy); err != nil {
+			log.Warn().Err(err).Str("proxy", cfg.Proxy).Msg("invalid proxy, authorization replays will not use it")
+		} else {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
+	p := &Plugin{
+		service: service,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   time.Second * 30,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		lock:       &sync.Mutex{},
+		pending:    make(map[string]*pendingRequest),
+		accessed:   make(map[string]map[string]struct{}),
+		replayed:   make(map[string]struct{}),
+		candidates: make([]*replayCandidate, 0),
+	}
+	service.Register(p)
+	return p
+}
+
+// Name of the plugin
+func (h *Plugin) Name() string {
+	return "AuthorizationPlugin"
+}
+
+// ID unique to browserker
+func (h *Plugin) ID() string {
+	return "BR-P-0005"
+}
+
+// Config for this plugin
+func (h *Plugin) Config() *browserk.PluginConfig {
+	return nil
+}
+
+func (h *Plugin) InitContext(bctx *browserk.Context) {
+
+}
+
+// Options for the plugin manager to take into consideration when dispatching
+func (h *Plugin) Options() *browserk.PluginOpts {
+	return &browserk.PluginOpts{
+		IsolatedRequests: true,
+		ListenRequests:   true,
+		ListenResponses:  true,
+		ListenCrawl:      true,
+		ExecutionType:    browserk.ExecAlways,
+	}
+}
+
+// Ready to attack
+func (h *Plugin) Ready(injector browserk.Injector) (bool, error) {
+	return false, nil
+}
+
+// OnEvent handles passive events
+func (h *Plugin) OnEvent(evt *browserk.PluginEvent) {
+	if evt.Type == browserk.EvtCrawlComplete {
+		h.replayAll(evt.BCtx)
+		return
+	}
+
+	if evt.BCtx == nil || evt.BCtx.Auth == nil || evt.BCtx.Auth.Role() == nil || len(evt.BCtx.Roles) < 2 {
+		return
+	}
+	role := evt.BCtx.Auth.Role()
+
+	switch evt.Type {
+	case browserk.EvtHTTPRequest:
+		req := evt.Request()
+		if req == nil || req.Request == nil || req.Request.Method != http.MethodGet {
+			return
+		}
+		h.lock.Lock()
+		h.evictPending(time.Now())
+		h.pending[req.RequestId] = &pendingRequest{req: req, added: time.Now()}
+		if _, ok := h.accessed[role.Name]; !ok {
+			h.accessed[role.Name] = make(map[string]struct{})
+		}
+		h.accessed[role.Name][req.Request.Url] = struct{}{}
+		h.lock.Unlock()
+	case browserk.EvtHTTPResponse:
+		resp := evt.Response()
+		if resp == nil || resp.Response == nil || !replayable(resp) {
+			return
+		}
+
+		h.lock.Lock()
+		pending, ok := h.pending[resp.RequestId]
+		delete(h.pending, resp.RequestId)
+		h.lock.Unlock()
+		if !ok {
+			return
+		}
+		req := pending.req
+
+		for _, lower := range evt.BCtx.Roles {
+			if lower.Role().Privilege >= role.Privilege {
+				continue
+			}
+			h.queue(&replayCandidate{nav: evt.Nav, role: role, req: req, resp: resp, lower: lower})
+		}
+	}
+}
+
+// evictPending requests that never got a response, must be called with the lock held
+func (h *Plugin) evictPending(now time.Time) {
+	for requestID, pending := range h.pending {
+		if now.Sub(pending.added) > pendingTimeout {
+			delete(h.pending, requestID)
+		}
+	}
+}
+
+// queue the candidate if we haven't already for this role and url
+func (h *Plugin) queue(candidate *replayCandidate) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	key := candidate.lower.Role().Name + " " + candidate.req.Request.Url
+	if _, ok := h.replayed[key]; ok {
+		return
+	}
+	h.replayed[key] = struct{}{}
+	h.candidates = append(h.candidates, candidate)
+}
+
+// replayAll queued candidates the less privileged role didn't request itself during its crawl (so it's
+// probably allowed to), returning once they are all replayed
+func (h *Plugin) replayAll(bctx *browserk.Context) {
+	h.lock.Lock()
+	replays := make([]*replayCandidate, 0, len(h.candidates))
+	for _, candidate := range h.candidates {
+		if _, ok := h.accessed[candidate.lower.Role().Name][candidate.req.Request.Url]; !ok {
+			replays = append(replays, candidate)
+		}
+	}
+	h.candidates = h.candidates[:0]
+	h.lock.Unlock()
+
+	if len(replays) == 0 {
+		return
+	}
+	bctx.Log.Info().Int("replays", len(replays)).Msg("replaying requests as less privileged roles")
+
+	replayCh := make(chan *replayCandidate)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < replayWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for candidate := range replayCh {
+				h.replay(bctx, candidate)
+			}
+		}()
+	}
+
+	for _, candidate := range replays {
+		select {
+		case replayCh <- candidate:
+		case <-bctx.Ctx.Done():
+		}
+	}
+	close(replayCh)
+	wg.Wait()
+}
+
+func (h *Plugin) replay(bctx *browserk.Context, candidate *replayCandidate) {
+	req, resp, lower := candidate.req, candidate.resp, candidate.lower
+	ctx, cancel := context.WithTimeout(bctx.Ctx, time.Second*30)
+	defer cancel()
+
+	replayReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.Request.Url, nil)
+	if err != nil {
+		return
+	}
+
+	for name, value := range req.Request.Headers {
+		if strings.EqualFold(name, "cookie") || strings.EqualFold(name, "authorization") {
+			continue
+		}
+		replayReq.Header.Set(name, fmt.Sprintf("%v", value))
+	}
+
+	for name, value := range lower.AuthHeaders() {
+		replayReq.Header.Set(name, fmt.Sprintf("%v", value))
+	}
+
+	if session := lower.Session(); session != nil {
+		for _, cookie := range session.Cookies {
+			if domainMatch(replayReq.URL, cookie.Domain) {
+				replayReq.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+			}
+		}
+	}
+
+	replayResp, err := h.client.Do(replayReq)
+	if err != nil {
+		bctx.Log.Debug().Err(err).Str("url", req.Request.Url).Msg("failed to replay request as another role")
+		return
+	}
+	defer replayResp.Body.Close()
+
+	body, err := ioutil.ReadAll(replayResp.Body)
+	if err != nil {
+		return
+	}
+
+	if replayResp.StatusCode != resp.Response.Status || !bytes.Equal(normalize(body), normalize(resp.Body)) {
+		return
+	}
+
+	role := candidate.role
+	lowerName := lower.Role().Name
+	if lower.Role().Credentials == nil {
+		lowerName += " (anonymous)"
+	}
+
+	report := &browserk.Report{
+		Plugin:      h.Name(),
+		CheckID:     1,
+		CWE:         639,
+		Description: fmt.Sprintf("%s was able to access a resource of the more privileged role %s", lowerName, role.Name),
+		Remediation: "Verify the user is authorized to access the requested object on every request, not just that they are logged in",
+		Severity:    "HIGH",
+		URL:         req.Request.Url,
+		Nav:         candidate.nav,
+		Evidence: browserk.NewUniqueEvidence(
+			fmt.Sprintf("GET %s returned status %d with an equivalent body for roles %s and %s", req.Request.Url, replayResp.StatusCode, role.Name, lower.Role().Name),
+			[]byte(lower.Role().Name+role.Name+req.Request.Url),
+		),
+		Reported: time.Now(),
+	}
+	report.Hash()
+
+	h.service.Store().AddReport(report)
+}
+
+// replayable responses are successful documents or API responses, static files are rarely protected
+func replayable(resp *browserk.HTTPResponse) bool {
+	if resp.Response.Status < 200 || resp.Response.Status >= 300 {
+		return false
+	}
+
+	switch resp.Type {
+	case "", "Document", "XHR", "Fetch":
+		return true
+	}
+	return false
+}
+
+func domainMatch(u *url.URL, domain string) bool {
+	domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+	host := strings.ToLower(u.Hostname())
+	return domain == "" || host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// normalize the body so dynamic values don't cause equivalent responses to differ
+func normalize(body []byte) []byte {
+	body = tokenRe.ReplaceAll(body, []byte("T"))
+	body = digitsRe.ReplaceAll(body, []byte("0"))
+	return bytes.TrimSpace(whitespaceRe.ReplaceAll(body, []byte(" ")))
+}
@@ -0,0 +1,153 @@
+package authz_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/wirepair/gcd/v2/gcdapi"
+	"gitlab.com/browserker/browserk"
+	"gitlab.com/browserker/mock"
+	"gitlab.com/browserker/scanner/plugin/authz"
+)
+
+// sendRequest as the role of bctx, with the response body
+func sendRequest(plug *authz.Plugin, bctx *browserk.Context, requestID, reqURL string, body []byte) {
+	nav := browserk.NewNavigation(browserk.TrigCrawler, browserk.NewLoadURLAction(reqURL))
+	nav.SetRole(bctx.Auth.Role().Name)
+
+	req := &browserk.HTTPRequest{
+		RequestId: requestID,
+		Type:      "Document",
+		Request:   &gcdapi.NetworkRequest{Url: reqURL, Method: "GET", Headers: map[string]interface{}{"Cookie": "session=" + bctx.Auth.Role().Name}},
+	}
+	plug.OnEvent(browserk.HTTPRequestPluginEvent(bctx, req.Request.Url, nav, req))
+
+	httpResp := &browserk.HTTPResponse{
+		RequestId: requestID,
+		Type:      "Document",
+		Response:  &gcdapi.NetworkResponse{Url: reqURL, Status: 200},
+		Body:      body,
+	}
+	plug.OnEvent(browserk.HTTPResponsePluginEvent(bctx, httpResp.Response.Url, nav, httpResp))
+}
+
+func makeServicer(reports chan *browserk.Report) *mock.PluginServicer {
+	store := mock.MakeMockPluginStore()
+	store.AddReportFn = func(report *browserk.Report) {
+		reports <- report
+	}
+	servicer := mock.MakeMockPluginServicer()
+	servicer.StoreFn = func() browserk.PluginStorer { return store }
+	return servicer
+}
+
+func TestAuthorization(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, _ := r.Cookie("session")
+		switch r.URL.Path {
+		case "/admin/users":
+			if session == nil || session.Value != "admin" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`<ul><li>admin</li><li>user</li></ul>`))
+		case "/invoices/1":
+			// only checks that we are logged in, not who we are
+			if session == nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("<p>Invoice 1 generated at " + time.Now().Format(time.RFC3339) + "</p>"))
+		case "/profile":
+			if session == nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("<p>Profile</p>"))
+		}
+	}))
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	admin := mock.MakeMockAuthService(&browserk.Role{Name: "admin", Privilege: 10, Credentials: &browserk.Credentials{}}, []*browserk.Cookie{{Name: "session", Value: "admin", Domain: target.Hostname()}})
+	user := mock.MakeMockAuthService(&browserk.Role{Name: "user", Privilege: 5, Credentials: &browserk.Credentials{}}, []*browserk.Cookie{{Name: "session", Value: "user", Domain: target.Hostname()}})
+	anonymous := mock.MakeMockAuthService(&browserk.Role{Name: "anonymous"}, nil)
+
+	adminCtx := mock.MakeMockContext(context.Background(), target)
+	adminCtx.Auth = admin
+	adminCtx.Roles = []browserk.AuthService{admin, user, anonymous}
+
+	reports := make(chan *browserk.Report, 10)
+	servicer := makeServicer(reports)
+	adminCtx.PluginServicer = servicer
+
+	plug := authz.New(servicer, &browserk.Config{})
+	sendRequest(plug, adminCtx, "a", srv.URL+"/admin/users", []byte(`<ul><li>admin</li><li>user</li></ul>`))
+	sendRequest(plug, adminCtx, "b", srv.URL+"/invoices/1", []byte("<p>Invoice 1 generated at "+time.Now().Add(-time.Hour).Format(time.RFC3339)+"</p>"))
+	sendRequest(plug, adminCtx, "c", srv.URL+"/profile", []byte("<p>Profile</p>"))
+
+	// the user's crawl reaches its own profile after the admin's
+	userCtx := adminCtx.Copy()
+	userCtx.Log = adminCtx.Log
+	userCtx.Auth = user
+	sendRequest(plug, userCtx, "d", srv.URL+"/profile", []byte("<p>Profile</p>"))
+
+	select {
+	case report := <-reports:
+		t.Fatalf("expected no replays before the crawl is complete got %s\n", report.URL)
+	case <-time.After(time.Millisecond * 200):
+	}
+
+	servicer.CrawlComplete(adminCtx)
+	if len(reports) != 1 {
+		t.Fatalf("expected only the invoice to be reported got %d reports\n", len(reports))
+	}
+
+	report := <-reports
+	if report.URL != srv.URL+"/invoices/1" || report.CWE != 639 || report.Nav == nil {
+		t.Fatalf("expected the invoice to be reported got %s %d\n", report.URL, report.CWE)
+	}
+
+	// replays are only sent once
+	servicer.CrawlComplete(adminCtx)
+	if len(reports) != 0 {
+		t.Fatalf("expected no more reports got %d\n", len(reports))
+	}
+}
+
+func TestAuthorizationProxy(t *testing.T) {
+	proxied := make(chan string, 10)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied <- r.URL.String()
+		w.Write([]byte("<p>Invoice 1</p>"))
+	}))
+	defer proxy.Close()
+
+	target, _ := url.Parse("http://app.invalid")
+	admin := mock.MakeMockAuthService(&browserk.Role{Name: "admin", Privilege: 10, Credentials: &browserk.Credentials{}}, nil)
+	user := mock.MakeMockAuthService(&browserk.Role{Name: "user", Privilege: 5, Credentials: &browserk.Credentials{}}, nil)
+
+	bctx := mock.MakeMockContext(context.Background(), target)
+	bctx.Auth = admin
+	bctx.Roles = []browserk.AuthService{admin, user}
+
+	reports := make(chan *browserk.Report, 10)
+	servicer := makeServicer(reports)
+	bctx.PluginServicer = servicer
+
+	plug := authz.New(servicer, &browserk.Config{Proxy: proxy.URL})
+	sendRequest(plug, bctx, "a", "http://app.invalid/invoices/1", []byte("<p>Invoice 1</p>"))
+	servicer.CrawlComplete(bctx)
+
+	if len(proxied) != 1 || <-proxied != "http://app.invalid/invoices/1" {
+		t.Fatalf("expected the replay to be sent through the proxy\n")
+	}
+
+	if len(reports) != 1 {
+		t.Fatalf("expected the invoice to be reported got %d reports\n", len(reports))
+	}
+}
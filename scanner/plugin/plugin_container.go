@@ -79,6 +79,8 @@ func (c *Container) Call(evt *browserk.PluginEvent) {
 			plugin.OnEvent(evt)
 		} else if evt.Type == browserk.EvtConsole && plugin.Options().ListenConsole {
 			plugin.OnEvent(evt)
+		} else if evt.Type == browserk.EvtCrawlComplete && plugin.Options().ListenCrawl {
+			plugin.OnEvent(evt)
 		}
 	}
 }
@@ -13,6 +13,7 @@ import (
 	"gitlab.com/browserker/scanner/plugin/active/lfi"
 	"gitlab.com/browserker/scanner/plugin/active/oscmd"
 	"gitlab.com/browserker/scanner/plugin/active/sqli"
+	"gitlab.com/browserker/scanner/plugin/authz"
 	"gitlab.com/browserker/scanner/plugin/cookies"
 	"gitlab.com/browserker/scanner/plugin/headers"
 	"gitlab.com/browserker/scanner/plugin/storage"
@@ -130,6 +131,15 @@ func (s *Service) DispatchEvent(evt *browserk.PluginEvent) {
 	}
 }
 
+// CrawlComplete notifies the plugins listening for it that every role finished crawling. Unlike DispatchEvent
+// it returns once the plugins handled the event, so their work is done before the attack phase starts.
+func (s *Service) CrawlComplete(bctx *browserk.Context) {
+	evt := browserk.CrawlCompletePluginEvent(bctx)
+	for _, plugins := range []*Container{s.hostPlugins, s.pathPlugins, s.filePlugins, s.pagePlugins, s.urlPlugins, s.requestPlugins, s.responsePlugins, s.alwaysPlugins} {
+		plugins.Call(evt)
+	}
+}
+
 func (s *Service) listenForEvents() {
 	for {
 		select {
@@ -210,6 +220,7 @@ func (s *Service) importPlugins() {
 	s.Register(headers.NewPerPathHeader(s))
 	s.Register(headers.NewPerFileHeader(s))
 	s.Register(storage.New(s))
+	s.Register(authz.New(s, s.cfg))
 	s.Register(sqli.New(s))
 	s.Register(oscmd.New(s))
 	s.Register(lfi.New(s))
@@ -3,7 +3,6 @@ package scanner
 import (
 	"context"
 	"fmt"
-	"net/url"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -39,7 +38,7 @@ func NewReplayer(cfg *browserk.Config, crawl browserk.CrawlGrapher, navID []byte
 
 // Init the browsers and stores
 func (b *Replayer) Init(ctx context.Context) error {
-	target, err := url.Parse(b.cfg.URL)
+	scope, err := NewScopeFromConfig(b.cfg)
 	if err != nil {
 		return err
 	}
@@ -49,8 +48,14 @@ func (b *Replayer) Init(ctx context.Context) error {
 
 	pluginService := mock.MakeMockPluginServicer()
 
-	b.mainContext.Auth = auth.New(b.cfg)
-	b.mainContext.Scope = b.scopeService(target)
+	authService := auth.New(b.cfg)
+	if err := authService.Init(); err != nil {
+		return err
+	}
+
+	b.mainContext.Auth = authService
+	b.mainContext.AddReqHandler(authService.AddAuthHeader)
+	b.mainContext.Scope = scope
 	b.mainContext.FormHandler = crawler.NewCrawlerFormHandler(b.cfg.FormData)
 	b.mainContext.Crawl = b.crawlGraph
 	b.mainContext.PluginServicer = pluginService
@@ -68,21 +73,6 @@ func (b *Replayer) Init(ctx context.Context) error {
 	return pool.Init()
 }
 
-func (b *Replayer) scopeService(target *url.URL) browserk.ScopeService {
-	allowed := b.cfg.AllowedHosts
-	ignored := b.cfg.IgnoredHosts
-	excluded := b.cfg.ExcludedHosts
-
-	scope := NewScopeService(target)
-	scope.AddScope(allowed, browserk.InScope)
-	scope.AddScope(ignored, browserk.OutOfScope)
-	scope.AddScope(excluded, browserk.ExcludedFromScope)
-	if b.cfg.ExcludedURIs != nil {
-		scope.AddExcludedURIs(b.cfg.ExcludedURIs)
-	}
-	return scope
-}
-
 // Start the browsers
 func (b *Replayer) Start() error {
 	navs := b.crawlGraph.FindPathByNavID(b.mainContext.Ctx, b.navID)
@@ -96,6 +86,13 @@ func (b *Replayer) Start() error {
 		return err
 	}
 
+	if navCtx.Auth.MustLogin() {
+		navCtx.Log = &log.Logger
+		if err := navCtx.Auth.Login(navCtx, browser); err != nil {
+			log.Warn().Err(err).Msg("failed to login, replaying unauthenticated")
+		}
+	}
+
 	crawler := crawler.New(b.cfg)
 	if err := crawler.Init(); err != nil {
 		b.browsers.Return(navCtx.Ctx, port)
@@ -149,6 +146,10 @@ func (b *Replayer) Stop() error {
 		log.Warn().Err(err).Msg("failed to close browsers")
 	}
 
+	if err := browser.RemoveFixtures(); err != nil {
+		log.Warn().Err(err).Msg("failed to remove upload fixtures")
+	}
+
 	log.Info().Msg("Closing crawl graph")
 	err = b.crawlGraph.Close()
 	if err != nil {
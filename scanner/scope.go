@@ -1,70 +1,402 @@
 package scanner
 
 import (
+	"context"
+	"net"
 	"net/url"
+	"path"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"gitlab.com/browserker/browserk"
 )
 
+// Priorities of the rules created from AllowedHosts/IgnoredHosts/ExcludedHosts/ExcludedURIs, excluded
+// hosts take precedence over ignored hosts, which take precedence over excluded URIs.
+const (
+	allowedHostPriority  = 0
+	excludedURIPriority  = 10
+	ignoredHostPriority  = 20
+	excludedHostPriority = 30
+)
+
+// resolveTimeout for looking up a hostname when matching it against IP/CIDR rules
+const resolveTimeout = time.Second * 2
+
+// lookupFn returns the IP addresses of a hostname
+type lookupFn func(host string) []net.IP
+
+// scopeRule is a compiled browserk.ScopeRule
+type scopeRule struct {
+	scope    browserk.Scope
+	priority int
+	scheme   string
+	host     string
+	glob     bool
+	domain   string     // .corp.local matches corp.local and all of its subdomains
+	network  *net.IPNet // CIDR or single IP
+	port     string
+	path     *regexp.Regexp
+	query    map[string]*regexp.Regexp
+}
+
+func compileRule(rule *browserk.ScopeRule) (*scopeRule, error) {
+	r := &scopeRule{
+		scope:    rule.Scope,
+		priority: rule.Priority,
+		scheme:   strings.ToLower(rule.Scheme),
+		host:     strings.ToLower(rule.Host),
+		port:     rule.Port,
+	}
+
+	if strings.Contains(r.host, "/") {
+		_, network, err := net.ParseCIDR(r.host)
+		if err != nil {
+			return nil, err
+		}
+		r.network = network
+		r.host = ""
+	}
+
+	// allow localhost:8080 as the host, but not ipv6 addresses
+	if strings.Count(r.host, ":") == 1 {
+		host, port, err := net.SplitHostPort(r.host)
+		if err != nil {
+			return nil, err
+		}
+		r.host = host
+		if r.port == "" {
+			r.port = port
+		}
+	}
+	r.host = strings.TrimSuffix(strings.TrimPrefix(r.host, "["), "]")
+	r.glob = strings.ContainsAny(r.host, "*?[")
+
+	if ip := net.ParseIP(r.host); ip != nil {
+		bits := net.IPv6len * 8
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, net.IPv4len*8
+		}
+		r.network = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		r.host = ""
+	} else if strings.HasPrefix(r.host, ".") {
+		r.domain = r.host
+		r.host = ""
+	}
+
+	if r.glob {
+		if _, err := path.Match(r.host, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Path != "" {
+		re, err := regexp.Compile(rule.Path)
+		if err != nil {
+			return nil, err
+		}
+		r.path = re
+	}
+
+	if len(rule.Query) > 0 {
+		r.query = make(map[string]*regexp.Regexp, len(rule.Query))
+		for name, value := range rule.Query {
+			if value == "" {
+				r.query[name] = nil
+				continue
+			}
+
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			r.query[name] = re
+		}
+	}
+	return r, nil
+}
+
+// matches returns true if every condition of the rule matches the url, lookup is used to
+// resolve hostnames for IP/CIDR rules and may be nil
+func (r *scopeRule) matches(u *url.URL, lookup lookupFn) bool {
+	if r.scheme != "" && r.scheme != strings.ToLower(u.Scheme) {
+		return false
+	}
+
+	if !r.matchesHost(strings.ToLower(u.Hostname()), lookup) {
+		return false
+	}
+
+	if r.port != "" && r.port != urlPort(u) {
+		return false
+	}
+
+	if r.path != nil && !r.path.MatchString(u.Path) {
+		return false
+	}
+
+	if r.query != nil {
+		values := u.Query()
+		for name, re := range r.query {
+			params, ok := values[name]
+			if !ok {
+				return false
+			}
+
+			if re != nil && !anyMatch(re, params) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r *scopeRule) matchesHost(host string, lookup lookupFn) bool {
+	switch {
+	case r.network != nil:
+		ips := []net.IP{net.ParseIP(host)}
+		if ips[0] == nil {
+			if lookup == nil {
+				return false
+			}
+			ips = lookup(host)
+		}
+
+		for _, ip := range ips {
+			if r.network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	case r.domain != "":
+		return host == r.domain[1:] || strings.HasSuffix(host, r.domain)
+	case r.glob:
+		matched, _ := path.Match(r.host, host)
+		return matched
+	case r.host != "":
+		return r.host == host
+	}
+	return true
+}
+
+func anyMatch(re *regexp.Regexp, values []string) bool {
+	for _, value := range values {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// urlPort returns the explicit port or the scheme's default port
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "ws":
+		return "80"
+	case "https", "wss":
+		return "443"
+	}
+	return ""
+}
+
 // ScopeService is used to ensure we stay with in the scope
 // of the target as we scan
-// TODO: make this better (support for schemes/params etc)
 type ScopeService struct {
-	target       *url.URL
-	allowed      []string
-	ignored      []string
-	excluded     []string
-	excludedURIs []string // todo make regex
+	target        *url.URL
+	rules         []*scopeRule // sorted by precedence
+	excludedForms []string
+	elementRules  []*elementRule
+
+	resolve     bool
+	resolveLock *sync.Mutex
+	resolved    map[string][]net.IP
 }
 
-// NewScopeService set the target url for easier matching
-// TODO: allow ports as well
+// NewScopeService set the target url for easier matching. Only the target's port is in scope, other apps
+// on the same host are not unless rules add them. A target without a port allows both default ports as
+// sites commonly redirect from http to https.
 func NewScopeService(target *url.URL) *ScopeService {
 	s := &ScopeService{
-		target:       target,
-		allowed:      make([]string, 0),
-		ignored:      make([]string, 0),
-		excluded:     make([]string, 0),
-		excludedURIs: make([]string, 0),
+		target:      target,
+		rules:       make([]*scopeRule, 0),
+		resolveLock: &sync.Mutex{},
+		resolved:    make(map[string][]net.IP),
+	}
+
+	ports := []string{target.Port()}
+	if ports[0] == "" {
+		ports = []string{"80", "443"}
+	}
+
+	rules := make([]*browserk.ScopeRule, 0, len(ports))
+	for _, port := range ports {
+		rules = append(rules, &browserk.ScopeRule{Scope: browserk.InScope, Priority: allowedHostPriority, Host: target.Hostname(), Port: port})
+	}
+
+	if err := s.AddRules(rules); err != nil {
+		log.Warn().Err(err).Msg("failed to add target to scope")
 	}
-	s.AddScope([]string{target.Hostname()}, browserk.InScope)
 	return s
 }
 
-// AddScope to the scope service
-func (s *ScopeService) AddScope(inputs []string, scope browserk.Scope) {
+// NewScopeFromConfig creates the scope service for the config's URL with the configured hosts, excluded
+// URIs, rules, forms and elements. Invalid rules are logged and ignored so they don't stop the scan.
+func NewScopeFromConfig(cfg *browserk.Config) (*ScopeService, error) {
+	target, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
 
-	if inputs == nil || len(inputs) == 0 {
-		return
+	scope := NewScopeService(target)
+	scope.ResolveHostnames(cfg.ResolveHosts)
+	scope.AddScope(cfg.AllowedHosts, browserk.InScope)
+	scope.AddScope(cfg.IgnoredHosts, browserk.OutOfScope)
+	scope.AddScope(cfg.ExcludedHosts, browserk.ExcludedFromScope)
+	if cfg.ExcludedURIs != nil {
+		scope.AddExcludedURIs(cfg.ExcludedURIs)
+	}
+	if err := scope.AddRules(cfg.ScopeRules); err != nil {
+		log.Error().Err(err).Msg("invalid scope rule, ignoring ScopeRules")
 	}
-	lowered := mapFunction(inputs, strings.ToLower)
+	scope.ExcludeForms(cfg.ExcludedForms)
+	if err := scope.ExcludeElements(cfg.ExcludedElements); err != nil {
+		log.Error().Err(err).Msg("invalid element rule, ignoring ExcludedElements")
+	}
+	return scope, nil
+}
 
+// AddScope for hosts to the scope service, hosts may be globs (*.example.com), domains including
+// their subdomains (.example.com), IPs or CIDRs (10.0.0.0/8), include a port (localhost:8080) or be
+// a url (https://example.com:8443)
+func (s *ScopeService) AddScope(inputs []string, scope browserk.Scope) {
+	priority := allowedHostPriority
 	switch scope {
-	case browserk.InScope:
-		s.allowed = append(s.allowed, lowered...)
 	case browserk.OutOfScope:
-		s.ignored = append(s.ignored, lowered...)
+		priority = ignoredHostPriority
 	case browserk.ExcludedFromScope:
-		s.excluded = append(s.excluded, lowered...)
+		priority = excludedHostPriority
 	}
-}
 
-// AddExcludedURIs so we don't logout or whatever
-// TODO: allow ability to add query params as well
-func (s *ScopeService) AddExcludedURIs(inputs []string) {
+	rules := make([]*browserk.ScopeRule, 0, len(inputs))
 	for _, input := range inputs {
-		if strings.HasPrefix(input, "http") {
+		input = strings.TrimSpace(input)
+		rule := &browserk.ScopeRule{Scope: scope, Priority: priority, Host: input}
+		if strings.Contains(input, "://") {
 			u, err := url.Parse(input)
 			if err != nil {
-				log.Warn().Err(err).Msg("failed to add URI to exclusion list")
+				log.Warn().Err(err).Str("host", input).Msg("failed to add host to scope")
 				continue
 			}
-			s.excludedURIs = append(s.excludedURIs, strings.ToLower(u.Path))
-		} else {
-			s.excludedURIs = append(s.excludedURIs, strings.ToLower(input))
+			rule.Scheme = u.Scheme
+			rule.Host = u.Host
+		}
+
+		// a rule without a host would match every host, blank config entries/flags are not meant to
+		if rule.Host == "" {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := s.AddRules(rules); err != nil {
+		log.Warn().Err(err).Msg("failed to add hosts to scope")
+	}
+}
+
+// ResolveHostnames through the system resolver so hostnames can match IP/CIDR rules, lookups are cached
+// for the lifetime of the scope service
+func (s *ScopeService) ResolveHostnames(enabled bool) {
+	s.resolve = enabled
+}
+
+// lookup the IP addresses of the host, failed lookups are cached as well
+func (s *ScopeService) lookup(host string) []net.IP {
+	s.resolveLock.Lock()
+	ips, ok := s.resolved[host]
+	s.resolveLock.Unlock()
+	if ok {
+		return ips
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		log.Debug().Err(err).Str("host", host).Msg("failed to resolve host for scope check")
+	}
+
+	ips = make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+
+	s.resolveLock.Lock()
+	s.resolved[host] = ips
+	s.resolveLock.Unlock()
+	return ips
+}
+
+// AddRules to the scope service, no rules are added if any of them are invalid
+func (s *ScopeService) AddRules(rules []*browserk.ScopeRule) error {
+	compiled := make([]*scopeRule, 0, len(rules))
+	for _, rule := range rules {
+		r, err := compileRule(rule)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, r)
+	}
+
+	s.rules = append(s.rules, compiled...)
+	sort.SliceStable(s.rules, func(i, j int) bool {
+		if s.rules[i].priority != s.rules[j].priority {
+			return s.rules[i].priority > s.rules[j].priority
+		}
+		// the more restrictive scope wins
+		return s.rules[i].scope > s.rules[j].scope
+	})
+	return nil
+}
+
+// AddExcludedURIs so we don't logout or whatever. The path must match exactly, query
+// parameters (/login?logout or /user?action=logout) must also be present in the url.
+func (s *ScopeService) AddExcludedURIs(inputs []string) {
+	rules := make([]*browserk.ScopeRule, 0, len(inputs))
+	for _, input := range inputs {
+		u, err := url.Parse(input)
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to add URI to exclusion list")
+			continue
+		}
+
+		rule := &browserk.ScopeRule{
+			Scope:    browserk.ExcludedFromScope,
+			Priority: excludedURIPriority,
+			Path:     "(?i)^" + regexp.QuoteMeta(u.Path) + "$",
+		}
+
+		if u.RawQuery != "" {
+			rule.Query = make(map[string]string)
+			for name, values := range u.Query() {
+				rule.Query[name] = ""
+				if values[0] != "" {
+					rule.Query[name] = "^" + regexp.QuoteMeta(values[0]) + "$"
+				}
+			}
 		}
+		rules = append(rules, rule)
+	}
+
+	if err := s.AddRules(rules); err != nil {
+		log.Warn().Err(err).Msg("failed to add URIs to exclusion list")
 	}
 }
 
@@ -79,88 +411,66 @@ func (s *ScopeService) GetTargetHost() *url.URL {
 	return targetHost
 }
 
-// Check a url to see if it's in scope
+// Check a url to see if it's in scope, relative urls are resolved against the target
 func (s *ScopeService) Check(target *url.URL) browserk.Scope {
-	host := s.target.Hostname()
-	if target.Host != "" && target.Host != s.target.Host {
-		return s.CheckRelative(target.Host, target.Path)
+	if target == nil {
+		return browserk.OutOfScope
 	}
-	return s.CheckRelative(host, target.Path)
+
+	if target.Host == "" {
+		target = s.target.ResolveReference(target)
+	}
+	return s.scopeOf(target)
 }
 
 // CheckURL an unparsed url to see if it's in scope
 func (s *ScopeService) CheckURL(targetURL string) browserk.Scope {
-	uri, _ := url.Parse(targetURL)
+	uri, err := url.Parse(targetURL)
+	if err != nil {
+		return browserk.OutOfScope
+	}
 	return s.Check(uri)
 }
 
 // ResolveBaseHref for html document links
 func (s *ScopeService) ResolveBaseHref(baseHref, candidate string) browserk.Scope {
-	var scope browserk.Scope
-
 	u, err := url.Parse(candidate)
 	if err != nil {
 		return browserk.OutOfScope
 	}
 
-	if strings.HasPrefix(candidate, "http") {
-		scope = s.Check(u)
-	} else {
-		base := s.target
-		if baseHref != "" {
-			base, err = url.Parse(baseHref)
-			if err != nil {
-				base = s.target
-			}
-			if base.Host != u.Host {
-				log.Info().Msgf("%s != %s\n", base.Host, u.Host)
-			}
-		}
-		scope = s.Check(base.ResolveReference(u))
+	if u.IsAbs() || baseHref == "" {
+		return s.Check(u)
+	}
+
+	base, err := url.Parse(baseHref)
+	if err != nil {
+		base = s.target
 	}
-	return scope
+	return s.Check(base.ResolveReference(u))
 }
 
-// CheckRelative hosts to see if it's in scope
-// First we check if excluded, then we check if it's ignored,
-// then we check if the uri is excluded and finally if it's allowed
-// default to out of scope
+// CheckRelative url (path and query) of the host (which may include a port) to see if it's in scope
 func (s *ScopeService) CheckRelative(host, relative string) browserk.Scope {
-	if includeFunction(s.excluded, host) {
-		return browserk.ExcludedFromScope
-	} else if includeFunction(s.ignored, host) {
+	u, err := url.Parse(relative)
+	if err != nil {
 		return browserk.OutOfScope
-	} else if includeFunction(s.excludedURIs, relative) {
-		return browserk.ExcludedFromScope
-	} else if includeFunction(s.allowed, host) {
-		return browserk.InScope
 	}
-	return browserk.OutOfScope
-}
-
-// ExcludeForms based on name or id for html element
-func (s *ScopeService) ExcludeForms(idsOrNames []string) {
-	// TODO IMPLEMENT
+	base := &url.URL{Scheme: s.target.Scheme, Host: host, Path: "/"}
+	return s.scopeOf(base.ResolveReference(u))
 }
 
-func mapFunction(vs []string, f func(string) string) []string {
-	vsm := make([]string, len(vs))
-	for i, v := range vs {
-		vsm[i] = f(v)
+// scopeOf the first matching rule, defaults to out of scope
+func (s *ScopeService) scopeOf(u *url.URL) browserk.Scope {
+	var lookup lookupFn
+	if s.resolve {
+		lookup = s.lookup
 	}
-	return vsm
-}
 
-func indexFunction(vs []string, t string) int {
-	for i, v := range vs {
-		if v == t {
-			return i
+	for _, rule := range s.rules {
+		if rule.matches(u, lookup) {
+			return rule.scope
 		}
 	}
-	return -1
-}
-
-// TODO: do regex matching
-func includeFunction(vs []string, t string) bool {
-	return indexFunction(vs, t) >= 0
+	return browserk.OutOfScope
 }
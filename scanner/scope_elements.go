@@ -0,0 +1,165 @@
+package scanner
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gitlab.com/browserker/browserk"
+)
+
+// elementRule is a compiled browserk.ElementRule
+type elementRule struct {
+	name       string
+	selector   string
+	text       *regexp.Regexp
+	attributes map[string]*regexp.Regexp
+}
+
+func compileElementRule(rule *browserk.ElementRule) (*elementRule, error) {
+	r := &elementRule{name: rule.Name, selector: rule.Selector}
+	if rule.Selector == "" && rule.Text == "" && len(rule.Attributes) == 0 {
+		return nil, fmt.Errorf("element rule %s has nothing to match", rule.Name)
+	}
+
+	if rule.Text != "" {
+		re, err := regexp.Compile(rule.Text)
+		if err != nil {
+			return nil, err
+		}
+		r.text = re
+	}
+
+	if len(rule.Attributes) > 0 {
+		r.attributes = make(map[string]*regexp.Regexp, len(rule.Attributes))
+		for name, value := range rule.Attributes {
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, err
+			}
+			r.attributes[strings.ToLower(name)] = re
+		}
+	}
+
+	if r.name == "" {
+		r.name = describeElementRule(rule)
+	}
+	return r, nil
+}
+
+// describeElementRule for the report if the rule wasn't named
+func describeElementRule(rule *browserk.ElementRule) string {
+	parts := make([]string, 0)
+	if rule.Selector != "" {
+		parts = append(parts, fmt.Sprintf("selector %q", rule.Selector))
+	}
+
+	if rule.Text != "" {
+		parts = append(parts, fmt.Sprintf("text %q", rule.Text))
+	}
+
+	names := make([]string, 0, len(rule.Attributes))
+	for name := range rule.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s %q", name, rule.Attributes[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matches returns true if every condition of the rule matches. selectors are the CSS selectors
+// the browser found the element with.
+func (r *elementRule) matches(text string, attributes map[string]string, selectors []string) bool {
+	if r.selector != "" && !containsString(selectors, r.selector) {
+		return false
+	}
+
+	if r.text != nil && !r.text.MatchString(text) && !r.text.MatchString(attributes["value"]) {
+		return false
+	}
+
+	for name, re := range r.attributes {
+		value, ok := attributes[name]
+		if !ok || !re.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludeForms based on name or id for html element
+func (s *ScopeService) ExcludeForms(idsOrNames []string) {
+	s.excludedForms = append(s.excludedForms, idsOrNames...)
+}
+
+// ExcludeElements matching the rules, no rules are added if any of them are invalid
+func (s *ScopeService) ExcludeElements(rules []*browserk.ElementRule) error {
+	compiled := make([]*elementRule, 0, len(rules))
+	for _, rule := range rules {
+		r, err := compileElementRule(rule)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, r)
+	}
+	s.elementRules = append(s.elementRules, compiled...)
+	return nil
+}
+
+// ExcludedSelectors returns the CSS selectors of the element rules, the crawler looks them up in the
+// browser and passes the matches to ExcludedElement/ExcludedForm
+func (s *ScopeService) ExcludedSelectors() []string {
+	selectors := make([]string, 0)
+	for _, rule := range s.elementRules {
+		if rule.selector != "" && !containsString(selectors, rule.selector) {
+			selectors = append(selectors, rule.selector)
+		}
+	}
+	return selectors
+}
+
+// ExcludedElement returns the name of the rule the element matched, or an empty string. selected is
+// keyed by element hash and contains the selectors the element was found with.
+func (s *ScopeService) ExcludedElement(ele *browserk.HTMLElement, selected map[string][]string) string {
+	for _, rule := range s.elementRules {
+		if rule.matches(ele.InnerText, ele.Attributes, selected[string(ele.Hash())]) {
+			return "ExcludedElements: " + rule.name
+		}
+	}
+	return ""
+}
+
+// ExcludedForm returns the rule the form matched, or an empty string. Forms are excluded by their id
+// or name (ExcludedForms), or if the form or any of its elements match an element rule.
+func (s *ScopeService) ExcludedForm(form *browserk.HTMLFormElement, selected map[string][]string) string {
+	for _, idOrName := range s.excludedForms {
+		if strings.EqualFold(form.GetAttribute("id"), idOrName) || strings.EqualFold(form.GetAttribute("name"), idOrName) {
+			return "ExcludedForms: " + idOrName
+		}
+	}
+
+	for _, rule := range s.elementRules {
+		if rule.matches("", form.Attributes, selected[string(form.Hash())]) {
+			return "ExcludedElements: " + rule.name
+		}
+	}
+
+	for _, child := range form.ChildElements {
+		if reason := s.ExcludedElement(child, selected); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
@@ -152,3 +152,256 @@ func TestResolveBaseHref(t *testing.T) {
 		}
 	}
 }
+
+func TestScopeRules(t *testing.T) {
+	target, _ := url.Parse("http://app.corp.local:8080/")
+
+	s := scanner.NewScopeService(target)
+	s.AddScope([]string{"*.corp.local"}, browserk.InScope)
+	s.AddScope([]string{"legacy.corp.local:9000"}, browserk.OutOfScope)
+	s.AddExcludedURIs([]string{"/WebGoat/login?logout", "/user?action=delete"})
+	err := s.AddRules([]*browserk.ScopeRule{
+		{Scope: browserk.ExcludedFromScope, Host: "app.corp.local", Port: "9090"},
+		{Scope: browserk.ExcludedFromScope, Scheme: "http", Host: "pay.corp.local"},
+		{Scope: browserk.ExcludedFromScope, Path: `^/admin/.*\.php$`},
+		{Scope: browserk.OutOfScope, Path: "^/api/", Query: map[string]string{"debug": "^(1|true)$"}},
+		// higher priority overrides the excluded admin rule
+		{Scope: browserk.InScope, Priority: 50, Host: "app.corp.local", Path: "^/admin/index.php$"},
+	})
+	if err != nil {
+		t.Fatalf("error adding rules: %s\n", err)
+	}
+
+	var inputs = []struct {
+		in       string
+		expected browserk.Scope
+	}{
+		{"http://app.corp.local:8080/", browserk.InScope},
+		{"http://app.corp.local:3000/", browserk.InScope}, // widened by *.corp.local
+		{"http://app.corp.local:9090/", browserk.ExcludedFromScope},
+		{"http://api.corp.local/v1", browserk.InScope},
+		{"http://a.b.corp.local/", browserk.InScope},
+		{"http://corp.local/", browserk.OutOfScope},
+		{"http://evilcorp.local/", browserk.OutOfScope},
+		{"http://legacy.corp.local:9000/", browserk.OutOfScope},
+		{"http://legacy.corp.local/", browserk.InScope},
+		{"http://pay.corp.local/", browserk.ExcludedFromScope},
+		{"https://pay.corp.local/", browserk.InScope},
+		{"http://app.corp.local:8080/admin/users.php", browserk.ExcludedFromScope},
+		{"http://app.corp.local:8080/admin/index.php", browserk.InScope},
+		{"http://app.corp.local:8080/api/users?debug=1", browserk.OutOfScope},
+		{"http://app.corp.local:8080/api/users?debug=0", browserk.InScope},
+		{"http://app.corp.local:8080/WebGoat/login?logout", browserk.ExcludedFromScope},
+		{"http://app.corp.local:8080/WebGoat/login", browserk.InScope},
+		{"http://app.corp.local:8080/user?action=delete&id=1", browserk.ExcludedFromScope},
+		{"http://app.corp.local:8080/user?action=view", browserk.InScope},
+		{"/admin/users.php", browserk.ExcludedFromScope},
+	}
+
+	for _, in := range inputs {
+		if ret := s.CheckURL(in.in); ret != in.expected {
+			t.Fatalf("%v did not match %v for %s\n", ret, in.expected, in.in)
+		}
+
+		u, _ := url.Parse(in.in)
+		if u.Host == "" {
+			continue
+		}
+
+		// CheckRelative uses the target's scheme
+		if ret := s.CheckRelative(u.Host, u.RequestURI()); u.Scheme == target.Scheme && ret != in.expected {
+			t.Fatalf("%v did not match CheckRelative %v for %s\n", ret, in.expected, in.in)
+		}
+
+		if ret := s.ResolveBaseHref("http://other.local/", in.in); ret != in.expected {
+			t.Fatalf("%v did not match ResolveBaseHref %v for %s\n", ret, in.expected, in.in)
+		}
+	}
+
+	if ret := s.ResolveBaseHref("http://app.corp.local:9090/", "/index.php"); ret != browserk.ExcludedFromScope {
+		t.Fatalf("expected base href port to be used got %v\n", ret)
+	}
+
+	if err := s.AddRules([]*browserk.ScopeRule{{Scope: browserk.InScope, Path: "("}}); err == nil {
+		t.Fatalf("expected invalid path regex to fail\n")
+	}
+
+	// blank entries from the config/flags must not match every host
+	s.AddScope([]string{"", " ", "http://"}, browserk.ExcludedFromScope)
+	if ret := s.CheckURL("http://app.corp.local:8080/"); ret != browserk.InScope {
+		t.Fatalf("expected blank excluded hosts to be ignored got %v\n", ret)
+	}
+}
+
+func TestScopeNetworks(t *testing.T) {
+	target, _ := url.Parse("http://app.corp.local/")
+
+	s := scanner.NewScopeService(target)
+	s.AddScope([]string{"10.1.0.0/16", "fd00::/8", ".svc.local"}, browserk.InScope)
+	s.AddScope([]string{"10.1.2.3", "127.0.0.0/8"}, browserk.ExcludedFromScope)
+
+	var inputs = []struct {
+		in       string
+		expected browserk.Scope
+	}{
+		{"http://10.1.200.7:8080/", browserk.InScope},
+		{"http://10.2.0.1/", browserk.OutOfScope},
+		{"http://10.1.2.3/", browserk.ExcludedFromScope},
+		{"http://[fd00::1]/", browserk.InScope},
+		{"http://[fe80::1]/", browserk.OutOfScope},
+		{"http://svc.local/", browserk.InScope},
+		{"http://users.svc.local/", browserk.InScope},
+		{"http://a.b.svc.local/", browserk.InScope},
+		{"http://evilsvc.local/", browserk.OutOfScope},
+		// hostnames are not resolved by default
+		{"http://localhost/", browserk.OutOfScope},
+	}
+
+	for _, in := range inputs {
+		if ret := s.CheckURL(in.in); ret != in.expected {
+			t.Fatalf("%v did not match %v for %s\n", ret, in.expected, in.in)
+		}
+	}
+
+	s.ResolveHostnames(true)
+	if ret := s.CheckURL("http://localhost/"); ret != browserk.ExcludedFromScope {
+		t.Fatalf("expected localhost to resolve into the excluded CIDR got %v\n", ret)
+	}
+
+	if err := s.AddRules([]*browserk.ScopeRule{{Scope: browserk.InScope, Host: "10.0.0.0/33"}}); err == nil {
+		t.Fatalf("expected invalid CIDR to fail\n")
+	}
+}
+
+func TestExcludeElements(t *testing.T) {
+	target, _ := url.Parse("http://example.com")
+	s := scanner.NewScopeService(target)
+	s.ExcludeForms([]string{"delete-account"})
+	err := s.ExcludeElements([]*browserk.ElementRule{
+		{Text: "(?i)reset database"},
+		{Name: "danger buttons", Selector: "button.danger"},
+		{Attributes: map[string]string{"data-action": "^destroy$"}},
+	})
+	if err != nil {
+		t.Fatalf("error adding element rules: %s\n", err)
+	}
+
+	if selectors := s.ExcludedSelectors(); len(selectors) != 1 || selectors[0] != "button.danger" {
+		t.Fatalf("expected only the danger selector got %v\n", selectors)
+	}
+
+	reset := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Reset Database"}
+	danger := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Go", Attributes: map[string]string{"class": "danger"}}
+	destroy := &browserk.HTMLElement{Type: browserk.A, Attributes: map[string]string{"data-action": "destroy", "href": "#"}}
+	safe := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Save", Attributes: map[string]string{"data-action": "destroyer"}}
+	selected := map[string][]string{string(danger.Hash()): {"button.danger"}}
+
+	if rule := s.ExcludedElement(reset, selected); rule != `ExcludedElements: text "(?i)reset database"` {
+		t.Fatalf("expected reset button to match the text rule got %s\n", rule)
+	}
+
+	if rule := s.ExcludedElement(danger, selected); rule != "ExcludedElements: danger buttons" {
+		t.Fatalf("expected danger button to match the selector rule got %s\n", rule)
+	}
+
+	if rule := s.ExcludedElement(destroy, selected); rule != `ExcludedElements: data-action "^destroy$"` {
+		t.Fatalf("expected link to match the attribute rule got %s\n", rule)
+	}
+
+	if rule := s.ExcludedElement(safe, selected); rule != "" {
+		t.Fatalf("expected save button to not be excluded got %s\n", rule)
+	}
+
+	form := &browserk.HTMLFormElement{Type: browserk.FORM, Attributes: map[string]string{"id": "Delete-Account"}}
+	if rule := s.ExcludedForm(form, selected); rule != "ExcludedForms: delete-account" {
+		t.Fatalf("expected form to be excluded by id got %s\n", rule)
+	}
+
+	form = &browserk.HTMLFormElement{Type: browserk.FORM, Attributes: map[string]string{"action": "/admin"}, ChildElements: []*browserk.HTMLElement{reset}}
+	if rule := s.ExcludedForm(form, selected); rule == "" {
+		t.Fatalf("expected form containing the reset button to be excluded\n")
+	}
+
+	form = &browserk.HTMLFormElement{Type: browserk.FORM, Attributes: map[string]string{"action": "/save"}, ChildElements: []*browserk.HTMLElement{safe}}
+	if rule := s.ExcludedForm(form, selected); rule != "" {
+		t.Fatalf("expected form to not be excluded got %s\n", rule)
+	}
+
+	if err := s.ExcludeElements([]*browserk.ElementRule{{Name: "empty"}}); err == nil {
+		t.Fatalf("expected rule without conditions to fail\n")
+	}
+}
+
+func TestScopeTargetPort(t *testing.T) {
+	var tests = []struct {
+		target   string
+		in       string
+		expected browserk.Scope
+	}{
+		{"http://app.corp.local:8080/", "http://app.corp.local:8080/login", browserk.InScope},
+		{"http://app.corp.local:8080/", "https://app.corp.local:8080/login", browserk.InScope},
+		{"http://app.corp.local:8080/", "http://app.corp.local:3000/", browserk.OutOfScope},
+		{"http://app.corp.local:8080/", "http://app.corp.local/", browserk.OutOfScope},
+		{"http://example.com/", "http://example.com/login", browserk.InScope},
+		{"http://example.com/", "https://example.com/login", browserk.InScope},
+		{"http://example.com/", "http://example.com:3000/", browserk.OutOfScope},
+	}
+
+	for _, tt := range tests {
+		target, _ := url.Parse(tt.target)
+		s := scanner.NewScopeService(target)
+		if ret := s.CheckURL(tt.in); ret != tt.expected {
+			t.Fatalf("target %s expected %v for %s got %v\n", tt.target, tt.expected, tt.in, ret)
+		}
+	}
+
+	// other ports of the host can be added
+	target, _ := url.Parse("http://app.corp.local:8080/")
+	s := scanner.NewScopeService(target)
+	s.AddScope([]string{"app.corp.local:3000"}, browserk.InScope)
+	if ret := s.CheckURL("http://app.corp.local:3000/"); ret != browserk.InScope {
+		t.Fatalf("expected the added port to be in scope got %v\n", ret)
+	}
+}
+
+func TestScopeFromConfig(t *testing.T) {
+	cfg := &browserk.Config{
+		URL:              "http://example.com/app/",
+		AllowedHosts:     []string{"api.example.com"},
+		IgnoredHosts:     []string{"cdn.example.com"},
+		ExcludedURIs:     []string{"/app/logout"},
+		ExcludedForms:    []string{"delete-account"},
+		ExcludedElements: []*browserk.ElementRule{{Text: "(?i)sign out"}},
+	}
+
+	s, err := scanner.NewScopeFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("error creating scope: %s\n", err)
+	}
+
+	checks := map[string]browserk.Scope{
+		"http://example.com/app/":       browserk.InScope,
+		"http://api.example.com/v1":     browserk.InScope,
+		"http://cdn.example.com/app.js": browserk.OutOfScope,
+		"http://example.com/app/logout": browserk.ExcludedFromScope,
+	}
+	for u, expected := range checks {
+		if scope := s.CheckURL(u); scope != expected {
+			t.Fatalf("expected %s to be %v got %v\n", u, expected, scope)
+		}
+	}
+
+	form := &browserk.HTMLFormElement{Type: browserk.FORM, Attributes: map[string]string{"name": "delete-account"}}
+	if rule := s.ExcludedForm(form, nil); rule == "" {
+		t.Fatalf("expected the configured form to be excluded\n")
+	}
+
+	signOut := &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Sign out"}
+	if rule := s.ExcludedElement(signOut, nil); rule == "" {
+		t.Fatalf("expected the configured element to be excluded\n")
+	}
+
+	if _, err := scanner.NewScopeFromConfig(&browserk.Config{URL: "http://%zz"}); err == nil {
+		t.Fatalf("expected an invalid url to fail\n")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	badger "github.com/dgraph-io/badger/v2"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
+	"github.com/vmihailenco/msgpack/v4"
 	"gitlab.com/browserker/browserk"
 )
 
@@ -264,6 +265,24 @@ func (g *CrawlGraph) SetNavigationState(navID []byte, state browserk.NavState) e
 	})
 }
 
+// ExcludeNavigation so it won't be crawled or attacked again, recording why
+func (g *CrawlGraph) ExcludeNavigation(navID []byte, reason string) error {
+	return g.GraphStore.Update(func(txn *badger.Txn) error {
+		state, _ := EncodeState(browserk.NavExcluded)
+		if err := txn.Set(MakeKey(navID, "state"), state); err != nil {
+			return err
+		}
+
+		scope, _ := msgpack.Marshal(browserk.ExcludedFromScope)
+		if err := txn.Set(MakeKey(navID, "scope"), scope); err != nil {
+			return err
+		}
+
+		value, _ := msgpack.Marshal(reason)
+		return txn.Set(MakeKey(navID, "excluded_reason"), value)
+	})
+}
+
 // GetNavigationResult from the navigation id
 func (g *CrawlGraph) GetNavigationResult(navID []byte) (*browserk.NavigationResult, error) {
 	exist := &browserk.NavigationResult{}
@@ -321,6 +340,57 @@ func (g *CrawlGraph) GetNavigationResults() ([]*browserk.NavigationResult, error
 	return navs, err
 }
 
+// SkeletonPages returns the unique end urls of the results found by role that share the DOM skeleton
+func (g *CrawlGraph) SkeletonPages(skeleton []byte, role string) []string {
+	pages := make([]string, 0)
+	if skeleton == nil {
+		return pages
+	}
+
+	value, _ := EncodeBytes(skeleton)
+	roleValue, _ := msgpack.Marshal(role)
+	err := g.GraphStore.View(func(txn *badger.Txn) error {
+		resultIDs, err := IfIterator(txn, []byte("r_skeleton:"), value, -1)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[string]struct{})
+		for _, resultID := range resultIDs {
+			item, err := txn.Get(MakeKey(resultID, "r_role"))
+			if err != nil {
+				continue
+			}
+
+			resultRole, err := item.ValueCopy(nil)
+			if err != nil || !bytes.Equal(resultRole, roleValue) {
+				continue
+			}
+
+			item, err = txn.Get(MakeKey(resultID, "r_end_url"))
+			if err != nil {
+				continue
+			}
+
+			var endURL string
+			if err := item.Value(func(val []byte) error { return msgpack.Unmarshal(val, &endURL) }); err != nil {
+				continue
+			}
+
+			if _, ok := seen[endURL]; !ok {
+				seen[endURL] = struct{}{}
+				pages = append(pages, endURL)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to find pages by skeleton")
+	}
+	return pages
+}
+
 func (g *CrawlGraph) FindWithResults(ctx context.Context, byState, setState browserk.NavState, limit int64) [][]*browserk.NavigationWithResult {
 	// make sure limit is sane
 	if limit <= 0 || limit > 1000 {
@@ -433,6 +503,33 @@ func (g *CrawlGraph) Find(ctx context.Context, byState, setState browserk.NavSta
 	return entries
 }
 
+// FindNavigations returns every navigation in the state (without the path to get to it) so the scheduler can
+// choose from all of them
+func (g *CrawlGraph) FindNavigations(ctx context.Context, byState browserk.NavState) []*browserk.Navigation {
+	navs := make([]*browserk.Navigation, 0)
+	err := g.GraphStore.View(func(txn *badger.Txn) error {
+		nodeIDs, err := StateIterator(txn, byState, -1)
+		if err != nil {
+			return err
+		}
+
+		for _, nodeID := range nodeIDs {
+			nav, err := DecodeNavigation(txn, g.navPredicates, nodeID)
+			if err != nil {
+				return err
+			}
+			navs = append(navs, nav)
+		}
+		return nil
+	})
+
+	// TODO: retry on transaction conflict errors
+	if err != nil {
+		log.Error().Err(err).Msg("failed to find navigations")
+	}
+	return navs
+}
+
 // FindPathByNavID returns the path start -> finish (navID)
 func (g *CrawlGraph) FindPathByNavID(ctx context.Context, navID []byte) []*browserk.Navigation {
 	path := make([]*browserk.Navigation, 0)
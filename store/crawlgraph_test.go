@@ -2,6 +2,7 @@ package store_test
 
 import (
 	"bytes"
+	"context"
 	"os"
 	"testing"
 
@@ -106,6 +107,153 @@ func TestCrawlGraph(t *testing.T) {
 	_ = g.Find(nil, browserk.NavUnvisited, browserk.NavInProcess, 5)
 }
 
+func TestCrawlRoles(t *testing.T) {
+	os.RemoveAll("testdata/roles")
+	g := store.NewCrawlGraph(testConfig, "testdata/roles")
+	if err := g.Init(); err != nil {
+		t.Fatalf("error init graph: %s\n", err)
+	}
+	defer g.Close()
+
+	admin := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction("http://example.com"))
+	admin.SetRole("admin")
+	user := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction("http://example.com"))
+	user.SetRole("user")
+
+	if bytes.Equal(admin.ID, user.ID) {
+		t.Fatalf("expected each role to have a unique navigation id")
+	}
+
+	if err := g.AddNavigations([]*browserk.Navigation{admin, user}); err != nil {
+		t.Fatalf("error adding: %s\n", err)
+	}
+
+	ele := &browserk.HTMLElement{Type: browserk.A, Attributes: map[string]string{"href": "/admin"}}
+	child := browserk.NewNavigationFromElement(admin, browserk.TrigCrawler, ele, browserk.ActLeftClick)
+	if err := g.AddNavigation(child); err != nil {
+		t.Fatalf("error adding: %s\n", err)
+	}
+
+	result, err := g.GetNavigation(child.ID)
+	if err != nil {
+		t.Fatalf("error reading back navigation: %s\n", err)
+	}
+
+	if result.Role != "admin" {
+		t.Fatalf("expected child navigation to inherit the admin role got %s\n", result.Role)
+	}
+
+	navResult := &browserk.NavigationResult{NavigationID: child.ID, Role: child.Role}
+	navResult.Hash()
+	if err := g.AddResult(navResult); err != nil {
+		t.Fatalf("error adding result: %s\n", err)
+	}
+
+	storedResult, err := g.GetNavigationResult(child.ID)
+	if err != nil {
+		t.Fatalf("error reading back result: %s\n", err)
+	}
+
+	if storedResult.Role != "admin" {
+		t.Fatalf("expected result to be tagged with the admin role got %s\n", storedResult.Role)
+	}
+}
+
+func TestCrawlExcluded(t *testing.T) {
+	os.RemoveAll("testdata/excluded")
+	g := store.NewCrawlGraph(testConfig, "testdata/excluded")
+	if err := g.Init(); err != nil {
+		t.Fatalf("error init graph: %s\n", err)
+	}
+	defer g.Close()
+
+	root := browserk.NewNavigation(browserk.TrigInitial, browserk.NewLoadURLAction("http://example.com"))
+	logout := browserk.NewNavigationFromElement(root, browserk.TrigCrawler, &browserk.HTMLElement{Type: browserk.A, Attributes: map[string]string{"href": "/logout"}}, browserk.ActLeftClick)
+	logout.Exclude("logout href: /logout")
+	clear := browserk.NewNavigationFromElement(root, browserk.TrigCrawler, &browserk.HTMLElement{Type: browserk.BUTTON, InnerText: "Bye"}, browserk.ActLeftClick)
+
+	if err := g.AddNavigations([]*browserk.Navigation{root, logout, clear}); err != nil {
+		t.Fatalf("error adding: %s\n", err)
+	}
+
+	result := &browserk.NavigationResult{NavigationID: clear.ID, SessionClosed: true}
+	result.Hash()
+	if err := g.AddResult(result); err != nil {
+		t.Fatalf("error adding result: %s\n", err)
+	}
+
+	if err := g.ExcludeNavigation(clear.ID, "cleared the session cookie"); err != nil {
+		t.Fatalf("error excluding: %s\n", err)
+	}
+
+	entries := g.Find(nil, browserk.NavExcluded, browserk.NavExcluded, 10)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 excluded entries got %d\n", len(entries))
+	}
+
+	for _, path := range entries {
+		nav := path[len(path)-1]
+		if nav.Scope != browserk.ExcludedFromScope || nav.ExcludedReason == "" {
+			t.Fatalf("expected excluded scope and reason got %v %s\n", nav.Scope, nav.ExcludedReason)
+		}
+	}
+
+	storedResult, err := g.GetNavigationResult(clear.ID)
+	if err != nil {
+		t.Fatalf("error reading back result: %s\n", err)
+	}
+
+	if !storedResult.SessionClosed {
+		t.Fatalf("expected result to record the session was closed\n")
+	}
+
+	if unvisited := g.Find(nil, browserk.NavUnvisited, browserk.NavUnvisited, 10); len(unvisited) != 1 {
+		t.Fatalf("expected only the root to be unvisited got %d\n", len(unvisited))
+	}
+}
+
+func TestCrawlSkeletonPages(t *testing.T) {
+	os.RemoveAll("testdata/skeleton")
+	g := store.NewCrawlGraph(testConfig, "testdata/skeleton")
+	if err := g.Init(); err != nil {
+		t.Fatalf("error init graph: %s\n", err)
+	}
+	defer g.Close()
+
+	product := []byte("product skeleton")
+	var results = []struct {
+		url      string
+		role     string
+		skeleton []byte
+	}{
+		{"http://example.com/product/1", "", product},
+		{"http://example.com/product/2", "", product},
+		{"http://example.com/product/2", "", product}, // menu opened on the same page
+		{"http://example.com/product/3", "admin", product},
+		{"http://example.com/", "", []byte("home skeleton")},
+	}
+
+	for i, in := range results {
+		result := &browserk.NavigationResult{NavigationID: []byte{byte(i)}, EndURL: in.url, Role: in.role, Skeleton: in.skeleton}
+		result.Hash()
+		if err := g.AddResult(result); err != nil {
+			t.Fatalf("error adding result: %s\n", err)
+		}
+	}
+
+	if pages := g.SkeletonPages(product, ""); len(pages) != 2 {
+		t.Fatalf("expected 2 unique product pages got %v\n", pages)
+	}
+
+	if pages := g.SkeletonPages(product, "admin"); len(pages) != 1 || pages[0] != "http://example.com/product/3" {
+		t.Fatalf("expected admin product page got %v\n", pages)
+	}
+
+	if pages := g.SkeletonPages(nil, ""); len(pages) != 0 {
+		t.Fatalf("expected no pages for an empty skeleton got %v\n", pages)
+	}
+}
+
 func TestCrawlAddMultiple(t *testing.T) {
 	path := "testdata/multi/crawl"
 	os.RemoveAll(path)
@@ -258,3 +406,36 @@ func TestCrawlAddResults(t *testing.T) {
 		t.Fatalf("expected body %s got %s", expectedBody, res.Messages[0].Response.Body)
 	}
 }
+
+func TestCrawlFindNavigations(t *testing.T) {
+	os.RemoveAll("testdata/findnavs")
+	g := store.NewCrawlGraph(testConfig, "testdata/findnavs")
+	if err := g.Init(); err != nil {
+		t.Fatalf("error init graph: %s\n", err)
+	}
+	defer g.Close()
+
+	// more than Find's old per round limit, the scheduler must see all of them
+	for i := 0; i < 600; i++ {
+		nav := mock.MakeMockNavi([]byte{byte(i >> 8), byte(i), 2})
+		nav.OriginID = []byte{}
+		if err := g.AddNavigation(nav); err != nil {
+			t.Fatalf("error adding: %s\n", err)
+		}
+	}
+
+	if err := g.SetNavigationState([]byte{0, 1, 2}, browserk.NavVisited); err != nil {
+		t.Fatalf("error setting state: %s\n", err)
+	}
+
+	navs := g.FindNavigations(context.Background(), browserk.NavUnvisited)
+	if len(navs) != 599 {
+		t.Fatalf("expected all unvisited navigations got %d\n", len(navs))
+	}
+
+	for _, nav := range navs {
+		if bytes.Equal(nav.ID, []byte{0, 1, 2}) || nav.Action == nil {
+			t.Fatalf("expected only decoded unvisited navigations got %#v\n", nav)
+		}
+	}
+}
@@ -232,6 +232,48 @@ func DecodeNavigationResultItem(txn *badger.Txn, item *badger.Item, nav *browser
 			nav.Errors = v
 			return err
 		})
+	case "r_role":
+		err = item.Value(func(val []byte) error {
+			var v string
+			err := msgpack.Unmarshal(val, &v)
+			nav.Role = v
+			return err
+		})
+	case "r_session_closed":
+		err = item.Value(func(val []byte) error {
+			var v bool
+			err := msgpack.Unmarshal(val, &v)
+			nav.SessionClosed = v
+			return err
+		})
+	case "r_skeleton":
+		err = item.Value(func(val []byte) error {
+			var b []byte
+			err := msgpack.Unmarshal(val, &b)
+			nav.Skeleton = b
+			return err
+		})
+	case "r_dialogs":
+		err = item.Value(func(val []byte) error {
+			v := make([]*browserk.DialogEvent, 0)
+			err := msgpack.Unmarshal(val, &v)
+			nav.DialogEvents = v
+			return err
+		})
+	case "r_frames":
+		err = item.Value(func(val []byte) error {
+			v := make([]*browserk.FrameDocument, 0)
+			err := msgpack.Unmarshal(val, &v)
+			nav.FrameDocuments = v
+			return err
+		})
+	case "r_attack_targets":
+		err = item.Value(func(val []byte) error {
+			v := make([]*browserk.AttackTarget, 0)
+			err := msgpack.Unmarshal(val, &v)
+			nav.AttackTargets = v
+			return err
+		})
 	default:
 		panic("unknown predicate for navigation")
 	}
@@ -299,6 +341,34 @@ func DecodeNavigationItem(item *badger.Item, nav *browserk.Navigation, pred stri
 			nav.Action = v
 			return err
 		})
+	case "role":
+		err = item.Value(func(val []byte) error {
+			var v string
+			err := msgpack.Unmarshal(val, &v)
+			nav.Role = v
+			return err
+		})
+	case "excluded_reason":
+		err = item.Value(func(val []byte) error {
+			var v string
+			err := msgpack.Unmarshal(val, &v)
+			nav.ExcludedReason = v
+			return err
+		})
+	case "blocked_reason":
+		err = item.Value(func(val []byte) error {
+			var v string
+			err := msgpack.Unmarshal(val, &v)
+			nav.BlockedReason = v
+			return err
+		})
+	case "source":
+		err = item.Value(func(val []byte) error {
+			var v string
+			err := msgpack.Unmarshal(val, &v)
+			nav.Source = v
+			return err
+		})
 	default:
 		panic("unknown predicate for navigation")
 	}
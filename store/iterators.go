@@ -7,6 +7,7 @@ import (
 	"gitlab.com/browserker/browserk"
 )
 
+// StateIterator returns up to limit node IDs in the state, a negative limit returns all of them
 func StateIterator(txn *badger.Txn, byState browserk.NavState, limit int64) ([][]byte, error) {
 	states := make([][]byte, 0)
 	idx := int64(0)
@@ -202,7 +202,7 @@ func (s *PluginStore) SetRequestAudit(request *browserk.HTTPRequest) (browserk.A
 	var err error
 	audited := browserk.AuditFailed
 	err = s.Store.Update(func(txn *badger.Txn) error {
-		key := MakeKey(request.ID, "audreq")
+		key := MakeKey(request.Hash(), "audreq")
 		_, err := txn.Get(key)
 		if err == badger.ErrKeyNotFound {
 			txn.Set(key, []byte{byte(browserk.AuditInProgress)})